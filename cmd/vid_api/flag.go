@@ -7,4 +7,5 @@ var (
 	Help   = flag.Bool("h", false, "show help")
 
 	survivalTimeout = int(3e9)
+	rbacChannel     = "vid:rbac:update" // 策略变更通知频道
 )
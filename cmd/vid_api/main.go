@@ -4,13 +4,24 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/vidorg/vid_backend/internal/conf"
 	"github.com/vidorg/vid_backend/internal/middleware"
 	"github.com/vidorg/vid_backend/internal/model"
 	"github.com/vidorg/vid_backend/internal/router"
+	"github.com/vidorg/vid_backend/internal/service/captcha"
+	"github.com/vidorg/vid_backend/internal/service/keyword"
+	"github.com/vidorg/vid_backend/internal/service/user"
 	"github.com/vidorg/vid_backend/pkg/jwt"
 	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/mail"
+	"github.com/vidorg/vid_backend/pkg/oauth"
 	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/password"
+	"github.com/vidorg/vid_backend/pkg/rbac"
+	"github.com/vidorg/vid_backend/pkg/redis"
+	"github.com/vidorg/vid_backend/pkg/upload"
+	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"net/http"
 	"os"
@@ -40,12 +51,36 @@ func run() {
 		logger.SetOutput(true),
 		logger.SetPath(conf.Config().Meta.LogPath))
 
-	jwt.SetMeta(conf.Config().Jwt.Secret, conf.Config().Jwt.Issuer)
+	jwt.SetMeta(conf.Config().Jwt.Issuer, conf.Config().Jwt.Audience)
+	if err := loadKeyring(conf.Config().Jwt); err != nil {
+		panic(err)
+	}
+	if conf.Config().Meta.UploadPath != "" {
+		upload.SetMeta(conf.Config().Meta.UploadPath, conf.Config().Meta.UploadURL)
+	}
+
+	if c := conf.Config().Email; c != nil && c.SmtpHost != "" {
+		from := c.From
+		if from == "" {
+			from = c.Username
+		}
+		mail.Init(&mail.SMTPSender{
+			Host:     c.SmtpHost,
+			Port:     int(c.SmtpPort),
+			Username: c.Username,
+			Password: c.Password,
+			From:     from,
+			Name:     c.Name,
+		})
+	}
 
-	//err := redis.Init(conf.Config().Redis.Addr, conf.Config().Redis.Password, conf.Config().Redis.Db)
-	//if err != nil {
-	//	logger.Logger().Error("redis initialize err", zap.Error(errors.Wrap(err, "redis initialize err")))
-	//}
+	loadOAuth(conf.Config().OAuth)
+	loadPassword(conf.Config().Password)
+
+	err := redis.Init(conf.Config().Redis.Addr, conf.Config().Redis.Password, conf.Config().Redis.Db)
+	if err != nil {
+		logger.Logger().Error("redis initialize err", zap.Error(errors.Wrap(err, "redis initialize err")))
+	}
 
 	dbParams := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
 		conf.Config().MySQL.User, conf.Config().MySQL.Password,
@@ -56,7 +91,47 @@ func run() {
 		panic(err)
 	}
 
-	orm.DB().AutoMigrate(&model.User{}, &model.Category{}, &model.Channel{}, &model.Video{})
+	if err := orm.DB().SetupJoinTable(&model.User{}, "Fans", &model.UserFan{}); err != nil {
+		panic(err)
+	}
+	if err := orm.DB().SetupJoinTable(&model.Channel{}, "Users", &model.ChannelAuthor{}); err != nil {
+		panic(err)
+	}
+	if err := orm.DB().SetupJoinTable(&model.Channel{}, "Subscribers", &model.ChannelSubscriber{}); err != nil {
+		panic(err)
+	}
+	orm.DB().AutoMigrate(&model.User{}, &model.Category{}, &model.Channel{}, &model.Video{},
+		&model.FeedItem{}, &model.Block{}, &model.Session{}, &model.RefreshToken{},
+		&model.UserToken{}, &model.RecoveryCode{}, &model.Passkey{}, &model.PasskeyCeremony{},
+		&model.UserIdentity{}, &model.OAuthState{}, &model.AuditLog{}, &model.APIToken{},
+		&model.HandleHistory{}, &model.DataExport{}, &model.ModerationCase{}, &model.Report{},
+		&model.Notification{}, &model.FilterRule{})
+	if err := model.RebuildCategoryPaths(); err != nil {
+		panic(err)
+	}
+
+	if err := rbac.Init(orm.DB(), conf.Config().Casbin.ConfigPath); err != nil {
+		panic(err)
+	}
+	if err := rbac.Seed(model.DefaultPolicies, model.DefaultGroupings); err != nil {
+		panic(err)
+	}
+	if redis.Enabled() {
+		if err := rbac.SetWatcher(rbac.NewWatcher(rbacChannel)); err != nil {
+			panic(err)
+		}
+	}
+	if err := keyword.Init(); err != nil {
+		panic(err)
+	}
+	if err := middleware.InitRateLimit(); err != nil {
+		panic(err)
+	}
+	if err := captcha.Init(); err != nil {
+		panic(err)
+	}
+	model.StartAuditWriter()
+	user.StartMaintenance(time.Hour)
 	engine := router.Init()
 	s := &http.Server{
 		Addr:           ":" + strconv.Itoa(conf.Config().Meta.Port),
@@ -64,8 +139,7 @@ func run() {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	middleware.Init(engine)
-
+	stopped := make(chan struct{})
 	go func() {
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals,
@@ -81,6 +155,9 @@ func run() {
 				time.AfterFunc(time.Duration(survivalTimeout), func() {
 					logger.Logger().Info(fmt.Sprintf("[%s] shutting down", "vid_api"))
 					_ = s.Shutdown(context.Background())
+					// 请求处理完后写完队列中的审计日志再退出
+					model.StopAuditWriter()
+					close(stopped)
 				})
 				return
 			}
@@ -92,4 +169,69 @@ func run() {
 			panic(err)
 		}
 	}
+	<-stopped
+}
+
+// loadKeyring 加载jwt签名密钥，未配置keys时兼容旧的HS256 secret
+func loadKeyring(c *conf.JwtConfig) error {
+	keyring := jwt.NewKeyring()
+	if len(c.Keys) == 0 {
+		key, err := jwt.LoadKey("default", "HS256", "", "", c.Secret)
+		if err != nil {
+			return err
+		}
+		keyring.Add(key)
+		jwt.SetKeyring(keyring)
+		return keyring.Activate("default")
+	}
+
+	for _, k := range c.Keys {
+		key, err := jwt.LoadKey(k.ID, k.Alg, k.PrivateKey, k.PublicKey, k.Secret)
+		if err != nil {
+			return err
+		}
+		keyring.Add(key)
+	}
+	jwt.SetKeyring(keyring)
+	return keyring.Activate(c.ActiveKey)
+}
+
+// loadOAuth 注册外部身份提供方，单个提供方初始化失败时跳过
+func loadOAuth(configs []*conf.OAuthConfig) {
+	for _, c := range configs {
+		switch c.Type {
+		case "github":
+			oauth.Register(oauth.NewGitHubProvider(c.Name, c.ClientID, c.ClientSecret, c.RedirectURL,
+				c.AuthURL, c.TokenURL, c.APIURL, c.Scopes))
+		case "oidc":
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			p, err := oauth.NewOIDCProvider(ctx, c.Name, c.Issuer, c.ClientID, c.ClientSecret, c.RedirectURL, c.Scopes)
+			cancel()
+			if err != nil {
+				logger.Logger().Error("oauth provider initialize err", zap.String("name", c.Name), zap.Error(err))
+				continue
+			}
+			oauth.Register(p)
+		default:
+			logger.Logger().Error("unknown oauth provider type", zap.String("name", c.Name), zap.String("type", c.Type))
+		}
+	}
+}
+
+// loadPassword 设置新密码使用的哈希算法与密码策略
+func loadPassword(c *conf.PasswordConfig) {
+	if c == nil {
+		return
+	}
+	switch c.Algorithm {
+	case "argon2id":
+		password.SetDefault(&password.Argon2id{Memory: c.Argon2Memory, Time: c.Argon2Time, Threads: c.Argon2Threads})
+	case "bcrypt", "":
+		password.SetDefault(&password.Bcrypt{Cost: c.BcryptCost})
+	default:
+		panic("unknown password algorithm " + c.Algorithm)
+	}
+	if c.MinLength > 0 {
+		password.DefaultPolicy.MinLength = c.MinLength
+	}
 }
@@ -3,14 +3,17 @@ module github.com/vidorg/vid_backend
 go 1.16
 
 require (
+	github.com/casbin/casbin/v2 v2.37.0
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-gonic/gin v1.6.3
 	github.com/go-redis/redis/v8 v8.5.0
-	github.com/kataras/jwt v0.0.9
+	github.com/kataras/jwt v0.1.2
 	github.com/pkg/errors v0.8.1
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.7.0
 	go.uber.org/zap v1.16.0
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/image v0.0.0-20210220032944-ac19c3e999fb
 	gopkg.in/yaml.v2 v2.4.0
 	gorm.io/driver/mysql v1.0.4
 	gorm.io/gorm v1.20.12
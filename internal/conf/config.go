@@ -24,6 +24,10 @@ type MetaConfig struct {
 	LogMq       bool   `yaml:"log-mq"`
 	DefPageSize int32  `yaml:"def-page-size"`
 	MaxPageSize int32  `yaml:"max-page-size"`
+	UploadPath  string `yaml:"upload-path"`
+	UploadURL   string `yaml:"upload-url"`
+	SiteURL     string `yaml:"site-url"`
+	ExportPath  string `yaml:"export-path"` // 个人数据导出文件目录，不能位于upload-path下
 }
 
 type MySQLConfig struct {
@@ -65,12 +69,86 @@ type EmailConfig struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	Expire   int64  `yaml:"expire"`
+	From     string `yaml:"from"`
+}
+
+type JwtKeyConfig struct {
+	ID         string `yaml:"id"`
+	Alg        string `yaml:"alg"` // HS256, RS256 or EdDSA
+	PrivateKey string `yaml:"private-key"`
+	PublicKey  string `yaml:"public-key"`
+	Secret     string `yaml:"secret"`
 }
 
 type JwtConfig struct {
-	Secret string `yaml:"secret"`
-	Expire int64  `yaml:"expire"`
-	Issuer string `yaml:"issuer"`
+	Secret        string          `yaml:"secret"`
+	Expire        int64           `yaml:"expire"`
+	RefreshExpire int64           `yaml:"refresh-expire"`
+	Issuer        string          `yaml:"issuer"`
+	Audience      string          `yaml:"audience"`
+	ActiveKey     string          `yaml:"active-key"`
+	Keys          []*JwtKeyConfig `yaml:"keys"`
+}
+
+type WebAuthnConfig struct {
+	RPID        string   `yaml:"rp-id"`
+	RPName      string   `yaml:"rp-name"`
+	Origins     []string `yaml:"origins"`
+	Attestation string   `yaml:"attestation"` // none or direct
+}
+
+type OAuthConfig struct {
+	Name         string   `yaml:"name"`
+	Type         string   `yaml:"type"` // oidc or github
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client-id"`
+	ClientSecret string   `yaml:"client-secret"`
+	RedirectURL  string   `yaml:"redirect-url"`
+	Scopes       []string `yaml:"scopes"`
+	AuthURL      string   `yaml:"auth-url"`  // github only, for GitHub Enterprise
+	TokenURL     string   `yaml:"token-url"` // github only
+	APIURL       string   `yaml:"api-url"`   // github only
+}
+
+type PasswordConfig struct {
+	Algorithm     string `yaml:"algorithm"` // bcrypt or argon2id
+	BcryptCost    int    `yaml:"bcrypt-cost"`
+	Argon2Memory  uint32 `yaml:"argon2-memory"` // KiB
+	Argon2Time    uint32 `yaml:"argon2-time"`
+	Argon2Threads uint8  `yaml:"argon2-threads"`
+	MinLength     int    `yaml:"min-length"`
+}
+
+type RateLimitPolicyConfig struct {
+	Algorithm string `yaml:"algorithm"` // token-bucket or sliding-window
+	Limit     int64  `yaml:"limit"`
+	Period    int64  `yaml:"period"` // 秒
+	Burst     int64  `yaml:"burst"`  // token-bucket only, 默认等于limit
+	Key       string `yaml:"key"`    // ip, user or token
+}
+
+type RateLimitRouteConfig struct {
+	Path   string `yaml:"path"`   // 完整路由，以*结尾时按前缀匹配
+	Method string `yaml:"method"` // 为空时匹配所有方法
+	Policy string `yaml:"policy"`
+}
+
+type RateLimitConfig struct {
+	Enabled  bool                              `yaml:"enabled"`
+	Default  string                            `yaml:"default"`  // 未匹配任何路由时使用的策略，为空时不限流
+	PreAuth  string                            `yaml:"pre-auth"` // 需登录的路由在鉴权前按ip计数的策略，为空时不限流
+	Policies map[string]*RateLimitPolicyConfig `yaml:"policies"`
+	Routes   []*RateLimitRouteConfig           `yaml:"routes"` // 按顺序匹配第一条
+}
+
+type CaptchaConfig struct {
+	Length     int      `yaml:"length"` // 图片验证码的文字长度
+	Width      int      `yaml:"width"`
+	Height     int      `yaml:"height"`
+	Difficulty int      `yaml:"difficulty"` // 工作量证明要求的0比特数
+	TTL        int64    `yaml:"ttl"`        // 秒
+	Login      []string `yaml:"login"`      // 登录接受的挑战类型，image或pow
+	Register   []string `yaml:"register"`   // 注册接受的挑战类型
 }
 
 type CasbinConfig struct {
@@ -78,13 +156,18 @@ type CasbinConfig struct {
 }
 
 type AppConfig struct {
-	Meta   *MetaConfig   `yaml:"meta"`
-	MySQL  *MySQLConfig  `yaml:"mysql"`
-	Redis  *RedisConfig  `yaml:"redis"`
-	Amqp   *AmqpConfig   `yaml:"amqp"`
-	Email  *EmailConfig  `yaml:"email"`
-	Jwt    *JwtConfig    `yaml:"jwt"`
-	Casbin *CasbinConfig `yaml:"casbin"`
+	Meta      *MetaConfig      `yaml:"meta"`
+	MySQL     *MySQLConfig     `yaml:"mysql"`
+	Redis     *RedisConfig     `yaml:"redis"`
+	Amqp      *AmqpConfig      `yaml:"amqp"`
+	Email     *EmailConfig     `yaml:"email"`
+	Jwt       *JwtConfig       `yaml:"jwt"`
+	Casbin    *CasbinConfig    `yaml:"casbin"`
+	WebAuthn  *WebAuthnConfig  `yaml:"webauthn"`
+	OAuth     []*OAuthConfig   `yaml:"oauth"`
+	Password  *PasswordConfig  `yaml:"password"`
+	RateLimit *RateLimitConfig `yaml:"rate-limit"`
+	Captcha   *CaptchaConfig   `yaml:"captcha"`
 }
 
 func Load(path string) error {
@@ -5,11 +5,16 @@ import (
 	"github.com/vidorg/vid_backend/internal/model"
 	"github.com/vidorg/vid_backend/internal/serializer"
 	"github.com/vidorg/vid_backend/pkg/jwt"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"go.uber.org/zap"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-// Auth 鉴权
-func Auth() gin.HandlerFunc {
+// Auth 鉴权，默认只接受登录会话的JWT
+// 指定scopes时同时接受拥有全部这些权限范围的个人访问令牌
+func Auth(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//token,err := c.Cookie("token")
 		//if errors.Is(err,http.ErrNoCookie){
@@ -28,8 +33,16 @@ func Auth() gin.HandlerFunc {
 			})
 			return
 		}
+		if model.IsAPIToken(token) {
+			apiTokenAuth(c, token, scopes)
+			return
+		}
 		userClaims, err := jwt.ParseToken([]byte(token))
-		if err != nil || userClaims.UID == 0 {
+		var session *model.Session
+		if err == nil && userClaims.UID != 0 {
+			session = activeSession(c, userClaims)
+		}
+		if session == nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, &serializer.Response{
 				Code: 403,
 				Msg:  "token失效",
@@ -38,6 +51,7 @@ func Auth() gin.HandlerFunc {
 			return
 		}
 		c.Set("user_id", userClaims.UID)
+		c.Set("session_id", userClaims.SID)
 		user, err := model.GetUser(userClaims.UID)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, &serializer.Response{
@@ -45,8 +59,144 @@ func Auth() gin.HandlerFunc {
 				Msg:  "没有找到该用户",
 				Data: err,
 			})
+			return
+		}
+		// 被封禁或未激活的用户已签发的token同样失效
+		if !user.IsActive() {
+			c.AbortWithStatusJSON(http.StatusForbidden, serializer.UserStatusErr("账号状态异常"))
+			return
+		}
+		c.Set("user", user)
+		if session.Impersonator != 0 {
+			c.Set("impersonator_id", session.Impersonator)
+		}
+		c.Next()
+		if session.Impersonator != 0 {
+			auditImpersonation(c, session.Impersonator, user.ID)
+		}
+	}
+}
+
+// OptionalAuth 可选鉴权，token有效时设置当前用户，否则按游客继续
+// 指定scopes时个人访问令牌同样可用
+func OptionalAuth(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("Authorization")
+		if token == "" {
+			c.Next()
+			return
+		}
+		if model.IsAPIToken(token) {
+			if len(scopes) > 0 {
+				if apiToken, user := findAPIToken(c, token); apiToken != nil && apiToken.HasScopes(scopes...) {
+					c.Set("user_id", user.ID)
+					c.Set("api_token", apiToken)
+					c.Set("user", user)
+				}
+			}
+			c.Next()
+			return
+		}
+		userClaims, err := jwt.ParseToken([]byte(token))
+		if err != nil || userClaims.UID == 0 {
+			c.Next()
+			return
+		}
+		session := activeSession(c, userClaims)
+		if session == nil {
+			c.Next()
+			return
+		}
+		user, err := model.GetUser(userClaims.UID)
+		if err != nil || !user.IsActive() {
+			c.Next()
+			return
 		}
+		c.Set("user_id", userClaims.UID)
+		c.Set("session_id", userClaims.SID)
 		c.Set("user", user)
+		// 代为登录期间的请求与Auth一样记录审计日志
+		if session.Impersonator != 0 {
+			c.Set("impersonator_id", session.Impersonator)
+		}
 		c.Next()
+		if session.Impersonator != 0 {
+			auditImpersonation(c, session.Impersonator, user.ID)
+		}
+	}
+}
+
+// apiTokenAuth 个人访问令牌鉴权，令牌须拥有接口要求的全部权限范围
+func apiTokenAuth(c *gin.Context, token string, scopes []string) {
+	apiToken, user := findAPIToken(c, token)
+	if apiToken == nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, &serializer.Response{
+			Code: 403,
+			Msg:  "token失效",
+		})
+		return
+	}
+	// 未声明权限范围的接口只接受登录会话
+	if len(scopes) == 0 || !apiToken.HasScopes(scopes...) {
+		c.AbortWithStatusJSON(http.StatusForbidden, serializer.Err(serializer.CodeNoRightError, "令牌权限不足", nil))
+		return
+	}
+	c.Set("user_id", user.ID)
+	c.Set("api_token", apiToken)
+	c.Set("user", user)
+	c.Next()
+}
+
+// findAPIToken 查找有效的个人访问令牌及其用户，并按间隔记录最近使用时间与IP
+func findAPIToken(c *gin.Context, token string) (*model.APIToken, *model.User) {
+	apiToken, err := model.GetAPIToken(token)
+	if err != nil {
+		return nil, nil
+	}
+	user, err := model.GetUser(apiToken.UserID)
+	if err != nil || !user.IsActive() {
+		return nil, nil
+	}
+	now := time.Now().Unix()
+	if now-apiToken.LastUsed >= model.APITokenSeenPeriod {
+		if err := apiToken.Seen(c.ClientIP(), now); err != nil {
+			logger.Logger().Warn("[Auth] record api token seen err", zap.Int64("token_id", apiToken.ID), zap.Error(err))
+		}
+	}
+	return apiToken, user
+}
+
+// activeSession token所属会话仍有效时返回该会话，会话被吊销后token立即失效
+// 有效时按间隔记录会话的最近访问时间与IP
+func activeSession(c *gin.Context, claims jwt.UserClaims) *model.Session {
+	if claims.SID == "" {
+		return nil
 	}
+	session, err := model.Sessions().GetSession(claims.SID)
+	if err != nil {
+		return nil
+	}
+	now := time.Now().Unix()
+	if session.UserID != claims.UID || !session.Active(now) {
+		return nil
+	}
+	if now-session.LastSeen >= model.SessionSeenInterval {
+		if err := model.Sessions().SeenSession(session.ID, c.ClientIP(), now); err != nil {
+			logger.Logger().Warn("[Auth] record session seen err", zap.String("session_id", session.ID), zap.Error(err))
+		}
+	}
+	return session
+}
+
+// auditImpersonation 记录管理员代为登录期间的每次请求，包括读取
+func auditImpersonation(c *gin.Context, impersonator, userID int64) {
+	model.Audit(&model.AuditLog{
+		ActorID:    impersonator,
+		Action:     model.AuditImpersonateCall,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(userID, 10),
+		IP:         c.ClientIP(),
+		RequestID:  c.GetString("request_id"),
+		Detail:     c.Request.Method + " " + c.Request.URL.Path + " " + strconv.Itoa(c.Writer.Status()),
+	})
 }
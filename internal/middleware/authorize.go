@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/rbac"
+	"net/http"
+)
+
+// Authorize 基于casbin的RBAC鉴权，需在Auth之后使用
+func Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusForbidden, serializer.LoginErr())
+			return
+		}
+		ok, err := rbac.Enforce(user.(*model.User).Role, c.Request.URL.Path, c.Request.Method)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, serializer.ServerErr("鉴权失败", err))
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, serializer.NoRightErr())
+			return
+		}
+		c.Next()
+	}
+}
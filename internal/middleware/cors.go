@@ -9,7 +9,9 @@ import (
 func Cors() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Cookie", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Cookie", "Accept", "Authorization", RequestIDHeader}
+	config.ExposeHeaders = []string{RequestIDHeader, RateLimitLimitHeader, RateLimitRemainingHeader,
+		RateLimitResetHeader, RateLimitPolicyHeader, "Retry-After"}
 	if gin.Mode() == gin.ReleaseMode {
 		// 生产环境需要配置跨域域名，否则403
 		config.AllowOrigins = []string{"http://www.seefs0.com"}
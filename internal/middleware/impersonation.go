@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+)
+
+// NoImpersonation 管理员代为登录期间禁止调用的接口，需在Auth之后使用
+// 修改凭证与身份的操作会使临时代登录变为长期控制账号，须由用户本人完成
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("impersonator_id"); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, serializer.Err(serializer.CodeNoRightError, "代为登录期间不能进行该操作", nil))
+			return
+		}
+		c.Next()
+	}
+}
@@ -3,5 +3,5 @@ package middleware
 import "github.com/gin-gonic/gin"
 
 func Init(r *gin.Engine) {
-	r.Use(Recover(), Cors(), Logger())
+	r.Use(RequestID(), Recover(), Cors(), Logger())
 }
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/conf"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/ratelimit"
+	"github.com/vidorg/vid_backend/pkg/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// rateLimitPolicy 配置的限流策略
+type rateLimitPolicy struct {
+	limiter  *ratelimit.Limiter
+	fallback *ratelimit.Limiter // redis出错时使用的进程内计数
+	key      string             // ip, user or token
+	header   string             // RateLimit-Policy响应头
+}
+
+// rateLimitRoute 路由与策略的对应关系
+type rateLimitRoute struct {
+	path   string
+	prefix bool
+	method string
+	policy *rateLimitPolicy
+}
+
+var (
+	rateLimitRoutes   []*rateLimitRoute
+	rateLimitFallback *rateLimitPolicy
+	rateLimitPreAuth  *rateLimitPolicy
+)
+
+// InitRateLimit 根据配置创建限流器，需在启动时调用，配置错误时返回错误
+func InitRateLimit() error {
+	cfg := conf.Config().RateLimit
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	memory := ratelimit.NewMemoryStore()
+	var store ratelimit.Store = memory
+	if redis.Enabled() {
+		store = &ratelimit.RedisStore{}
+	}
+
+	policies := map[string]*rateLimitPolicy{}
+	for name, pc := range cfg.Policies {
+		p := &ratelimit.Policy{
+			Algorithm: pc.Algorithm,
+			Limit:     pc.Limit,
+			Period:    time.Duration(pc.Period) * time.Second,
+			Burst:     pc.Burst,
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("rate-limit: invalid policy %s: %w", name, err)
+		}
+		key := pc.Key
+		if key == "" {
+			key = "ip"
+		}
+		if key != "ip" && key != "user" && key != "token" {
+			return fmt.Errorf("rate-limit: invalid key %s of policy %s", key, name)
+		}
+		header := strconv.FormatInt(p.Limit, 10) + ";w=" + strconv.FormatInt(pc.Period, 10)
+		if p.Algorithm == ratelimit.TokenBucket {
+			header += ";burst=" + strconv.FormatInt(p.Capacity(), 10)
+		}
+		prefix := "vid:ratelimit:" + name + ":"
+		policies[name] = &rateLimitPolicy{
+			limiter:  ratelimit.New(prefix, p, store),
+			fallback: ratelimit.New(prefix, p, memory),
+			key:      key,
+			header:   header,
+		}
+	}
+	find := func(name string) (*rateLimitPolicy, error) {
+		p, ok := policies[name]
+		if !ok {
+			return nil, fmt.Errorf("rate-limit: policy %s not found", name)
+		}
+		return p, nil
+	}
+
+	var routes []*rateLimitRoute
+	for _, rc := range cfg.Routes {
+		policy, err := find(rc.Policy)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, &rateLimitRoute{
+			path:   strings.TrimSuffix(rc.Path, "*"),
+			prefix: strings.HasSuffix(rc.Path, "*"),
+			method: strings.ToUpper(rc.Method),
+			policy: policy,
+		})
+	}
+	var fallback, preAuth *rateLimitPolicy
+	var err error
+	if cfg.Default != "" {
+		if fallback, err = find(cfg.Default); err != nil {
+			return err
+		}
+	}
+	if cfg.PreAuth != "" {
+		if preAuth, err = find(cfg.PreAuth); err != nil {
+			return err
+		}
+	}
+	rateLimitRoutes, rateLimitFallback, rateLimitPreAuth = routes, fallback, preAuth
+	return nil
+}
+
+// matchRateLimit 查找请求对应的策略，没有时返回nil
+func matchRateLimit(c *gin.Context) *rateLimitPolicy {
+	path := c.FullPath()
+	for _, route := range rateLimitRoutes {
+		if route.method != "" && route.method != c.Request.Method {
+			continue
+		}
+		if path == route.path || route.prefix && strings.HasPrefix(path, route.path) {
+			return route.policy
+		}
+	}
+	return rateLimitFallback
+}
+
+// rateLimitKey 请求的计数对象，未登录或未使用令牌时退化为按IP计数
+func rateLimitKey(c *gin.Context, key string) string {
+	if key == "token" {
+		if token, ok := c.Get("api_token"); ok {
+			return "token:" + strconv.FormatInt(token.(*model.APIToken).ID, 10)
+		}
+		key = "user"
+	}
+	if key == "user" {
+		if user, ok := c.Get("user"); ok {
+			return "user:" + strconv.FormatInt(user.(*model.User).ID, 10)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds 向上取整到秒
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// limit 消耗key的一次配额并设置响应头，超出配额时中止请求并返回false
+// redis出错时改用进程内计数，避免故障期间不限流
+func limit(c *gin.Context, policy *rateLimitPolicy, key string) bool {
+	res, err := policy.limiter.Allow(key)
+	if err != nil {
+		logger.Logger().Warn("[RateLimit] take quota err", zap.String("path", c.FullPath()), zap.Error(err))
+		if res, err = policy.fallback.Allow(key); err != nil {
+			return true
+		}
+	}
+	c.Header(RateLimitLimitHeader, strconv.FormatInt(res.Limit, 10))
+	c.Header(RateLimitRemainingHeader, strconv.FormatInt(res.Remaining, 10))
+	c.Header(RateLimitResetHeader, seconds(res.Reset))
+	c.Header(RateLimitPolicyHeader, policy.header)
+	if !res.Allowed {
+		c.Header("Retry-After", seconds(res.RetryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, serializer.TooManyRequestsErr(""))
+		return false
+	}
+	return true
+}
+
+// PreAuthRateLimit 在Auth之前按IP限流，令牌无效而被Auth拒绝的请求同样计数
+func PreAuthRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rateLimitPreAuth != nil && !limit(c, rateLimitPreAuth, "ip:"+c.ClientIP()) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimit 按配置的策略限流，需放在Auth之后才能按用户或令牌计数
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := matchRateLimit(c)
+		if policy != nil && !limit(c, policy, rateLimitKey(c, policy.key)) {
+			return
+		}
+		c.Next()
+	}
+}
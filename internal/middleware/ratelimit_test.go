@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/ratelimit"
+)
+
+// brokenStore 模拟redis故障
+type brokenStore struct{}
+
+func (brokenStore) Take(string, *ratelimit.Policy, int64) (*ratelimit.Result, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestMatchRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	login, user, api := &rateLimitPolicy{key: "ip"}, &rateLimitPolicy{key: "user"}, &rateLimitPolicy{key: "user"}
+	rateLimitRoutes = []*rateLimitRoute{
+		{path: "/api/v1/UserLogin", method: http.MethodPost, policy: login},
+		{path: "/api/v1/auth/", prefix: true, policy: user},
+		{path: "/api/v1/UserLogin", prefix: true, policy: api},
+	}
+	rateLimitFallback = api
+	defer func() { rateLimitRoutes, rateLimitFallback = nil, nil }()
+
+	var matched *rateLimitPolicy
+	r := gin.New()
+	handler := func(c *gin.Context) { matched = matchRateLimit(c) }
+	r.POST("/api/v1/UserLogin", handler)
+	r.GET("/api/v1/UserLogin", handler)
+	r.POST("/api/v1/UserLoginTOTP", handler)
+	r.GET("/api/v1/auth/GetVideos", handler)
+	r.GET("/api/v1/ping", handler)
+
+	// 按顺序匹配第一条，method不符时继续匹配
+	for _, tc := range []struct {
+		method, path string
+		policy       *rateLimitPolicy
+	}{
+		{http.MethodPost, "/api/v1/UserLogin", login},
+		{http.MethodGet, "/api/v1/UserLogin", api},
+		{http.MethodPost, "/api/v1/UserLoginTOTP", api},
+		{http.MethodGet, "/api/v1/auth/GetVideos", user},
+		{http.MethodGet, "/api/v1/ping", api},
+	} {
+		matched = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Same(t, tc.policy, matched, tc.method+" "+tc.path)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+
+	// 未登录时退化为按IP计数
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(c, "token"))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(c, "user"))
+
+	c.Set("user", &model.User{BaseModel: model.BaseModel{ID: 7}})
+	assert.Equal(t, "user:7", rateLimitKey(c, "token"))
+	assert.Equal(t, "user:7", rateLimitKey(c, "user"))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(c, "ip"))
+
+	c.Set("api_token", &model.APIToken{ID: 3})
+	assert.Equal(t, "token:3", rateLimitKey(c, "token"))
+	assert.Equal(t, "user:7", rateLimitKey(c, "user"))
+}
+
+func TestRateLimitStoreFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.New(logger.SetPath(t.TempDir()))
+	p := &ratelimit.Policy{Algorithm: ratelimit.SlidingWindow, Limit: 1, Period: time.Minute}
+	policy := &rateLimitPolicy{
+		limiter:  ratelimit.New("test:", p, brokenStore{}),
+		fallback: ratelimit.New("test:", p, ratelimit.NewMemoryStore()),
+		key:      "ip",
+	}
+
+	// 存储出错时改用进程内计数，仍然限流
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.True(t, limit(c, policy, "ip:10.0.0.1"))
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(t, limit(c, policy, "ip:10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+// APIToken 用户生成的个人访问令牌，供脚本调用接口，只保存哈希
+type APIToken struct {
+	ID        int64  `gorm:"primaryKey"`
+	UserID    int64  `gorm:"index;not null"`
+	Name      string `gorm:"size:50;not null"`
+	Hint      string `gorm:"size:20;not null;comment:令牌前几位，便于用户辨认"`
+	Hash      string `gorm:"uniqueIndex;size:64;not null"`
+	Scopes    string `gorm:"size:255;not null;comment:空格分隔的权限范围"`
+	ExpiresAt int64  `gorm:"not null;default:0;comment:0表示永不过期"`
+	LastUsed  int64  `gorm:"not null;default:0"`
+	LastIP    string `gorm:"size:45"`
+	Created   int64  `gorm:"autoCreateTime"`
+}
+
+const (
+	APITokenPrefix     = "vidpat_" // 个人访问令牌前缀，用于与会话JWT区分
+	APITokenLimit      = 20        // 每个用户最多持有的令牌数
+	APITokenSeenPeriod = 60        // 最近使用时间的记录间隔，单位秒
+
+	ScopeProfileRead   = "profile:read"
+	ScopeProfileWrite  = "profile:write"
+	ScopeVideosRead    = "videos:read"
+	ScopeVideosWrite   = "videos:write"
+	ScopeChannelsWrite = "channels:write"
+	ScopeSocialWrite   = "social:write" // 关注、订阅、拉黑
+)
+
+// Scopes 所有可授予个人访问令牌的权限范围
+var Scopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeVideosRead,
+	ScopeVideosWrite,
+	ScopeChannelsWrite,
+	ScopeSocialWrite,
+}
+
+var ErrAPITokenInvalid = errors.New("api token is invalid or expired")
+
+// ValidScope 是否为已定义的权限范围
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIToken 是否为个人访问令牌格式
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// NewAPIToken 创建个人访问令牌，返回明文，明文只在创建时出现一次
+func NewAPIToken(userID int64, name string, scopes []string, ttl time.Duration) (*APIToken, string, error) {
+	random, _, err := NewRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := APITokenPrefix + random
+	token := &APIToken{
+		UserID: userID,
+		Name:   name,
+		Hint:   plain[:len(APITokenPrefix)+4],
+		Hash:   HashRefreshToken(plain),
+		Scopes: strings.Join(scopes, " "),
+	}
+	if ttl > 0 {
+		token.ExpiresAt = time.Now().Add(ttl).Unix()
+	}
+	if err := orm.DB().Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, plain, nil
+}
+
+// GetAPIToken 按明文查找未过期的个人访问令牌
+func GetAPIToken(plain string) (*APIToken, error) {
+	token := &APIToken{}
+	if err := orm.DB().Where("hash = ?", HashRefreshToken(plain)).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+	if token.Expired(time.Now().Unix()) {
+		return nil, ErrAPITokenInvalid
+	}
+	return token, nil
+}
+
+// ListAPITokens 用户的全部个人访问令牌
+func ListAPITokens(userID int64) ([]*APIToken, error) {
+	tokens := make([]*APIToken, 0)
+	err := orm.DB().Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// CountAPITokens 用户持有的令牌数
+func CountAPITokens(userID int64) (int64, error) {
+	var count int64
+	err := orm.DB().Model(&APIToken{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// DeleteAPIToken 删除用户的个人访问令牌，令牌不存在时返回ErrAPITokenInvalid
+func DeleteAPIToken(userID, id int64) error {
+	rdb := orm.DB().Where("id = ? AND user_id = ?", id, userID).Delete(&APIToken{})
+	if rdb.Error != nil {
+		return rdb.Error
+	}
+	if rdb.RowsAffected == 0 {
+		return ErrAPITokenInvalid
+	}
+	return nil
+}
+
+// DeleteUserAPITokens 删除用户的全部个人访问令牌，用于重置密码等场景
+func DeleteUserAPITokens(userID int64) error {
+	return orm.DB().Where("user_id = ?", userID).Delete(&APIToken{}).Error
+}
+
+// Expired 令牌是否已过期
+func (t *APIToken) Expired(now int64) bool {
+	return t.ExpiresAt > 0 && t.ExpiresAt <= now
+}
+
+// ScopeList 令牌的权限范围列表
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// HasScopes 令牌是否拥有全部指定的权限范围
+func (t *APIToken) HasScopes(scopes ...string) bool {
+	granted := t.ScopeList()
+	for _, scope := range scopes {
+		found := false
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Seen 记录令牌的最近使用时间与IP
+func (t *APIToken) Seen(ip string, now int64) error {
+	return orm.DB().Model(&APIToken{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+		"last_used": now,
+		"last_ip":   ip,
+	}).Error
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokenScopes(t *testing.T) {
+	token := &APIToken{Scopes: ScopeVideosRead + " " + ScopeVideosWrite}
+	assert.True(t, token.HasScopes(ScopeVideosRead))
+	assert.True(t, token.HasScopes(ScopeVideosRead, ScopeVideosWrite))
+	assert.False(t, token.HasScopes(ScopeVideosRead, ScopeChannelsWrite))
+	assert.True(t, ValidScope(ScopeSocialWrite))
+	assert.False(t, ValidScope("videos:*"))
+	assert.False(t, ValidScope("comments:write"))
+}
+
+func TestAPITokenExpired(t *testing.T) {
+	assert.False(t, (&APIToken{}).Expired(100))
+	assert.False(t, (&APIToken{ExpiresAt: 101}).Expired(100))
+	assert.True(t, (&APIToken{ExpiresAt: 100}).Expired(100))
+	assert.True(t, IsAPIToken(APITokenPrefix+"abc"))
+	assert.False(t, IsAPIToken("eyJhbGciOi"))
+}
@@ -0,0 +1,267 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AuditLog 审计日志，只追加不修改
+type AuditLog struct {
+	ID         int64     `gorm:"primaryKey" json:"id"`
+	ActorID    int64     `gorm:"index;not null;default:0;comment:操作者，0为系统" json:"actor_id"`
+	Action     string    `gorm:"size:50;not null;index" json:"action"`
+	TargetType string    `gorm:"size:20" json:"target_type"`
+	TargetID   string    `gorm:"size:64;index" json:"target_id"`
+	IP         string    `gorm:"size:50" json:"ip"`
+	RequestID  string    `gorm:"size:64;index;comment:请求ID，同一请求产生的日志相同" json:"request_id"`
+	Diff       AuditDiff `gorm:"type:text;comment:修改前后有变化的字段" json:"diff,omitempty"`
+	Detail     string    `gorm:"type:text" json:"detail"`
+	Created    int64     `gorm:"autoCreateTime;index" json:"created"`
+}
+
+// AuditDiff 有变化的字段，值为[修改前, 修改后]，新建时修改前为null，删除时修改后为null
+type AuditDiff map[string][2]interface{}
+
+const (
+	AuditAccountLocked   = "account.locked"             // 账号因多次登录失败被锁定
+	AuditAccountUnlocked = "account.unlocked"           // 账号通过邮件解锁
+	AuditIPLocked        = "ip.locked"                  // IP因多次登录失败被锁定
+	AuditDeletionRequest = "account.deletion_requested" // 用户申请注销
+	AuditDeletionCancel  = "account.deletion_canceled"  // 用户撤销注销
+	AuditAccountPurged   = "account.purged"             // 冷静期结束，个人数据已清除
+	AuditAdminSetRole    = "admin.set_role"             // 管理员修改用户角色
+	AuditAdminSetStatus  = "admin.set_status"           // 管理员修改用户状态
+	AuditAdminLogout     = "admin.force_logout"         // 管理员强制用户下线
+	AuditAdminResetPwd   = "admin.reset_password"       // 管理员重置用户密码
+	AuditImpersonate     = "admin.impersonate"          // 管理员代为登录用户账号
+	AuditImpersonateCall = "impersonation.request"      // 代为登录期间的写操作
+
+	AuditUserRegister      = "user.register"        // 注册
+	AuditUserVerifyEmail   = "user.verify_email"    // 验证邮箱
+	AuditUserUpdate        = "user.update_profile"  // 修改资料
+	AuditUserAvatar        = "user.upload_avatar"   // 上传头像
+	AuditUserRename        = "user.change_username" // 修改用户名
+	AuditUserEmailRequest  = "user.email_requested" // 申请修改邮箱
+	AuditUserEmailChange   = "user.change_email"    // 确认修改邮箱
+	AuditUserPassword      = "user.change_password" // 修改密码
+	AuditUserPasswordReset = "user.reset_password"  // 通过邮件重置密码
+	AuditSessionRevoke     = "session.revoke"       // 注销会话
+	AuditSessionRevokeAll  = "session.revoke_all"   // 注销全部会话
+	AuditAPITokenCreate    = "api_token.create"     // 新建访问令牌
+	AuditAPITokenDelete    = "api_token.delete"     // 删除访问令牌
+	AuditPasskeyCreate     = "passkey.create"       // 注册通行密钥
+	AuditPasskeyDelete     = "passkey.delete"       // 删除通行密钥
+	AuditTOTPEnable        = "totp.enable"          // 开启两步验证
+	AuditTOTPDisable       = "totp.disable"         // 关闭两步验证
+	AuditRecoveryCodes     = "totp.recovery_codes"  // 重新生成恢复码
+	AuditIdentityLink      = "identity.link"        // 绑定外部身份
+	AuditIdentityUnlink    = "identity.unlink"      // 解绑外部身份
+
+	AuditChannelCreate       = "channel.create"        // 新建频道
+	AuditChannelUpdate       = "channel.update"        // 修改频道
+	AuditChannelImage        = "channel.upload_image"  // 上传频道头像或横幅
+	AuditChannelSetAuthor    = "channel.set_author"    // 添加作者或修改作者角色
+	AuditChannelRemoveAuthor = "channel.remove_author" // 移除作者
+	AuditChannelTransfer     = "channel.transfer"      // 转让频道
+	AuditVideoSetChannel     = "video.set_channel"     // 修改视频所属频道
+
+	AuditModerationResolve = "moderation.resolve"  // 处理举报工单
+	AuditModerationRestore = "moderation.restore"  // 恢复被隐藏的视频
+	AuditFilterAdd         = "filter.add_rules"    // 添加过滤规则
+	AuditFilterUpdate      = "filter.update_rule"  // 修改过滤规则
+	AuditFilterDelete      = "filter.delete_rules" // 删除过滤规则
+	AuditFilterReload      = "filter.reload"       // 重新加载过滤规则
+
+	AuditCategoryCreate = "category.create" // 新建分类
+	AuditCategoryUpdate = "category.update" // 修改分类
+	AuditCategoryMove   = "category.move"   // 移动分类
+	AuditCategorySort   = "category.sort"   // 调整分类顺序
+	AuditCategoryDelete = "category.delete" // 删除分类
+
+	AuditPolicyAdd      = "role.add_policy"      // 添加策略
+	AuditPolicyRemove   = "role.remove_policy"   // 删除策略
+	AuditInheritAdd     = "role.add_inherit"     // 添加角色继承
+	AuditInheritRemove  = "role.remove_inherit"  // 删除角色继承
+	AuditPoliciesReload = "role.reload_policies" // 重新加载策略
+)
+
+const (
+	auditQueueSize = 1024 // 待写入队列长度，队列满时改为同步写入
+	auditBatchSize = 100  // 每批最多写入的条数
+)
+
+var (
+	ErrAuditAppendOnly = errors.New("audit log is append-only")
+
+	auditQueue   chan *AuditLog
+	auditStarted bool
+	auditMu      sync.RWMutex
+	auditDone    chan struct{}
+
+	auditIgnored = map[string]bool{"updated_at": true} // 每次修改都会变化的字段不记录
+)
+
+// BeforeUpdate 审计日志不允许修改
+func (log *AuditLog) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// BeforeDelete 审计日志不允许删除
+func (log *AuditLog) BeforeDelete(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// Value 以JSON保存
+func (d AuditDiff) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(d)
+	return string(b), err
+}
+
+// Scan 从JSON读取
+func (d *AuditDiff) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("unsupported audit diff type %T", value)
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, d)
+}
+
+// NewAuditDiff 比较修改前后的数据，只保留有变化的标量字段，字段名取自json标签
+// before或after为nil时表示新建或删除，json标签为"-"的私密字段不会记录
+func NewAuditDiff(before, after interface{}) (AuditDiff, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+	diff := AuditDiff{}
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !reflect.DeepEqual(v, w) {
+			diff[k] = [2]interface{}{v, a[k]}
+		}
+	}
+	for k, w := range a {
+		if _, ok := b[k]; !ok {
+			diff[k] = [2]interface{}{nil, w}
+		}
+	}
+	return diff, nil
+}
+
+// auditFields 将结构体或map转为字段表，忽略嵌套对象与列表
+func auditFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return fields, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for k, value := range fields {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			delete(fields, k)
+		default:
+			if auditIgnored[k] {
+				delete(fields, k)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// StartAuditWriter 启动后台写入，审计日志批量写入数据库，未启动时同步写入
+func StartAuditWriter() {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditStarted {
+		return
+	}
+	auditStarted = true
+	auditQueue = make(chan *AuditLog, auditQueueSize)
+	auditDone = make(chan struct{})
+	queue, done := auditQueue, auditDone
+	go func() {
+		defer close(done)
+		batch := make([]*AuditLog, 0, auditBatchSize)
+		for log := range queue {
+			batch = append(batch, log)
+			// 取出已在队列中的日志一并写入
+			for len(batch) < auditBatchSize && len(queue) > 0 {
+				batch = append(batch, <-queue)
+			}
+			writeAudit(batch)
+			batch = batch[:0]
+		}
+	}()
+}
+
+// StopAuditWriter 停止后台写入并等待队列中的日志写完，停止后改为同步写入
+func StopAuditWriter() {
+	auditMu.Lock()
+	if !auditStarted {
+		auditMu.Unlock()
+		return
+	}
+	auditStarted = false
+	close(auditQueue)
+	auditMu.Unlock()
+	<-auditDone
+}
+
+// Audit 写入审计日志，失败时只记录日志不影响业务
+func Audit(log *AuditLog) {
+	if log.Created == 0 {
+		log.Created = time.Now().Unix()
+	}
+	auditMu.RLock()
+	if auditStarted {
+		select {
+		case auditQueue <- log:
+			auditMu.RUnlock()
+			return
+		default:
+		}
+	}
+	auditMu.RUnlock()
+	writeAudit([]*AuditLog{log})
+}
+
+func writeAudit(logs []*AuditLog) {
+	if err := orm.DB().Create(&logs).Error; err != nil {
+		for _, log := range logs {
+			logger.Logger().Warn("[Audit] write audit log err", zap.String("action", log.Action),
+				zap.Int64("actor_id", log.ActorID), zap.String("target_id", log.TargetID), zap.Error(err))
+		}
+	}
+}
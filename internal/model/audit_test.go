@@ -0,0 +1,45 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuditDiff(t *testing.T) {
+	before := &Category{Name: "动画", Sort: 1}
+	before.ID = 3
+	after := &Category{Name: "番剧", Sort: 1}
+	after.ID = 3
+	after.UpdatedAt = 100
+
+	diff, err := NewAuditDiff(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, AuditDiff{"name": {"动画", "番剧"}}, diff)
+
+	diff, err = NewAuditDiff(nil, map[string]interface{}{"role": "editor"})
+	assert.NoError(t, err)
+	assert.Equal(t, AuditDiff{"role": {nil, "editor"}}, diff)
+
+	var none *Category
+	diff, err = NewAuditDiff(map[string]interface{}{"role": "editor"}, none)
+	assert.NoError(t, err)
+	assert.Equal(t, AuditDiff{"role": {"editor", nil}}, diff)
+}
+
+func TestAuditDiffScan(t *testing.T) {
+	diff := AuditDiff{"status": {"active", "suspend"}}
+	value, err := diff.Value()
+	assert.NoError(t, err)
+
+	var scanned AuditDiff
+	assert.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, diff, scanned)
+
+	value, err = AuditDiff{}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "", value)
+	scanned = nil
+	assert.NoError(t, scanned.Scan(""))
+	assert.Nil(t, scanned)
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+// Block 拉黑关系，FromUID拉黑了ToUID
+type Block struct {
+	FromUID int64 `gorm:"primaryKey;autoIncrement:false" json:"from_uid"`
+	ToUID   int64 `gorm:"primaryKey;autoIncrement:false;index" json:"to_uid"`
+	Created int64 `gorm:"autoCreateTime" json:"created"`
+}
+
+// IsBlocked 判断blocker是否拉黑了target
+func IsBlocked(blocker, target int64) (bool, error) {
+	var count int64
+	err := orm.DB().Model(&Block{}).Where("from_uid = ? AND to_uid = ?", blocker, target).Count(&count).Error
+	return count > 0, err
+}
+
+// BlockedUIDs 返回userID拉黑的用户ID子查询，用于过滤内容
+func BlockedUIDs(userID int64) *gorm.DB {
+	return orm.DB().Model(&Block{}).Select("to_uid").Where("from_uid = ?", userID)
+}
@@ -1,8 +1,98 @@
 package model
 
+import (
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"strconv"
+	"strings"
+)
+
 type Category struct {
 	BaseModel
-	Name       string     `json:"name"`        // 分类名
-	CategoryID int64      `json:"category_id"` // 父ID 默认0为一级
-	Categories []Category `json:"categories"`  // 子分类列表
+	Name       string     `gorm:"not null;size:50;comment:分类名" json:"name"`          // 分类名
+	CategoryID int64      `gorm:"index;comment:父ID" json:"category_id"`              // 父ID 默认0为一级
+	Path       string     `gorm:"size:255;index;comment:物化路径，形如/1/5/" json:"path"`   // 物化路径 包含自身ID
+	Sort       int        `gorm:"not null;default:0;comment:同级排序，越小越靠前" json:"sort"` // 同级排序
+	VideoCount int64      `gorm:"-" json:"video_count"`                              // 本分类视频数
+	TotalCount int64      `gorm:"-" json:"total_video_count"`                        // 含子分类视频数
+	Categories []Category `json:"categories"`                                        // 子分类列表
+}
+
+// CategoryPath 生成分类的物化路径，parentPath为空表示一级分类
+func CategoryPath(parentPath string, id int64) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + strconv.FormatInt(id, 10) + "/"
+}
+
+// IsDescendantOf 判断分类是否为指定路径的后代（包含自身）
+func (c *Category) IsDescendantOf(path string) bool {
+	return path != "" && strings.HasPrefix(c.Path, path)
+}
+
+// BuildCategoryTree 将平铺的分类列表组装为树，rootID为0时返回所有一级分类
+// categories需预先按sort、id排序，counts为各分类自身的视频数
+func BuildCategoryTree(categories []*Category, rootID int64, counts map[int64]int64) []Category {
+	children := make(map[int64][]*Category, len(categories))
+	for _, c := range categories {
+		children[c.CategoryID] = append(children[c.CategoryID], c)
+	}
+
+	var build func(c *Category) Category
+	build = func(c *Category) Category {
+		node := *c
+		node.VideoCount = counts[c.ID]
+		node.TotalCount = node.VideoCount
+		node.Categories = make([]Category, 0, len(children[c.ID]))
+		for _, child := range children[c.ID] {
+			sub := build(child)
+			node.TotalCount += sub.TotalCount
+			node.Categories = append(node.Categories, sub)
+		}
+		return node
+	}
+
+	tree := make([]Category, 0)
+	for _, c := range categories {
+		if (rootID == 0 && c.CategoryID == 0) || c.ID == rootID {
+			tree = append(tree, build(c))
+		}
+	}
+	return tree
+}
+
+// RebuildCategoryPaths 为缺少物化路径的历史分类数据补全path
+func RebuildCategoryPaths() error {
+	var categories []*Category
+	if err := orm.DB().Order("id").Find(&categories).Error; err != nil {
+		return err
+	}
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	var resolve func(c *Category, depth int) (string, error)
+	resolve = func(c *Category, depth int) (string, error) {
+		// depth用于防止脏数据中的环导致死循环
+		if c.Path != "" || depth > len(categories) {
+			return c.Path, nil
+		}
+		parentPath := ""
+		if parent, ok := byID[c.CategoryID]; ok {
+			path, err := resolve(parent, depth+1)
+			if err != nil {
+				return "", err
+			}
+			parentPath = path
+		}
+		c.Path = CategoryPath(parentPath, c.ID)
+		return c.Path, orm.DB().Model(c).Update("path", c.Path).Error
+	}
+	for _, c := range categories {
+		if _, err := resolve(c, 0); err != nil {
+			return err
+		}
+	}
+	return nil
 }
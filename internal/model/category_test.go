@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildCategoryTree(t *testing.T) {
+	categories := []*Category{
+		{BaseModel: BaseModel{ID: 1}, CategoryID: 0, Path: "/1/"},
+		{BaseModel: BaseModel{ID: 2}, CategoryID: 1, Path: "/1/2/"},
+		{BaseModel: BaseModel{ID: 3}, CategoryID: 2, Path: "/1/2/3/"},
+		{BaseModel: BaseModel{ID: 4}, CategoryID: 3, Path: "/1/2/3/4/"},
+		{BaseModel: BaseModel{ID: 5}, CategoryID: 0, Path: "/5/"},
+	}
+	counts := map[int64]int64{1: 1, 3: 2, 4: 4}
+
+	tree := BuildCategoryTree(categories, 0, counts)
+	assert.Len(t, tree, 2)
+	assert.Equal(t, int64(7), tree[0].TotalCount)
+	assert.Equal(t, int64(4), tree[0].Categories[0].Categories[0].Categories[0].ID)
+
+	sub := BuildCategoryTree(categories[1:4], 2, counts)
+	assert.Len(t, sub, 1)
+	assert.Equal(t, int64(6), sub[0].TotalCount)
+}
+
+func TestIsDescendantOf(t *testing.T) {
+	c := &Category{Path: "/1/12/"}
+	assert.True(t, c.IsDescendantOf("/1/"))
+	assert.True(t, c.IsDescendantOf("/1/12/"))
+	assert.False(t, c.IsDescendantOf("/1/1/"))
+	assert.False(t, c.IsDescendantOf(""))
+}
@@ -1,10 +1,56 @@
 package model
 
+import "github.com/vidorg/vid_backend/pkg/orm"
+
 type Channel struct {
 	BaseModel
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
+	Avatar      string  `gorm:"size:1000;comment:频道头像" json:"avatar"`
+	Banner      string  `gorm:"size:1000;comment:频道横幅" json:"banner"`
+	Subscribed  int64   `gorm:"not null;default:0;comment:订阅数" json:"subscriber_count"`
 	Videos      []Video `json:"videos"`
-	Users       []User  `gorm:"many2many:channel_author;"`
-	Subscribers []User  `gorm:"many2many:channel_subscriber;"`
+	Users       []User  `gorm:"many2many:channel_author;" json:"-"`
+	Subscribers []User  `gorm:"many2many:channel_subscriber;" json:"-"`
+}
+
+// ChannelAuthor 频道作者关联表，记录作者在频道中的角色
+type ChannelAuthor struct {
+	ChannelID int64  `gorm:"primaryKey" json:"channel_id"`
+	UserID    int64  `gorm:"primaryKey" json:"user_id"`
+	Role      string `gorm:"size:10;not null;default:viewer;comment:作者角色" json:"role"`
+	Created   int64  `gorm:"autoCreateTime" json:"created"`
+	User      *User  `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// ChannelSubscriber 频道订阅关联表
+type ChannelSubscriber struct {
+	ChannelID int64 `gorm:"primaryKey" json:"channel_id"`
+	UserID    int64 `gorm:"primaryKey;index" json:"user_id"`
+	Created   int64 `gorm:"autoCreateTime" json:"created"`
+}
+
+const (
+	ChannelOwner  = "owner"  // channel owner
+	ChannelEditor = "editor" // may edit channel and publish videos
+	ChannelViewer = "viewer" // may view unpublished content only
+)
+
+// channelRoleRank 角色权限等级，数值越大权限越高
+var channelRoleRank = map[string]int{
+	ChannelViewer: 1,
+	ChannelEditor: 2,
+	ChannelOwner:  3,
+}
+
+// ChannelRoleAtLeast 判断角色是否不低于指定角色
+func ChannelRoleAtLeast(role, min string) bool {
+	return channelRoleRank[role] >= channelRoleRank[min] && channelRoleRank[role] > 0
+}
+
+// GetChannelAuthor 查找用户在频道中的作者记录
+func GetChannelAuthor(channelID, userID int64) (*ChannelAuthor, error) {
+	author := &ChannelAuthor{}
+	rdb := orm.DB().Where("channel_id = ? AND user_id = ?", channelID, userID).First(author)
+	return author, rdb.Error
 }
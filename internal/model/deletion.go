@@ -0,0 +1,156 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeletionGrace 申请注销后的冷静期，期间可以撤销
+const DeletionGrace = 14 * 24 * time.Hour
+
+// Purged 清除用户数据的结果，供调用方清理数据库以外的数据
+type Purged struct {
+	Anonymized bool     // 有投稿的用户只匿名化，保留账号行
+	Fans       []int64  // 原粉丝，其关注数缓存需要失效
+	Following  []int64  // 原关注对象，其粉丝数缓存需要失效
+	Exports    []string // 待删除的导出文件
+}
+
+// DueDeletions 冷静期已结束的待注销用户
+func DueDeletions(now int64, limit int) ([]int64, error) {
+	ids := make([]int64, 0)
+	err := orm.DB().Model(&User{}).
+		Where("deletion_at > 0 AND deletion_at <= ? AND status <> ?", now, UserDeleted).
+		Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PurgeUser 清除冷静期已结束用户的个人数据
+// 有视频的用户保留匿名化的账号行，使视频等需保留内容的引用保持有效，否则彻底删除账号
+// 用户不在待清除状态时返回nil，多个实例同时执行时只有一个生效
+func PurgeUser(userID int64, now int64) (*Purged, error) {
+	var purged *Purged
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		rdb := tx.Model(&User{}).
+			Where("id = ? AND deletion_at > 0 AND deletion_at <= ? AND status <> ?", userID, now, UserDeleted).
+			Update("status", UserDeleted)
+		if rdb.Error != nil || rdb.RowsAffected == 0 {
+			return rdb.Error
+		}
+		purged = &Purged{}
+
+		// 关注、订阅与拉黑关系
+		if err := tx.Model(&UserFan{}).Where("user_id = ?", userID).Pluck("fan_id", &purged.Fans).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&UserFan{}).Where("fan_id = ?", userID).Pluck("user_id", &purged.Following).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR fan_id = ?", userID, userID).Delete(&UserFan{}).Error; err != nil {
+			return err
+		}
+		subscribed := func() *gorm.DB {
+			return tx.Model(&ChannelSubscriber{}).Select("channel_id").Where("user_id = ?", userID)
+		}
+		// 订阅数将降到写扩散阈值以下的频道，需要为其余订阅者补入收件箱
+		shrunk := make([]int64, 0)
+		if err := tx.Model(&Channel{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN (?) AND subscribed = ?", subscribed(), FanOutLimit).Pluck("id", &shrunk).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Channel{}).Where("id IN (?)", subscribed()).
+			UpdateColumn("subscribed", gorm.Expr("subscribed - 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&ChannelSubscriber{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&FeedItem{}).Error; err != nil {
+			return err
+		}
+		for _, channelID := range shrunk {
+			if err := BackfillFeed(tx, channelID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("from_uid = ? OR to_uid = ?", userID, userID).Delete(&Block{}).Error; err != nil {
+			return err
+		}
+		if err := transferOwnedChannels(tx, userID); err != nil {
+			return err
+		}
+
+		// 登录凭证与账号相关记录
+		if err := tx.Model(&DataExport{}).Where("user_id = ? AND file <> ''", userID).Pluck("file", &purged.Exports).Error; err != nil {
+			return err
+		}
+		for _, m := range []interface{}{&Session{}, &RefreshToken{}, &UserToken{}, &RecoveryCode{},
+			&Passkey{}, &UserIdentity{}, &APIToken{}, &HandleHistory{}, &DataExport{}, &Notification{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+
+		// 举报记录保留用于审核，只清除用户填写的说明，举报人ID保留以维持同一工单不能重复举报的约束
+		if err := tx.Model(&Report{}).Where("reporter_id = ?", userID).Update("detail", "").Error; err != nil {
+			return err
+		}
+
+		var videos int64
+		if err := tx.Unscoped().Model(&Video{}).Where("user_id = ?", userID).Count(&videos).Error; err != nil {
+			return err
+		}
+		if videos == 0 {
+			return tx.Unscoped().Delete(&User{}, userID).Error
+		}
+		purged.Anonymized = true
+		return tx.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"username":     fmt.Sprintf("deleted_%d", userID),
+			"nickname":     "已注销用户",
+			"password":     "",
+			"email":        nil,
+			"new_email":    "",
+			"avatar":       gorm.Expr("DEFAULT"),
+			"bio":          "",
+			"gender":       GenderUnknown,
+			"birthday":     nil,
+			"phone":        "",
+			"role":         RoleNormal,
+			"totp_secret":  "",
+			"totp_enabled": false,
+			"totp_counter": 0,
+			"deletion_at":  0,
+		}).Error
+	})
+	return purged, err
+}
+
+// transferOwnedChannels 将用户拥有的频道转让给角色最高、加入最早的其他作者，并移除该用户的作者身份
+// 没有其他作者的频道保留为无主频道，内容不受影响
+func transferOwnedChannels(tx *gorm.DB, userID int64) error {
+	owned := make([]int64, 0)
+	if err := tx.Model(&ChannelAuthor{}).Where("user_id = ? AND role = ?", userID, ChannelOwner).
+		Pluck("channel_id", &owned).Error; err != nil {
+		return err
+	}
+	for _, channelID := range owned {
+		successors := make([]*ChannelAuthor, 0, 1)
+		if err := tx.Where("channel_id = ? AND user_id <> ?", channelID, userID).
+			Order("CASE role WHEN '" + ChannelEditor + "' THEN 0 ELSE 1 END, created").Limit(1).Find(&successors).Error; err != nil {
+			return err
+		}
+		if len(successors) == 0 {
+			continue
+		}
+		if err := tx.Model(&ChannelAuthor{}).
+			Where("channel_id = ? AND user_id = ?", channelID, successors[0].UserID).
+			Update("role", ChannelOwner).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("user_id = ?", userID).Delete(&ChannelAuthor{}).Error
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+)
+
+// DataExport 个人数据导出任务，文件在有效期内可下载
+type DataExport struct {
+	ID        int64  `gorm:"primaryKey" json:"id"`
+	UserID    int64  `gorm:"index;not null" json:"-"`
+	Status    string `gorm:"size:10;not null;comment:running生成中，done已完成，failed失败" json:"status"`
+	File      string `gorm:"size:255;not null;default:'';comment:导出目录下的文件名" json:"-"`
+	Size      int64  `gorm:"not null;default:0" json:"size"`
+	ExpiresAt int64  `gorm:"not null;default:0;index" json:"expires_at"`
+	Finished  int64  `gorm:"not null;default:0" json:"finished"`
+	Created   int64  `gorm:"autoCreateTime" json:"created"`
+}
+
+const (
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+
+	ExportExpire   = 7 * 24 * time.Hour // 导出文件的保留时间
+	ExportInterval = 24 * time.Hour     // 两次导出的最短间隔
+	ExportTimeout  = time.Hour          // 超过该时间仍未完成的任务视为失败，例如生成中服务重启
+)
+
+// LastExport 用户最近一次未失败的导出任务，不存在时返回nil
+func LastExport(userID int64) (*DataExport, error) {
+	exports := make([]*DataExport, 0, 1)
+	err := orm.DB().Where("user_id = ? AND status <> ?", userID, ExportFailed).
+		Order("created DESC").Limit(1).Find(&exports).Error
+	if err != nil || len(exports) == 0 {
+		return nil, err
+	}
+	return exports[0], nil
+}
+
+// ListExports 用户的导出任务
+func ListExports(userID int64) ([]*DataExport, error) {
+	exports := make([]*DataExport, 0)
+	err := orm.DB().Where("user_id = ?", userID).Order("created DESC").Find(&exports).Error
+	return exports, err
+}
+
+// ExpiredExports 已过期或生成超时的导出任务
+func ExpiredExports(now int64) ([]*DataExport, error) {
+	exports := make([]*DataExport, 0)
+	err := orm.DB().
+		Where("status = ? AND expires_at <= ?", ExportDone, now).
+		Or("status = ? AND created <= ?", ExportRunning, now-int64(ExportTimeout/time.Second)).
+		Or("status = ? AND created <= ?", ExportFailed, now-int64(ExportExpire/time.Second)).
+		Find(&exports).Error
+	return exports, err
+}
@@ -0,0 +1,29 @@
+package model
+
+import "gorm.io/gorm"
+
+// FeedItem 订阅动态收件箱，小频道发布视频时写扩散到每个订阅者
+type FeedItem struct {
+	UserID    int64 `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	VideoID   int64 `gorm:"primaryKey;autoIncrement:false" json:"video_id"`
+	ChannelID int64 `gorm:"index;not null" json:"channel_id"`
+	Created   int64 `gorm:"index;not null;comment:视频发布时间" json:"created"`
+}
+
+// FanOutLimit 订阅数低于该值的频道采用写扩散，否则读取时拉取
+const FanOutLimit = 1000
+
+// FeedBackfill 补入收件箱的历史视频数
+const FeedBackfill = 50
+
+// BackfillFeed 频道订阅数降到FanOutLimit以下时调用
+// 此前的视频在读取时拉取，没有写入收件箱，为全部订阅者补入最近的视频
+func BackfillFeed(tx *gorm.DB, channelID int64) error {
+	return tx.Exec(
+		"INSERT IGNORE INTO tb_feed_item (user_id, video_id, channel_id, created) "+
+			"SELECT s.user_id, v.id, v.channel_id, v.created FROM tb_channel_subscriber s "+
+			"JOIN (SELECT id, channel_id, created FROM tb_video "+
+			"WHERE channel_id = ? AND status <> ? AND deleted_at IS NULL ORDER BY created DESC LIMIT ?) v "+
+			"ON v.channel_id = s.channel_id WHERE s.channel_id = ?",
+		channelID, VideoRemoved, FeedBackfill, channelID).Error
+}
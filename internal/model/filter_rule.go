@@ -0,0 +1,46 @@
+package model
+
+import (
+	"github.com/vidorg/vid_backend/pkg/filter"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm/clause"
+)
+
+// FilterRule 关键词过滤规则
+type FilterRule struct {
+	ID      int64  `gorm:"primaryKey" json:"id"`
+	Word    string `gorm:"size:50;not null;uniqueIndex;comment:规范化后的词" json:"word"`
+	Action  string `gorm:"size:10;not null;comment:block拒绝提交，mask替换为*，flag转人工审核" json:"action"`
+	Note    string `gorm:"size:255;not null;default:''" json:"note"`
+	Created int64  `gorm:"autoCreateTime" json:"created"`
+	Updated int64  `gorm:"autoUpdateTime" json:"updated"`
+}
+
+// ListFilterRules 全部过滤规则，转为过滤器使用的形式
+func ListFilterRules() ([]*filter.Rule, error) {
+	rules := make([]*FilterRule, 0)
+	if err := orm.DB().Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*filter.Rule, len(rules))
+	for i, rule := range rules {
+		res[i] = &filter.Rule{ID: rule.ID, Word: rule.Word, Action: rule.Action}
+	}
+	return res, nil
+}
+
+// SeedFilterRules 规则表为空时写入内置的默认规则
+func SeedFilterRules() error {
+	var count int64
+	if err := orm.DB().Model(&FilterRule{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	rules := make([]*FilterRule, 0)
+	for _, rule := range filter.DefaultRules() {
+		rules = append(rules, &FilterRule{Word: filter.Normalize(rule.Word), Action: rule.Action, Note: "默认规则"})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return orm.DB().Clauses(clause.OnConflict{DoNothing: true}).Create(&rules).Error
+}
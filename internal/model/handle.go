@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+// HandleHistory 用户名修改记录，旧用户名在保留期内重定向到新用户名且不能被他人使用
+type HandleHistory struct {
+	ID         int64  `gorm:"primaryKey"`
+	UserID     int64  `gorm:"index;not null"`
+	Handle     string `gorm:"index;size:20;not null;comment:改名前的用户名"`
+	ReleasedAt int64  `gorm:"not null;comment:保留期结束时间，此后旧用户名可被他人使用且不再重定向"`
+	Created    int64  `gorm:"autoCreateTime"`
+}
+
+const (
+	HandleCooldown = 30 * 24 * time.Hour // 两次改名的最短间隔
+	HandleGrace    = 90 * 24 * time.Hour // 旧用户名的保留期
+)
+
+// LastRename 用户最近一次改名的时间，从未改名时返回0
+func LastRename(userID int64) (int64, error) {
+	history := &HandleHistory{}
+	err := orm.DB().Where("user_id = ?", userID).Order("created DESC").First(history).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return history.Created, err
+}
+
+// HandleAvailable 用户名是否未被其他用户占用，包括仍在保留期内的旧用户名
+// userID为0表示新用户，用户可以改回自己保留期内的旧用户名
+func HandleAvailable(handle string, userID int64) (bool, error) {
+	var count int64
+	if err := orm.DB().Model(&User{}).Where("username = ? AND id <> ?", handle, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	err := orm.DB().Model(&HandleHistory{}).
+		Where("handle = ? AND user_id <> ? AND released_at > ?", handle, userID, time.Now().Unix()).
+		Count(&count).Error
+	return count == 0, err
+}
+
+// RedirectHandle 查找保留期内的旧用户名，不存在时返回gorm.ErrRecordNotFound
+func RedirectHandle(handle string) (*HandleHistory, error) {
+	history := &HandleHistory{}
+	err := orm.DB().Where("handle = ? AND released_at > ?", handle, time.Now().Unix()).
+		Order("created DESC").First(history).Error
+	return history, err
+}
+
+// ListHandleHistory 用户的改名记录
+func ListHandleHistory(userID int64) ([]*HandleHistory, error) {
+	histories := make([]*HandleHistory, 0)
+	err := orm.DB().Where("user_id = ?", userID).Order("created DESC").Find(&histories).Error
+	return histories, err
+}
+
+// Rename 修改用户名并记录旧用户名
+func (user *User) Rename(handle string) error {
+	return orm.DB().Transaction(func(tx *gorm.DB) error {
+		// 改回自己的旧用户名时结束该记录的保留期
+		if err := tx.Model(&HandleHistory{}).
+			Where("user_id = ? AND handle = ? AND released_at > ?", user.ID, handle, time.Now().Unix()).
+			Update("released_at", time.Now().Unix()).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&HandleHistory{
+			UserID:     user.ID,
+			Handle:     user.UserName,
+			ReleasedAt: time.Now().Add(HandleGrace).Unix(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", user.ID).Update("username", handle).Error
+	})
+}
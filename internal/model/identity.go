@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// UserIdentity 用户绑定的外部身份
+type UserIdentity struct {
+	ID       int64  `gorm:"primaryKey" json:"id"`
+	UserID   int64  `gorm:"not null;uniqueIndex:idx_user_provider" json:"-"` // 每个提供方只能绑定一个身份
+	Provider string `gorm:"size:20;not null;uniqueIndex:idx_provider_subject;uniqueIndex:idx_user_provider" json:"provider"`
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_provider_subject;comment:提供方内的用户ID" json:"-"`
+	Username string `gorm:"size:100;comment:提供方内的用户名" json:"username"`
+	Email    string `gorm:"size:255" json:"email"`
+	Created  int64  `gorm:"autoCreateTime" json:"created"`
+}
+
+// OAuthState 跳转到提供方授权前保存的state，一次有效
+type OAuthState struct {
+	ID        string `gorm:"primaryKey;size:64" json:"-"`
+	Provider  string `gorm:"size:20;not null" json:"provider"`
+	Verifier  string `gorm:"size:64;not null;comment:PKCE code verifier" json:"verifier"`
+	Nonce     string `gorm:"size:64;not null" json:"nonce"`
+	UserID    int64  `gorm:"not null;default:0;comment:绑定时为当前用户，登录时为0" json:"user_id"`
+	ExpiresAt int64  `gorm:"not null" json:"expires_at"`
+}
+
+const oauthStateKey = "vid:oauth_state:%s"
+
+var ErrOAuthStateNotFound = errors.New("oauth state not found or expired")
+
+// SaveOAuthState 保存state，redis可用时使用redis
+func SaveOAuthState(state *OAuthState, ttl time.Duration) error {
+	state.ExpiresAt = time.Now().Add(ttl).Unix()
+	if redis.Enabled() {
+		b, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return redis.Set(fmt.Sprintf(oauthStateKey, state.ID), b, ttl)
+	}
+	return orm.DB().Create(state).Error
+}
+
+// TakeOAuthState 取出并删除state，保证每个授权码只能回调一次
+func TakeOAuthState(id, provider string) (*OAuthState, error) {
+	state := &OAuthState{}
+	if redis.Enabled() {
+		v, err := redis.GetDel(fmt.Sprintf(oauthStateKey, id))
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrOAuthStateNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(v), state); err != nil {
+			return nil, err
+		}
+	} else {
+		if rdb := orm.DB().Where("id = ?", id).Limit(1).Find(state); rdb.Error != nil {
+			return nil, rdb.Error
+		} else if rdb.RowsAffected == 0 {
+			return nil, ErrOAuthStateNotFound
+		}
+		rdb := orm.DB().Where("id = ?", id).Delete(&OAuthState{})
+		if rdb.Error != nil {
+			return nil, rdb.Error
+		}
+		if rdb.RowsAffected == 0 {
+			return nil, ErrOAuthStateNotFound
+		}
+		orm.DB().Where("expires_at < ?", time.Now().Unix()).Delete(&OAuthState{})
+	}
+	state.ID = id
+	if state.Provider != provider || state.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrOAuthStateNotFound
+	}
+	return state, nil
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"github.com/vidorg/vid_backend/pkg/orm"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID         int64  `gorm:"primaryKey" json:"id"`
+	UserID     int64  `gorm:"not null;index:idx_notification_user,priority:1" json:"-"`
+	Type       string `gorm:"size:20;not null" json:"type"`
+	Title      string `gorm:"size:100;not null" json:"title"`
+	Content    string `gorm:"size:1000;not null;default:''" json:"content"`
+	TargetType string `gorm:"size:10;not null;default:''" json:"target_type"`
+	TargetID   int64  `gorm:"not null;default:0" json:"target_id"`
+	Read       bool   `gorm:"column:is_read;not null;default:false;index:idx_notification_user,priority:2" json:"read"`
+	Created    int64  `gorm:"autoCreateTime" json:"created"`
+}
+
+const (
+	NotifyReportResult = "report_result" // 举报处理结果，发给举报人
+	NotifyModeration   = "moderation"    // 内容被处理或收到警告，发给作者
+)
+
+// Notify 批量发送通知
+func Notify(notifications ...*Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return orm.DB().Create(&notifications).Error
+}
+
+// ReadNotifications 将通知标记为已读，ids为空时标记全部
+func ReadNotifications(userID int64, ids []int64) error {
+	tx := orm.DB().Model(&Notification{}).Where("user_id = ? AND is_read = ?", userID, false)
+	if len(ids) > 0 {
+		tx = tx.Where("id IN ?", ids)
+	}
+	return tx.Update("is_read", true).Error
+}
+
+// CountUnread 未读通知数
+func CountUnread(userID int64) (int64, error) {
+	var count int64
+	err := orm.DB().Model(&Notification{}).Where("user_id = ? AND is_read = ?", userID, false).Count(&count).Error
+	return count, err
+}
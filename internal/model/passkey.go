@@ -0,0 +1,93 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// Passkey 用户注册的WebAuthn凭证
+type Passkey struct {
+	ID           int64  `gorm:"primaryKey" json:"id"`
+	UserID       int64  `gorm:"index;not null" json:"-"`
+	CredentialID string `gorm:"size:255;not null;uniqueIndex;comment:base64url编码的凭证ID" json:"-"`
+	PublicKey    []byte `gorm:"not null;comment:COSE编码的公钥" json:"-"`
+	Alg          int64  `gorm:"not null" json:"-"`
+	SignCount    uint32 `gorm:"not null;default:0" json:"-"`
+	AAGUID       string `gorm:"size:32;comment:认证器型号" json:"aaguid"`
+	Attestation  string `gorm:"size:20;comment:注册时的证明格式" json:"attestation"`
+	Name         string `gorm:"size:50;comment:用户为凭证起的名字" json:"name"`
+	LastUsed     int64  `gorm:"not null;default:0" json:"last_used"`
+	Created      int64  `gorm:"autoCreateTime" json:"created"`
+}
+
+// PasskeyCeremony 注册或登录过程中待验证的挑战，一次有效
+type PasskeyCeremony struct {
+	ID        string `gorm:"primaryKey;size:32" json:"-"`
+	Type      string `gorm:"size:20;not null" json:"type"`
+	UserID    int64  `gorm:"not null;default:0;comment:登录时为0表示未指定用户" json:"user_id"`
+	Challenge []byte `gorm:"not null" json:"challenge"`
+	ExpiresAt int64  `gorm:"not null" json:"expires_at"`
+}
+
+const (
+	CeremonyRegister = "register"
+	CeremonyLogin    = "login"
+
+	passkeyCeremonyKey = "vid:passkey_ceremony:%s"
+)
+
+var ErrCeremonyNotFound = errors.New("passkey ceremony not found or expired")
+
+// SaveCeremony 保存挑战，redis可用时使用redis
+func SaveCeremony(ceremony *PasskeyCeremony, ttl time.Duration) error {
+	ceremony.ExpiresAt = time.Now().Add(ttl).Unix()
+	if redis.Enabled() {
+		b, err := json.Marshal(ceremony)
+		if err != nil {
+			return err
+		}
+		return redis.Set(fmt.Sprintf(passkeyCeremonyKey, ceremony.ID), b, ttl)
+	}
+	return orm.DB().Create(ceremony).Error
+}
+
+// TakeCeremony 取出并删除挑战，保证每个挑战只能被验证一次
+func TakeCeremony(id, typ string) (*PasskeyCeremony, error) {
+	ceremony := &PasskeyCeremony{}
+	if redis.Enabled() {
+		v, err := redis.GetDel(fmt.Sprintf(passkeyCeremonyKey, id))
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrCeremonyNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(v), ceremony); err != nil {
+			return nil, err
+		}
+	} else {
+		if rdb := orm.DB().Where("id = ?", id).Limit(1).Find(ceremony); rdb.Error != nil {
+			return nil, rdb.Error
+		} else if rdb.RowsAffected == 0 {
+			return nil, ErrCeremonyNotFound
+		}
+		rdb := orm.DB().Where("id = ?", id).Delete(&PasskeyCeremony{})
+		if rdb.Error != nil {
+			return nil, rdb.Error
+		}
+		// 并发请求中只有删除成功的一方可以继续
+		if rdb.RowsAffected == 0 {
+			return nil, ErrCeremonyNotFound
+		}
+		// 顺带清理过期的挑战
+		orm.DB().Where("expires_at < ?", time.Now().Unix()).Delete(&PasskeyCeremony{})
+	}
+	if ceremony.Type != typ || ceremony.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrCeremonyNotFound
+	}
+	return ceremony, nil
+}
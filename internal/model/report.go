@@ -0,0 +1,158 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ModerationCase 审核工单，同一对象的举报在处理前合并到同一工单
+// 待处理工单的Archive为0，唯一索引保证同一对象同时只有一个待处理工单
+type ModerationCase struct {
+	ID          int64  `gorm:"primaryKey" json:"id"`
+	TargetType  string `gorm:"size:10;not null;uniqueIndex:idx_case_target;comment:被举报对象类型" json:"target_type"`
+	TargetID    int64  `gorm:"not null;uniqueIndex:idx_case_target" json:"target_id"`
+	AuthorID    int64  `gorm:"not null;index;comment:被举报内容的作者" json:"author_id"`
+	Status      string `gorm:"size:10;not null;index:idx_case_queue,priority:1;comment:pending待处理，resolved已处理，dismissed已驳回" json:"status"`
+	Severity    int    `gorm:"not null;default:0;index:idx_case_queue,priority:2;comment:举报理由中最高的严重程度" json:"severity"`
+	ReportCount int    `gorm:"not null;default:0;index:idx_case_queue,priority:3" json:"report_count"`
+	Action      string `gorm:"size:10;not null;default:'';comment:处理方式" json:"action"`
+	ModeratorID int64  `gorm:"not null;default:0" json:"moderator_id"`
+	Note        string `gorm:"size:500;not null;default:'';comment:处理说明" json:"note"`
+	Archive     int64  `gorm:"not null;default:0;uniqueIndex:idx_case_target;comment:待处理时为0，处理后置为工单ID，使同一对象可再次建单" json:"-"`
+	Resolved    int64  `gorm:"not null;default:0;comment:处理时间" json:"resolved"`
+	Created     int64  `gorm:"autoCreateTime" json:"created"`
+}
+
+// Report 举报记录，同一用户对同一工单只能举报一次
+type Report struct {
+	ID         int64  `gorm:"primaryKey" json:"id"`
+	CaseID     int64  `gorm:"not null;uniqueIndex:idx_report_reporter" json:"case_id"`
+	ReporterID int64  `gorm:"not null;uniqueIndex:idx_report_reporter;index" json:"reporter_id"`
+	Reason     string `gorm:"size:20;not null" json:"reason"`
+	Detail     string `gorm:"size:500;not null;default:''" json:"detail"`
+	Created    int64  `gorm:"autoCreateTime;index" json:"created"`
+}
+
+// ReportReason 举报理由
+type ReportReason struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Severity int    `json:"severity"` // 严重程度，越大越优先处理
+}
+
+const (
+	ReportVideo   = "video"   // 举报视频
+	ReportUser    = "user"    // 举报用户，例如昵称、头像或简介违规
+	ReportChannel = "channel" // 举报频道，例如名称或简介违规
+
+	ReportKeyword = "keyword" // 命中关键词过滤规则自动提交的举报，举报人为0
+
+	CasePending   = "pending"
+	CaseResolved  = "resolved"
+	CaseDismissed = "dismissed"
+
+	ModerateDismiss = "dismiss" // 驳回举报
+	ModerateHide    = "hide"    // 隐藏视频
+	ModerateRemove  = "remove"  // 下架视频
+	ModerateWarn    = "warn"    // 警告作者
+	ModerateSuspend = "suspend" // 封禁作者
+
+	ReportDailyLimit = 20 // 每个用户24小时内最多举报的次数
+)
+
+var (
+	ErrReportDuplicate = errors.New("already reported")
+
+	// ReportReasons 可选的举报理由
+	ReportReasons = []*ReportReason{
+		{Key: "spam", Name: "垃圾广告", Severity: 1},
+		{Key: "harassment", Name: "骚扰或人身攻击", Severity: 2},
+		{Key: "copyright", Name: "侵犯版权", Severity: 2},
+		{Key: "hate", Name: "仇恨言论", Severity: 3},
+		{Key: "sexual", Name: "色情低俗", Severity: 3},
+		{Key: "violence", Name: "暴力血腥", Severity: 3},
+		{Key: "illegal", Name: "违法违规", Severity: 4},
+		{Key: "other", Name: "其他", Severity: 1},
+	}
+)
+
+// FindReportReason 按key查找举报理由，不存在时返回nil
+func FindReportReason(key string) *ReportReason {
+	for _, r := range ReportReasons {
+		if r.Key == key {
+			return r
+		}
+	}
+	return nil
+}
+
+// ModerationAllowed 处理方式是否适用于该类对象，隐藏与下架只适用于视频
+func ModerationAllowed(targetType, action string) bool {
+	switch action {
+	case ModerateDismiss, ModerateWarn, ModerateSuspend:
+		return true
+	case ModerateHide, ModerateRemove:
+		return targetType == ReportVideo
+	}
+	return false
+}
+
+// CreateReport 记录一次举报，对象没有待处理工单时新建工单
+// 同一用户重复举报同一工单时返回ErrReportDuplicate
+func CreateReport(targetType string, targetID, authorID int64, report *Report, severity int) error {
+	return orm.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ModerationCase{
+			TargetType: targetType,
+			TargetID:   targetID,
+			AuthorID:   authorID,
+			Status:     CasePending,
+		}).Error; err != nil {
+			return err
+		}
+		mc := &ModerationCase{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("target_type = ? AND target_id = ? AND archive = 0", targetType, targetID).
+			First(mc).Error; err != nil {
+			return err
+		}
+
+		report.CaseID = mc.ID
+		rdb := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+		if rdb.Error != nil {
+			return rdb.Error
+		}
+		if rdb.RowsAffected == 0 {
+			return ErrReportDuplicate
+		}
+		return tx.Model(mc).UpdateColumns(map[string]interface{}{
+			"report_count": gorm.Expr("report_count + 1"),
+			"severity":     gorm.Expr("GREATEST(severity, ?)", severity),
+		}).Error
+	})
+}
+
+// CountRecentReports 用户最近一段时间内的举报次数
+func CountRecentReports(reporterID int64, since time.Duration) (int64, error) {
+	var count int64
+	err := orm.DB().Model(&Report{}).
+		Where("reporter_id = ? AND created >= ?", reporterID, time.Now().Add(-since).Unix()).
+		Count(&count).Error
+	return count, err
+}
+
+// CaseReporters 工单的全部举报人，不包括系统
+func CaseReporters(caseID int64) ([]int64, error) {
+	ids := make([]int64, 0)
+	err := orm.DB().Model(&Report{}).Where("case_id = ? AND reporter_id > 0", caseID).Pluck("reporter_id", &ids).Error
+	return ids, err
+}
+
+// SuspendUser 封禁用户，until为0表示永久封禁，已注销的用户不受影响
+func SuspendUser(tx *gorm.DB, userID int64, reason string, until int64) error {
+	return tx.Model(&User{}).Where("id = ? AND status <> ?", userID, UserDeleted).
+		Updates(map[string]interface{}{"status": UserSuspend, "suspend_reason": reason, "suspend_until": until}).Error
+}
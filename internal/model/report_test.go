@@ -0,0 +1,19 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindReportReason(t *testing.T) {
+	assert.Equal(t, 4, FindReportReason("illegal").Severity)
+	assert.Nil(t, FindReportReason("unknown"))
+}
+
+func TestModerationAllowed(t *testing.T) {
+	assert.True(t, ModerationAllowed(ReportVideo, ModerateRemove))
+	assert.True(t, ModerationAllowed(ReportUser, ModerateSuspend))
+	assert.False(t, ModerationAllowed(ReportUser, ModerateHide))
+	assert.False(t, ModerationAllowed(ReportVideo, "delete"))
+}
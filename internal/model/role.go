@@ -0,0 +1,12 @@
+package model
+
+// DefaultPolicies 策略表为空时写入的默认策略 (role, path, method)
+var DefaultPolicies = [][]string{
+	{RoleNormal, "/api/v1/auth/*", "*"},
+	{RoleAdmin, "/api/v1/admin/*", "*"},
+}
+
+// DefaultGroupings 默认角色继承 (role, parent)
+var DefaultGroupings = [][]string{
+	{RoleAdmin, RoleNormal},
+}
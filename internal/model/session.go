@@ -0,0 +1,113 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// Session 登录会话，同一会话内轮换的refresh token属于同一家族
+type Session struct {
+	ID        string `gorm:"primaryKey;size:32" json:"id"`
+	UserID    int64  `gorm:"index;not null" json:"user_id"`
+	Device    string `gorm:"size:100;comment:设备名" json:"device"`
+	UserAgent string `gorm:"size:500" json:"user_agent"`
+	IP        string `gorm:"size:50;comment:最近一次访问IP" json:"ip"`
+	Revoked   bool   `gorm:"not null;default:false" json:"-"`
+	ExpiresAt int64  `gorm:"not null" json:"expires_at"`
+	LastSeen  int64  `gorm:"not null;default:0;comment:最近一次访问时间" json:"last_seen"`
+	Created   int64  `gorm:"autoCreateTime" json:"created"`
+
+	Impersonator int64 `gorm:"not null;default:0;comment:代为登录的管理员，0表示本人登录" json:"impersonator,omitempty"`
+}
+
+// RefreshToken 服务端保存的refresh token，只保存哈希
+type RefreshToken struct {
+	Hash      string `gorm:"primaryKey;size:64"`
+	SessionID string `gorm:"index;size:32;not null"`
+	UserID    int64  `gorm:"not null"`
+	Used      bool   `gorm:"not null;default:false"`
+	ExpiresAt int64  `gorm:"not null"`
+	Created   int64  `gorm:"autoCreateTime"`
+}
+
+// SessionStore 会话与refresh token存储
+type SessionStore interface {
+	// CreateSession 创建会话
+	CreateSession(session *Session) error
+	// GetSession 查找会话，不存在时返回ErrSessionNotFound
+	GetSession(id string) (*Session, error)
+	// ListUserSessions 查找用户未吊销且未过期的会话
+	ListUserSessions(userID int64) ([]*Session, error)
+	// TouchSession 延长会话有效期
+	TouchSession(id string, expiresAt int64) error
+	// SeenSession 记录会话最近一次访问的时间与IP
+	SeenSession(id string, ip string, at int64) error
+	// RevokeSession 吊销会话
+	RevokeSession(id string) error
+	// RevokeUserSessions 吊销用户的全部会话
+	RevokeUserSessions(userID int64) error
+	// SaveRefreshToken 保存refresh token
+	SaveRefreshToken(token *RefreshToken) error
+	// UseRefreshToken 原子地将refresh token标记为已使用，first表示是否为首次使用
+	UseRefreshToken(hash string) (token *RefreshToken, first bool, err error)
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrTokenNotFound   = errors.New("refresh token not found")
+
+	sessionStore     SessionStore
+	sessionStoreOnce sync.Once
+)
+
+// Sessions 会话存储，启动时redis初始化成功则使用redis，未配置或连接失败时使用MySQL
+// 运行期间不在两者之间切换，否则redis故障期间写入MySQL的吊销在恢复后不会生效
+func Sessions() SessionStore {
+	sessionStoreOnce.Do(func() {
+		if redis.Enabled() {
+			sessionStore = &redisSessionStore{}
+		} else {
+			sessionStore = &mysqlSessionStore{}
+		}
+	})
+	return sessionStore
+}
+
+// Active 会话是否未吊销且未过期
+func (s *Session) Active(now int64) bool {
+	return !s.Revoked && s.ExpiresAt > now
+}
+
+// SessionSeenInterval 会话访问时间的最小记录间隔，避免每个请求都写存储
+const SessionSeenInterval = 60
+
+// NewSessionID 生成随机会话ID
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewRefreshToken 生成随机refresh token，返回明文与哈希
+func NewRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken 计算refresh token的存储哈希
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
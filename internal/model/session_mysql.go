@@ -0,0 +1,69 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+// mysqlSessionStore redis不可用时的会话存储
+type mysqlSessionStore struct{}
+
+func (m *mysqlSessionStore) CreateSession(session *Session) error {
+	return orm.DB().Create(session).Error
+}
+
+func (m *mysqlSessionStore) GetSession(id string) (*Session, error) {
+	session := &Session{}
+	err := orm.DB().Where("id = ?", id).First(session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	return session, err
+}
+
+func (m *mysqlSessionStore) ListUserSessions(userID int64) ([]*Session, error) {
+	sessions := make([]*Session, 0)
+	err := orm.DB().Where("user_id = ? AND revoked = ? AND expires_at > ?", userID, false, time.Now().Unix()).
+		Order("last_seen DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (m *mysqlSessionStore) SeenSession(id string, ip string, at int64) error {
+	return orm.DB().Model(&Session{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"ip": ip, "last_seen": at}).Error
+}
+
+func (m *mysqlSessionStore) TouchSession(id string, expiresAt int64) error {
+	return orm.DB().Model(&Session{}).Where("id = ?", id).Update("expires_at", expiresAt).Error
+}
+
+func (m *mysqlSessionStore) RevokeSession(id string) error {
+	return orm.DB().Model(&Session{}).Where("id = ?", id).Update("revoked", true).Error
+}
+
+func (m *mysqlSessionStore) RevokeUserSessions(userID int64) error {
+	return orm.DB().Model(&Session{}).Where("user_id = ? AND revoked = ?", userID, false).
+		Update("revoked", true).Error
+}
+
+func (m *mysqlSessionStore) SaveRefreshToken(token *RefreshToken) error {
+	return orm.DB().Create(token).Error
+}
+
+func (m *mysqlSessionStore) UseRefreshToken(hash string) (*RefreshToken, bool, error) {
+	token := &RefreshToken{}
+	if err := orm.DB().Where("hash = ?", hash).First(token).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, ErrTokenNotFound
+	} else if err != nil {
+		return nil, false, err
+	}
+	// 以条件更新保证并发下只有一个请求能完成轮换
+	rdb := orm.DB().Model(&RefreshToken{}).Where("hash = ? AND used = ?", hash, false).Update("used", true)
+	if rdb.Error != nil {
+		return nil, false, rdb.Error
+	}
+	return token, rdb.RowsAffected == 1, nil
+}
@@ -0,0 +1,188 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+const (
+	sessionKey      = "vid:session:%s"       // 会话 hash
+	userSessionsKey = "vid:user_sessions:%d" // 用户会话ID set
+	refreshTokenKey = "vid:refresh_token:%s" // refresh token hash
+)
+
+// redisSessionStore 基于redis的会话存储，键在过期后自动清除
+type redisSessionStore struct{}
+
+func (r *redisSessionStore) CreateSession(session *Session) error {
+	if session.Created == 0 {
+		session.Created = time.Now().Unix()
+	}
+	key := fmt.Sprintf(sessionKey, session.ID)
+	if err := redis.HSet(key,
+		"user_id", session.UserID,
+		"device", session.Device,
+		"user_agent", session.UserAgent,
+		"ip", session.IP,
+		"revoked", session.Revoked,
+		"expires_at", session.ExpiresAt,
+		"last_seen", session.LastSeen,
+		"created", session.Created,
+		"impersonator", session.Impersonator,
+	); err != nil {
+		return err
+	}
+	if err := redis.Expire(key, ttl(session.ExpiresAt)); err != nil {
+		return err
+	}
+	index := fmt.Sprintf(userSessionsKey, session.UserID)
+	if err := redis.SAdd(index, session.ID); err != nil {
+		return err
+	}
+	// 新会话的过期时间最晚，索引随之顺延，用户不再登录时与最后一个会话一起过期
+	return redis.Expire(index, ttl(session.ExpiresAt))
+}
+
+func (r *redisSessionStore) GetSession(id string) (*Session, error) {
+	values, err := redis.HGetAll(fmt.Sprintf(sessionKey, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	session := &Session{ID: id}
+	session.UserID, _ = strconv.ParseInt(values["user_id"], 10, 64)
+	session.Device = values["device"]
+	session.UserAgent = values["user_agent"]
+	session.IP = values["ip"]
+	session.Revoked = values["revoked"] == "1"
+	session.ExpiresAt, _ = strconv.ParseInt(values["expires_at"], 10, 64)
+	session.LastSeen, _ = strconv.ParseInt(values["last_seen"], 10, 64)
+	session.Created, _ = strconv.ParseInt(values["created"], 10, 64)
+	session.Impersonator, _ = strconv.ParseInt(values["impersonator"], 10, 64)
+	return session, nil
+}
+
+func (r *redisSessionStore) ListUserSessions(userID int64) ([]*Session, error) {
+	key := fmt.Sprintf(userSessionsKey, userID)
+	ids, err := redis.SMembers(key)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	sessions := make([]*Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := r.GetSession(id)
+		if err == ErrSessionNotFound {
+			// 会话已过期，顺便清理索引
+			_ = redis.SRem(key, id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if session.Active(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen > sessions[j].LastSeen
+	})
+	return sessions, nil
+}
+
+func (r *redisSessionStore) SeenSession(id string, ip string, at int64) error {
+	return redis.HSet(fmt.Sprintf(sessionKey, id), "ip", ip, "last_seen", at)
+}
+
+func (r *redisSessionStore) TouchSession(id string, expiresAt int64) error {
+	session, err := r.GetSession(id)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(sessionKey, id)
+	if err := redis.HSet(key, "expires_at", expiresAt); err != nil {
+		return err
+	}
+	if err := redis.Expire(key, ttl(expiresAt)); err != nil {
+		return err
+	}
+	return redis.Expire(fmt.Sprintf(userSessionsKey, session.UserID), ttl(expiresAt))
+}
+
+func (r *redisSessionStore) RevokeSession(id string) error {
+	session, err := r.GetSession(id)
+	if err == ErrSessionNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := redis.HSet(fmt.Sprintf(sessionKey, id), "revoked", true); err != nil {
+		return err
+	}
+	return redis.SRem(fmt.Sprintf(userSessionsKey, session.UserID), id)
+}
+
+func (r *redisSessionStore) RevokeUserSessions(userID int64) error {
+	ids, err := redis.SMembers(fmt.Sprintf(userSessionsKey, userID))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := r.RevokeSession(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *redisSessionStore) SaveRefreshToken(token *RefreshToken) error {
+	if token.Created == 0 {
+		token.Created = time.Now().Unix()
+	}
+	key := fmt.Sprintf(refreshTokenKey, token.Hash)
+	if err := redis.HSet(key,
+		"session_id", token.SessionID,
+		"user_id", token.UserID,
+		"used", 0,
+		"expires_at", token.ExpiresAt,
+		"created", token.Created,
+	); err != nil {
+		return err
+	}
+	return redis.Expire(key, ttl(token.ExpiresAt))
+}
+
+func (r *redisSessionStore) UseRefreshToken(hash string) (*RefreshToken, bool, error) {
+	key := fmt.Sprintf(refreshTokenKey, hash)
+	values, err := redis.HGetAll(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(values) == 0 {
+		return nil, false, ErrTokenNotFound
+	}
+	// HINCRBY是原子操作，只有第一个请求得到1
+	used, err := redis.HIncrBy(key, "used", 1)
+	if err != nil {
+		return nil, false, err
+	}
+	token := &RefreshToken{Hash: hash, SessionID: values["session_id"], Used: true}
+	token.UserID, _ = strconv.ParseInt(values["user_id"], 10, 64)
+	token.ExpiresAt, _ = strconv.ParseInt(values["expires_at"], 10, 64)
+	token.Created, _ = strconv.ParseInt(values["created"], 10, 64)
+	return token, used == 1, nil
+}
+
+// ttl 过期时间戳转为redis过期时长，至少保留一秒
+func ttl(expiresAt int64) time.Duration {
+	d := time.Until(time.Unix(expiresAt, 0))
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
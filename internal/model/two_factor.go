@@ -0,0 +1,96 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/totp"
+	"gorm.io/gorm"
+)
+
+// RecoveryCode 两步验证的一次性恢复码，只保存哈希
+type RecoveryCode struct {
+	ID      int64  `gorm:"primaryKey"`
+	UserID  int64  `gorm:"index;not null"`
+	Hash    string `gorm:"size:64;not null"`
+	Used    bool   `gorm:"not null;default:false"`
+	Created int64  `gorm:"autoCreateTime"`
+}
+
+const RecoveryCodeCount = 10 // 每次生成的恢复码数量
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes 生成一组形如abcde-fghij的恢复码
+func NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	b := make([]byte, 7)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写、空格与连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// ReplaceRecoveryCodes 用新的恢复码替换用户原有的全部恢复码
+func ReplaceRecoveryCodes(tx *gorm.DB, userID int64, codes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	rows := make([]*RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = &RecoveryCode{UserID: userID, Hash: HashRefreshToken(normalizeRecoveryCode(code))}
+	}
+	return tx.Create(rows).Error
+}
+
+// UseRecoveryCode 原子地消费一个恢复码
+func UseRecoveryCode(userID int64, code string) (bool, error) {
+	rdb := orm.DB().Model(&RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used = ?", userID, HashRefreshToken(normalizeRecoveryCode(code)), false).
+		Update("used", true)
+	return rdb.RowsAffected == 1, rdb.Error
+}
+
+// VerifyTOTP 校验TOTP验证码，同一时间步的验证码只能使用一次
+func (user *User) VerifyTOTP(code string) (bool, error) {
+	if user.TOTPSecret == "" {
+		return false, nil
+	}
+	counter, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	rdb := orm.DB().Model(&User{}).
+		Where("id = ? AND totp_counter < ?", user.ID, counter).
+		Update("totp_counter", counter)
+	if rdb.Error != nil {
+		return false, rdb.Error
+	}
+	if rdb.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TOTPCounter = counter
+	return true, nil
+}
+
+// VerifySecondFactor 校验TOTP验证码或恢复码
+func (user *User) VerifySecondFactor(code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		return user.VerifyTOTP(code)
+	}
+	return UseRecoveryCode(user.ID, code)
+}
@@ -1,28 +1,61 @@
 package model
 
 import (
+	"github.com/vidorg/vid_backend/pkg/logger"
 	"github.com/vidorg/vid_backend/pkg/orm"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/vidorg/vid_backend/pkg/password"
+	"go.uber.org/zap"
+	"time"
 )
 
 // User user model
 type User struct {
 	BaseModel
-	UserName string  `gorm:"column:username;not null;unique;comment:用户名" json:"username"`
-	Password string  `gorm:"not null;comment:用户密码" json:"password,omitempty"`
-	Nickname string  `gorm:"not null;size:15;comment:用户昵称" json:"nickname"`
-	Status   string  `gorm:"not null;default:active;comment:用户状态，active激活，incative未激活suspend被封禁" json:"status"`
-	Avatar   string  `gorm:"size:1000;default:https://static.seefs.cn/avatar.jpg;comment:用户头像" json:"avatar"`
-	Email    *string `gorm:"column:email;comment:用户Email" json:"email"`
-	Role     string  `gorm:"size:10;not null;comment:用户权限" json:"role"`
-	Fans     []*User `gorm:"many2many:user_fans"` // 粉丝
+	UserName string     `gorm:"column:username;not null;unique;comment:用户名" json:"username"`
+	Password string     `gorm:"not null;comment:用户密码" json:"-"`
+	Nickname string     `gorm:"not null;size:15;comment:用户昵称" json:"nickname"`
+	Status   string     `gorm:"not null;default:active;comment:用户状态，active激活，incative未激活suspend被封禁" json:"status"`
+	Avatar   string     `gorm:"size:1000;default:https://static.seefs.cn/avatar.jpg;comment:用户头像" json:"avatar"`
+	Email    *string    `gorm:"column:email;comment:用户Email" json:"-"`
+	Role     string     `gorm:"size:10;not null;comment:用户权限" json:"role"`
+	Bio      string     `gorm:"size:255;not null;default:'';comment:个人简介" json:"bio"`
+	Gender   int8       `gorm:"not null;default:0;comment:性别，0未知1男2女" json:"gender"`
+	Birthday *time.Time `gorm:"type:date;comment:生日" json:"-"`
+	Phone    string     `gorm:"size:20;not null;default:'';comment:手机号" json:"-"`
+	NewEmail string     `gorm:"size:255;not null;default:'';comment:待验证的新邮箱" json:"-"`
+	Fans     []*User    `gorm:"many2many:user_fans" json:"-"` // 粉丝
+
+	TOTPSecret  string `gorm:"column:totp_secret;size:64;comment:TOTP密钥，未确认时totp_enabled为false" json:"-"`
+	TOTPEnabled bool   `gorm:"column:totp_enabled;not null;default:false;comment:是否开启两步验证" json:"-"`
+	TOTPCounter int64  `gorm:"column:totp_counter;not null;default:0;comment:最近一次使用的TOTP时间步" json:"-"`
+
+	SuspendReason string `gorm:"size:255;not null;default:'';comment:封禁原因" json:"-"`
+	SuspendUntil  int64  `gorm:"not null;default:0;comment:封禁到期时间，0表示永久封禁" json:"-"`
+
+	DeletionAt int64 `gorm:"not null;default:0;index;comment:计划注销时间，0表示未申请注销" json:"-"`
+}
+
+// UserFan 关注关系，FanID关注了UserID
+type UserFan struct {
+	UserID  int64 `gorm:"primaryKey" json:"user_id"`
+	FanID   int64 `gorm:"primaryKey;index" json:"fan_id"`
+	Created int64 `gorm:"autoCreateTime" json:"created"`
 }
 
 const (
-	PasswordCost = bcrypt.MinCost // password cost
-	UserActive   = "active"       // active user
-	UserInactive = "inactive"     // inactive user
-	UserSuspend  = "suspend"      // banned user
+	UserActive   = "active"   // active user
+	UserInactive = "inactive" // inactive user
+	UserSuspend  = "suspend"  // banned user
+	UserDeleted  = "deleted"  // account deleted, personal data purged
+
+	GenderUnknown = 0 // 未知
+	GenderMale    = 1 // 男
+	GenderFemale  = 2 // 女
+
+	BirthdayLayout = "2006-01-02"
+
+	RoleNormal = "normal" // normal user
+	RoleAdmin  = "admin"  // administrator
 )
 
 // GetUser Get user by ID (for middleware GetUser)
@@ -32,24 +65,44 @@ func GetUser(ID interface{}) (*User, error) {
 	return user, rdb.Error
 }
 
-// SetPassword set user password
-func (user *User) SetPassword(password string) error {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+// IsActive 用户是否为激活状态，限期封禁到期时先自动解封
+func (user *User) IsActive() bool {
+	if user.Status == UserSuspend && user.SuspendUntil > 0 && user.SuspendUntil <= time.Now().Unix() {
+		// 带上到期时间作为条件，避免覆盖管理员刚刚做出的新封禁
+		err := orm.DB().Model(&User{}).
+			Where("id = ? AND status = ? AND suspend_until = ?", user.ID, UserSuspend, user.SuspendUntil).
+			Updates(map[string]interface{}{"status": UserActive, "suspend_reason": "", "suspend_until": 0}).Error
+		if err != nil {
+			logger.Logger().Warn("[User] lift suspension err", zap.Int64("user_id", user.ID), zap.Error(err))
+			return false
+		}
+		user.Status, user.SuspendReason, user.SuspendUntil = UserActive, "", 0
+	}
+	return user.Status == UserActive
+}
+
+// SetPassword set user password, hashed with the configured algorithm
+func (user *User) SetPassword(plain string) error {
+	hash, err := password.Hash(plain)
 	if err != nil {
 		return err
 	}
-	user.Password = string(bytes)
+	user.Password = hash
 	return nil
 }
 
 // MatchPassword match password
-func (user *User) MatchPassword(password string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) // encrypted, unencrypted
-	if err == nil {
-		return true, nil
-	} else if err == bcrypt.ErrMismatchedHashAndPassword {
-		return false, nil
-	} else {
-		return false, err
+func (user *User) MatchPassword(plain string) (bool, error) {
+	return password.Verify(plain, user.Password)
+}
+
+// RehashPassword 登录成功后，若密码哈希的算法或参数已过时则用明文重新计算并保存
+func (user *User) RehashPassword(plain string) error {
+	if !password.NeedsRehash(user.Password) {
+		return nil
+	}
+	if err := user.SetPassword(plain); err != nil {
+		return err
 	}
+	return orm.DB().Model(&User{}).Where("id = ?", user.ID).Update("password", user.Password).Error
 }
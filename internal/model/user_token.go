@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+// UserToken 邮件中发送的一次性令牌，只保存哈希
+type UserToken struct {
+	Hash      string `gorm:"primaryKey;size:64"`
+	UserID    int64  `gorm:"index;not null"`
+	Purpose   string `gorm:"size:20;not null;comment:用途，verify_email邮箱验证，reset_password重置密码，unlock_account解除锁定，change_email验证新邮箱"`
+	Used      bool   `gorm:"not null;default:false"`
+	ExpiresAt int64  `gorm:"not null"`
+	Created   int64  `gorm:"autoCreateTime"`
+}
+
+const (
+	TokenVerifyEmail   = "verify_email"   // 邮箱验证
+	TokenResetPassword = "reset_password" // 重置密码
+	TokenUnlockAccount = "unlock_account" // 解除登录锁定
+	TokenChangeEmail   = "change_email"   // 验证新邮箱
+)
+
+var ErrUserTokenInvalid = errors.New("user token is invalid or expired")
+
+// NewUserToken 为用户生成指定用途的一次性令牌，返回明文
+func NewUserToken(userID int64, purpose string, ttl time.Duration) (string, error) {
+	plain, hash, err := NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	token := &UserToken{
+		Hash:      hash,
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	if err := orm.DB().Create(token).Error; err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// GetUserToken 查找可用的一次性令牌但不消费，用于消费前先校验请求
+// 令牌不存在、已使用或已过期时返回ErrUserTokenInvalid
+func GetUserToken(plain string, purpose string) (*UserToken, error) {
+	token := &UserToken{}
+	err := orm.DB().Where("hash = ? AND purpose = ? AND used = ? AND expires_at > ?",
+		HashRefreshToken(plain), purpose, false, time.Now().Unix()).First(token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserTokenInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// UseUserToken 原子地消费一次性令牌，并使该用户同用途的其他令牌一并失效
+// 令牌不存在、已使用或已过期时返回ErrUserTokenInvalid
+func UseUserToken(tx *gorm.DB, plain string, purpose string) (*UserToken, error) {
+	token := &UserToken{}
+	hash := HashRefreshToken(plain)
+	if err := tx.Where("hash = ? AND purpose = ?", hash, purpose).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserTokenInvalid
+		}
+		return nil, err
+	}
+
+	rdb := tx.Model(&UserToken{}).
+		Where("hash = ? AND used = ? AND expires_at > ?", hash, false, time.Now().Unix()).
+		Update("used", true)
+	if rdb.Error != nil {
+		return nil, rdb.Error
+	}
+	if rdb.RowsAffected == 0 {
+		return nil, ErrUserTokenInvalid
+	}
+
+	err := tx.Model(&UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used = ?", token.UserID, purpose, false).
+		Update("used", true).Error
+	return token, err
+}
@@ -11,4 +11,11 @@ type Video struct {
 	Author      User    `gorm:"foreignKey:UserID" json:"author" json:"author"`
 	CategoryID  int64   `json:"category_id,omitempty"`
 	ChannelID   *int64  `json:"channel_id"`
+	Status      string  `gorm:"size:10;not null;default:normal;index;comment:审核状态，normal正常，hidden隐藏，removed下架" json:"status"`
 }
+
+const (
+	VideoNormal  = "normal"  // 正常展示
+	VideoHidden  = "hidden"  // 被隐藏，不出现在列表与动态中，可恢复
+	VideoRemoved = "removed" // 被下架，同时从订阅收件箱中移除
+)
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+)
+
+func GetAuditLogs(c *gin.Context) {
+	service := &audit.QueryAuditLogsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Query()
+		c.JSON(200, res)
+	}
+}
+
+func ExportAuditLogs(c *gin.Context) {
+	service := &audit.ExportAuditLogsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		service.Export(c)
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/block"
+)
+
+func BlockUser(c *gin.Context) {
+	service := &block.BlockService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Block(c)
+		c.JSON(200, res)
+	}
+}
+
+func UnblockUser(c *gin.Context) {
+	service := &block.BlockService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Unblock(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetBlockList(c *gin.Context) {
+	service := &block.BlockListService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.BlockList(c)
+		c.JSON(200, res)
+	}
+}
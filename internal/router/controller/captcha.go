@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/captcha"
+)
+
+func GetCaptcha(c *gin.Context) {
+	service := &captcha.CreateCaptchaService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Create()
+		c.JSON(200, res)
+	}
+}
@@ -15,3 +15,53 @@ func GetCategoryList(c *gin.Context) {
 		c.JSON(200, res)
 	}
 }
+
+func CreateCategory(c *gin.Context) {
+	service := &category.CreateCategoryService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Create(c)
+		c.JSON(200, res)
+	}
+}
+
+func UpdateCategory(c *gin.Context) {
+	service := &category.UpdateCategoryService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Update(c)
+		c.JSON(200, res)
+	}
+}
+
+func MoveCategory(c *gin.Context) {
+	service := &category.MoveCategoryService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Move(c)
+		c.JSON(200, res)
+	}
+}
+
+func SortCategories(c *gin.Context) {
+	service := &category.SortCategoriesService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Sort(c)
+		c.JSON(200, res)
+	}
+}
+
+func DeleteCategory(c *gin.Context) {
+	service := &category.DeleteCategoryService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/vidorg/vid_backend/internal/serializer"
 	"github.com/vidorg/vid_backend/internal/service/channel"
+	"github.com/vidorg/vid_backend/internal/service/subscription"
 )
 
 func GetChannelList(c *gin.Context) {
@@ -15,3 +16,123 @@ func GetChannelList(c *gin.Context) {
 		c.JSON(200, res)
 	}
 }
+
+func CreateChannel(c *gin.Context) {
+	service := &channel.CreateChannelService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Create(c)
+		c.JSON(200, res)
+	}
+}
+
+func UpdateChannel(c *gin.Context) {
+	service := &channel.UpdateChannelService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Update(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetChannelAuthors(c *gin.Context) {
+	service := &channel.ChannelAuthorsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Authors(c)
+		c.JSON(200, res)
+	}
+}
+
+func SetChannelAuthor(c *gin.Context) {
+	service := &channel.SetChannelAuthorService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.SetAuthor(c)
+		c.JSON(200, res)
+	}
+}
+
+func RemoveChannelAuthor(c *gin.Context) {
+	service := &channel.RemoveChannelAuthorService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.RemoveAuthor(c)
+		c.JSON(200, res)
+	}
+}
+
+func TransferChannel(c *gin.Context) {
+	service := &channel.TransferChannelService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Transfer(c)
+		c.JSON(200, res)
+	}
+}
+
+func UploadChannelImage(c *gin.Context) {
+	service := &channel.UploadChannelImageService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Upload(c)
+		c.JSON(200, res)
+	}
+}
+
+func SetVideoChannel(c *gin.Context) {
+	service := &channel.SetVideoChannelService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.SetVideoChannel(c)
+		c.JSON(200, res)
+	}
+}
+
+func SubscribeChannel(c *gin.Context) {
+	service := &subscription.SubscribeService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Subscribe(c)
+		c.JSON(200, res)
+	}
+}
+
+func UnsubscribeChannel(c *gin.Context) {
+	service := &subscription.SubscribeService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Unsubscribe(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetMySubscriptions(c *gin.Context) {
+	service := &subscription.MySubscriptionsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.MySubscriptions(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetSubscriptionFeed(c *gin.Context) {
+	service := &subscription.FeedService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Feed(c)
+		c.JSON(200, res)
+	}
+}
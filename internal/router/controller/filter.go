@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/keyword"
+)
+
+func GetFilterRules(c *gin.Context) {
+	service := &keyword.QueryRulesService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Query()
+		c.JSON(200, res)
+	}
+}
+
+func AddFilterRules(c *gin.Context) {
+	service := &keyword.AddRulesService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Add(c)
+		c.JSON(200, res)
+	}
+}
+
+func UpdateFilterRule(c *gin.Context) {
+	service := &keyword.UpdateRuleService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Update(c)
+		c.JSON(200, res)
+	}
+}
+
+func DeleteFilterRules(c *gin.Context) {
+	service := &keyword.DeleteRulesService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	}
+}
+
+func ReloadFilterRules(c *gin.Context) {
+	service := &keyword.ReloadRulesService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Reload(c)
+		c.JSON(200, res)
+	}
+}
+
+func TestFilter(c *gin.Context) {
+	service := &keyword.TestFilterService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Test()
+		c.JSON(200, res)
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/follow"
+)
+
+func FollowUser(c *gin.Context) {
+	service := &follow.FollowService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Follow(c)
+		c.JSON(200, res)
+	}
+}
+
+func UnfollowUser(c *gin.Context) {
+	service := &follow.FollowService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Unfollow(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetFollowers(c *gin.Context) {
+	service := &follow.FollowListService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Followers()
+		c.JSON(200, res)
+	}
+}
+
+func GetFollowing(c *gin.Context) {
+	service := &follow.FollowListService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Following()
+		c.JSON(200, res)
+	}
+}
+
+func GetFollowCount(c *gin.Context) {
+	service := &follow.FollowCountService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Count()
+		c.JSON(200, res)
+	}
+}
+
+func GetFollowRelation(c *gin.Context) {
+	service := &follow.FollowRelationService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Relation(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetFollowFeed(c *gin.Context) {
+	service := &follow.FollowFeedService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Feed(c)
+		c.JSON(200, res)
+	}
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/notification"
+	"github.com/vidorg/vid_backend/internal/service/report"
+)
+
+func GetReportReasons(c *gin.Context) {
+	service := &report.ReportReasonsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Reasons()
+		c.JSON(200, res)
+	}
+}
+
+func ReportContent(c *gin.Context) {
+	service := &report.ReportService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Report(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetModerationQueue(c *gin.Context) {
+	service := &report.ModerationQueueService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Queue()
+		c.JSON(200, res)
+	}
+}
+
+func GetModerationCase(c *gin.Context) {
+	service := &report.ModerationCaseService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Detail()
+		c.JSON(200, res)
+	}
+}
+
+func ResolveModerationCase(c *gin.Context) {
+	service := &report.ResolveCaseService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Resolve(c)
+		c.JSON(200, res)
+	}
+}
+
+func RestoreVideo(c *gin.Context) {
+	service := &report.RestoreVideoService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Restore(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetNotifications(c *gin.Context) {
+	service := &notification.NotificationsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.List(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetUnreadNotificationCount(c *gin.Context) {
+	service := &notification.UnreadCountService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.UnreadCount(c)
+		c.JSON(200, res)
+	}
+}
+
+func ReadNotifications(c *gin.Context) {
+	service := &notification.ReadNotificationsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Read(c)
+		c.JSON(200, res)
+	}
+}
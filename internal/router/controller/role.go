@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/role"
+)
+
+func GetPolicies(c *gin.Context) {
+	service := &role.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Policies()
+		c.JSON(200, res)
+	}
+}
+
+func ReloadPolicies(c *gin.Context) {
+	service := &role.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Reload(c)
+		c.JSON(200, res)
+	}
+}
+
+func AddPolicy(c *gin.Context) {
+	service := &role.PolicyService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Add(c)
+		c.JSON(200, res)
+	}
+}
+
+func RemovePolicy(c *gin.Context) {
+	service := &role.PolicyService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Remove(c)
+		c.JSON(200, res)
+	}
+}
+
+func AddRoleInherit(c *gin.Context) {
+	service := &role.InheritService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Add(c)
+		c.JSON(200, res)
+	}
+}
+
+func RemoveRoleInherit(c *gin.Context) {
+	service := &role.InheritService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Remove(c)
+		c.JSON(200, res)
+	}
+}
+
+func SetUserRole(c *gin.Context) {
+	service := &role.SetUserRoleService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.SetRole(c)
+		c.JSON(200, res)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/vidorg/vid_backend/internal/serializer"
 	"github.com/vidorg/vid_backend/internal/service/user"
+	"github.com/vidorg/vid_backend/pkg/jwt"
 )
 
 func UserLogin(c *gin.Context) {
@@ -11,7 +12,7 @@ func UserLogin(c *gin.Context) {
 	if err := c.ShouldBind(service); err != nil {
 		c.JSON(200, serializer.ParamErr("param err,", err))
 	} else {
-		res := service.Login()
+		res := service.Login(c)
 		c.JSON(200, res)
 	}
 }
@@ -21,7 +22,7 @@ func UserRegister(c *gin.Context) {
 	if err := c.ShouldBind(service); err != nil {
 		c.JSON(200, serializer.ParamErr("param err,", err))
 	} else {
-		res := service.Register()
+		res := service.Register(c)
 		c.JSON(200, res)
 	}
 }
@@ -35,3 +36,510 @@ func AuthUser(c *gin.Context) {
 		c.JSON(200, res)
 	}
 }
+
+func RefreshToken(c *gin.Context) {
+	service := &user.RefreshService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Refresh(c)
+		c.JSON(200, res)
+	}
+}
+
+func UserLogout(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Logout(c)
+		c.JSON(200, res)
+	}
+}
+
+func UserLogoutAll(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.LogoutAll(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetSessions(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Sessions(c)
+		c.JSON(200, res)
+	}
+}
+
+func RevokeSession(c *gin.Context) {
+	service := &user.RevokeSessionService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Revoke(c)
+		c.JSON(200, res)
+	}
+}
+
+// GetJWKS 公开jwt校验公钥，供其他服务校验token
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, jwt.DefaultKeyring().JWKS())
+}
+
+func ResetPassword(c *gin.Context) {
+	service := &user.ResetPasswordService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ResetPassword(c)
+		c.JSON(200, res)
+	}
+}
+
+func VerifyEmail(c *gin.Context) {
+	service := &user.VerifyEmailService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.VerifyEmail(c)
+		c.JSON(200, res)
+	}
+}
+
+func ResendVerifyEmail(c *gin.Context) {
+	service := &user.SendEmailService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ResendVerifyEmail()
+		c.JSON(200, res)
+	}
+}
+
+func ForgotPassword(c *gin.Context) {
+	service := &user.SendEmailService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ForgotPassword()
+		c.JSON(200, res)
+	}
+}
+
+func ResetPasswordByToken(c *gin.Context) {
+	service := &user.ResetPasswordByTokenService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ResetPassword(c)
+		c.JSON(200, res)
+	}
+}
+
+func UserLoginTOTP(c *gin.Context) {
+	service := &user.LoginTOTPService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Login(c)
+		c.JSON(200, res)
+	}
+}
+
+func EnrollTOTP(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.EnrollTOTP(c)
+		c.JSON(200, res)
+	}
+}
+
+func ConfirmTOTP(c *gin.Context) {
+	service := &user.TOTPCodeService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ConfirmTOTP(c)
+		c.JSON(200, res)
+	}
+}
+
+func DisableTOTP(c *gin.Context) {
+	service := &user.TwoFactorAuthService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.DisableTOTP(c)
+		c.JSON(200, res)
+	}
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	service := &user.TwoFactorAuthService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.RegenerateRecoveryCodes(c)
+		c.JSON(200, res)
+	}
+}
+
+func BeginPasskeyRegistration(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.BeginPasskeyRegistration(c)
+		c.JSON(200, res)
+	}
+}
+
+func FinishPasskeyRegistration(c *gin.Context) {
+	service := &user.PasskeyRegisterService{}
+	if err := c.ShouldBindJSON(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Register(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetPasskeys(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Passkeys(c)
+		c.JSON(200, res)
+	}
+}
+
+func DeletePasskey(c *gin.Context) {
+	service := &user.DeletePasskeyService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	}
+}
+
+func BeginPasskeyLogin(c *gin.Context) {
+	service := &user.PasskeyLoginBeginService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Begin()
+		c.JSON(200, res)
+	}
+}
+
+func FinishPasskeyLogin(c *gin.Context) {
+	service := &user.PasskeyLoginService{}
+	if err := c.ShouldBindJSON(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Login(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetOAuthProviders(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.OAuthProviders()
+		c.JSON(200, res)
+	}
+}
+
+func OAuthLogin(c *gin.Context) {
+	service := &user.OAuthStartService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Login()
+		c.JSON(200, res)
+	}
+}
+
+func OAuthCallback(c *gin.Context) {
+	service := &user.OAuthCallbackService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Callback(c)
+		c.JSON(200, res)
+	}
+}
+
+func OAuthLink(c *gin.Context) {
+	service := &user.OAuthStartService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Link(c)
+		c.JSON(200, res)
+	}
+}
+
+func OAuthUnlink(c *gin.Context) {
+	service := &user.OAuthStartService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Unlink(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetIdentities(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Identities(c)
+		c.JSON(200, res)
+	}
+}
+
+func UnlockAccount(c *gin.Context) {
+	service := &user.UnlockAccountService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Unlock(c)
+		c.JSON(200, res)
+	}
+}
+
+func CreateAPIToken(c *gin.Context) {
+	service := &user.CreateAPITokenService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.CreateAPIToken(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetAPITokens(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.APITokens(c)
+		c.JSON(200, res)
+	}
+}
+
+func DeleteAPIToken(c *gin.Context) {
+	service := &user.DeleteAPITokenService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.DeleteAPIToken(c)
+		c.JSON(200, res)
+	}
+}
+
+func UpdateProfile(c *gin.Context) {
+	service := &user.UpdateProfileService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.UpdateProfile(c)
+		c.JSON(200, res)
+	}
+}
+
+func UpdateEmail(c *gin.Context) {
+	service := &user.UpdateEmailService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.UpdateEmail(c)
+		c.JSON(200, res)
+	}
+}
+
+func ConfirmEmail(c *gin.Context) {
+	service := &user.VerifyEmailService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ConfirmEmail(c)
+		c.JSON(200, res)
+	}
+}
+
+func UploadAvatar(c *gin.Context) {
+	service := &user.UploadAvatarService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.UploadAvatar(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetUserProfile(c *gin.Context) {
+	service := &user.GetProfileService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.GetProfile()
+		c.JSON(200, res)
+	}
+}
+
+func CheckUserName(c *gin.Context) {
+	service := &user.UserNameService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.CheckUserName(c)
+		c.JSON(200, res)
+	}
+}
+
+func ChangeUserName(c *gin.Context) {
+	service := &user.UserNameService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ChangeUserName(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetUserNameHistory(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.HandleHistory(c)
+		c.JSON(200, res)
+	}
+}
+
+func RequestDataExport(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.RequestExport(c)
+		c.JSON(200, res)
+	}
+}
+
+func GetDataExports(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Exports(c)
+		c.JSON(200, res)
+	}
+}
+
+func DownloadDataExport(c *gin.Context) {
+	service := &user.ExportService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else if path, res := service.ExportFile(c); res != nil {
+		c.JSON(200, res)
+	} else {
+		c.FileAttachment(path, "vid-export.zip")
+	}
+}
+
+func RequestAccountDeletion(c *gin.Context) {
+	service := &user.DeleteAccountService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.RequestDeletion(c)
+		c.JSON(200, res)
+	}
+}
+
+func CancelAccountDeletion(c *gin.Context) {
+	service := &user.NoParamsService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.CancelDeletion(c)
+		c.JSON(200, res)
+	}
+}
+
+func QueryUsers(c *gin.Context) {
+	service := &user.QueryUsersService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.QueryUsers()
+		c.JSON(200, res)
+	}
+}
+
+func GetUserDetail(c *gin.Context) {
+	service := &user.AdminUserService{}
+	if err := c.ShouldBindQuery(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Detail()
+		c.JSON(200, res)
+	}
+}
+
+func SetUserStatus(c *gin.Context) {
+	service := &user.SetUserStatusService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.SetStatus(c)
+		c.JSON(200, res)
+	}
+}
+
+func ForceLogout(c *gin.Context) {
+	service := &user.AdminUserService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ForceLogout(c)
+		c.JSON(200, res)
+	}
+}
+
+func ResetUserPassword(c *gin.Context) {
+	service := &user.AdminUserService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.ResetPassword(c)
+		c.JSON(200, res)
+	}
+}
+
+func ImpersonateUser(c *gin.Context) {
+	service := &user.AdminUserService{}
+	if err := c.ShouldBind(service); err != nil {
+		c.JSON(200, serializer.ParamErr("param err,", err))
+	} else {
+		res := service.Impersonate(c)
+		c.JSON(200, res)
+	}
+}
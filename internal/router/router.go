@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/vidorg/vid_backend/internal/conf"
 	"github.com/vidorg/vid_backend/internal/middleware"
+	"github.com/vidorg/vid_backend/internal/model"
 	"github.com/vidorg/vid_backend/internal/router/controller"
 	"github.com/vidorg/vid_backend/internal/serializer"
 )
@@ -15,19 +16,154 @@ func Init() *gin.Engine {
 	if !(conf.Config().Meta.RunMode == "debug") {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	// 全局中间件需在注册路由之前添加才会生效
+	middleware.Init(router)
+	if conf.Config().Meta.UploadPath != "" {
+		router.Static(conf.Config().Meta.UploadURL, conf.Config().Meta.UploadPath)
+	}
+	router.GET("/.well-known/jwks.json", controller.GetJWKS)
 	r := router.Group("/api/v1")
 	{
-		r.GET("/ping", func(c *gin.Context) {
+		public := r.Group("").Use(middleware.RateLimit())
+		public.GET("/ping", func(c *gin.Context) {
 			c.JSON(200, &serializer.Response{Code: 200, Msg: "pong"})
 		})
-		r.POST("/UserLogin", controller.UserLogin)
-		r.POST("/UserRegister", controller.UserRegister)
-		r.GET("/GetCategories", controller.GetCategoryList)
-		r.GET("/GetVideoList", controller.GetVideoList)
-		r.GET("/GetChannelList", controller.GetChannelList)
-		auth := r.Group("/auth").Use(middleware.Auth())
-		{
-			auth.GET("/UserAuth", controller.AuthUser)
+		public.POST("/UserLogin", controller.UserLogin)
+		public.POST("/UserLoginTOTP", controller.UserLoginTOTP)
+		public.POST("/BeginPasskeyLogin", controller.BeginPasskeyLogin)
+		public.POST("/FinishPasskeyLogin", controller.FinishPasskeyLogin)
+		public.GET("/GetOAuthProviders", controller.GetOAuthProviders)
+		public.POST("/OAuthLogin", controller.OAuthLogin)
+		public.POST("/OAuthCallback", middleware.OptionalAuth(), controller.OAuthCallback)
+		public.POST("/UserRegister", controller.UserRegister)
+		public.POST("/RefreshToken", controller.RefreshToken)
+		public.POST("/VerifyEmail", controller.VerifyEmail)
+		public.POST("/ResendVerifyEmail", controller.ResendVerifyEmail)
+		public.POST("/ForgotPassword", controller.ForgotPassword)
+		public.POST("/ResetPasswordByToken", controller.ResetPasswordByToken)
+		public.POST("/UnlockAccount", controller.UnlockAccount)
+		public.POST("/ConfirmEmail", controller.ConfirmEmail)
+		public.GET("/GetUserProfile", controller.GetUserProfile)
+		public.GET("/CheckUserName", middleware.OptionalAuth(), controller.CheckUserName)
+		public.GET("/GetCategories", controller.GetCategoryList)
+		public.GET("/GetVideoList", middleware.OptionalAuth(model.ScopeVideosRead), controller.GetVideoList)
+		public.GET("/GetChannelList", controller.GetChannelList)
+		public.GET("/GetFollowers", controller.GetFollowers)
+		public.GET("/GetFollowing", controller.GetFollowing)
+		public.GET("/GetFollowCount", controller.GetFollowCount)
+		public.GET("/GetReportReasons", controller.GetReportReasons)
+		public.GET("/GetCaptcha", controller.GetCaptcha)
+		auth := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(), middleware.Authorize(), middleware.RateLimit())
+		{
+			auth.POST("/UserLogout", controller.UserLogout)
+			auth.POST("/UserLogoutAll", controller.UserLogoutAll)
+			auth.GET("/GetUserNameHistory", controller.GetUserNameHistory)
+			auth.POST("/RequestDataExport", controller.RequestDataExport)
+			auth.GET("/GetDataExports", controller.GetDataExports)
+			auth.GET("/DownloadDataExport", controller.DownloadDataExport)
+			auth.POST("/CancelAccountDeletion", controller.CancelAccountDeletion)
+			auth.GET("/GetPasskeys", controller.GetPasskeys)
+			auth.GET("/GetIdentities", controller.GetIdentities)
+			auth.GET("/GetSessions", controller.GetSessions)
+			auth.POST("/RevokeSession", controller.RevokeSession)
+			auth.GET("/GetAPITokens", controller.GetAPITokens)
+			auth.POST("/ReportContent", controller.ReportContent)
+			auth.GET("/GetNotifications", controller.GetNotifications)
+			auth.GET("/GetUnreadNotificationCount", controller.GetUnreadNotificationCount)
+			auth.POST("/ReadNotifications", controller.ReadNotifications)
+		}
+		// 修改凭证与身份的接口，管理员代为登录期间不可用
+		credentials := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(), middleware.Authorize(), middleware.NoImpersonation(), middleware.RateLimit())
+		{
+			credentials.POST("/ResetPassword", controller.ResetPassword)
+			credentials.POST("/UpdateEmail", controller.UpdateEmail)
+			credentials.POST("/ChangeUserName", controller.ChangeUserName)
+			credentials.POST("/RequestAccountDeletion", controller.RequestAccountDeletion)
+			credentials.POST("/EnrollTOTP", controller.EnrollTOTP)
+			credentials.POST("/ConfirmTOTP", controller.ConfirmTOTP)
+			credentials.POST("/DisableTOTP", controller.DisableTOTP)
+			credentials.POST("/RegenerateRecoveryCodes", controller.RegenerateRecoveryCodes)
+			credentials.POST("/BeginPasskeyRegistration", controller.BeginPasskeyRegistration)
+			credentials.POST("/FinishPasskeyRegistration", controller.FinishPasskeyRegistration)
+			credentials.POST("/DeletePasskey", controller.DeletePasskey)
+			credentials.POST("/OAuthLink", controller.OAuthLink)
+			credentials.POST("/OAuthUnlink", controller.OAuthUnlink)
+			credentials.POST("/TransferChannel", controller.TransferChannel)
+			credentials.POST("/CreateAPIToken", controller.CreateAPIToken)
+			credentials.POST("/DeleteAPIToken", controller.DeleteAPIToken)
+		}
+		// 以下接口同时接受拥有相应权限范围的个人访问令牌，其余接口只接受登录会话
+		profileRead := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(model.ScopeProfileRead), middleware.Authorize(), middleware.RateLimit())
+		{
+			profileRead.GET("/UserAuth", controller.AuthUser)
+			profileRead.GET("/GetMySubscriptions", controller.GetMySubscriptions)
+			profileRead.GET("/GetFollowRelation", controller.GetFollowRelation)
+			profileRead.GET("/GetBlockList", controller.GetBlockList)
+		}
+		profileWrite := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(model.ScopeProfileWrite), middleware.Authorize(), middleware.RateLimit())
+		{
+			profileWrite.POST("/UpdateProfile", controller.UpdateProfile)
+			profileWrite.POST("/UploadAvatar", controller.UploadAvatar)
+		}
+		videosRead := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(model.ScopeVideosRead), middleware.Authorize(), middleware.RateLimit())
+		{
+			videosRead.GET("/GetSubscriptionFeed", controller.GetSubscriptionFeed)
+			videosRead.GET("/GetFollowFeed", controller.GetFollowFeed)
+		}
+		videosWrite := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(model.ScopeVideosWrite), middleware.Authorize(), middleware.RateLimit())
+		{
+			videosWrite.POST("/SetVideoChannel", controller.SetVideoChannel)
+		}
+		channelsWrite := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(model.ScopeChannelsWrite), middleware.Authorize(), middleware.RateLimit())
+		{
+			channelsWrite.POST("/CreateChannel", controller.CreateChannel)
+			channelsWrite.POST("/UpdateChannel", controller.UpdateChannel)
+			channelsWrite.GET("/GetChannelAuthors", controller.GetChannelAuthors)
+			channelsWrite.POST("/SetChannelAuthor", controller.SetChannelAuthor)
+			channelsWrite.POST("/RemoveChannelAuthor", controller.RemoveChannelAuthor)
+			channelsWrite.POST("/UploadChannelImage", controller.UploadChannelImage)
+		}
+		socialWrite := r.Group("/auth").Use(middleware.PreAuthRateLimit(), middleware.Auth(model.ScopeSocialWrite), middleware.Authorize(), middleware.RateLimit())
+		{
+			socialWrite.POST("/SubscribeChannel", controller.SubscribeChannel)
+			socialWrite.POST("/UnsubscribeChannel", controller.UnsubscribeChannel)
+			socialWrite.POST("/FollowUser", controller.FollowUser)
+			socialWrite.POST("/UnfollowUser", controller.UnfollowUser)
+			socialWrite.POST("/BlockUser", controller.BlockUser)
+			socialWrite.POST("/UnblockUser", controller.UnblockUser)
+		}
+		admin := r.Group("/admin").Use(middleware.PreAuthRateLimit(), middleware.Auth(), middleware.Authorize(), middleware.RateLimit())
+		{
+			admin.POST("/CreateCategory", controller.CreateCategory)
+			admin.POST("/UpdateCategory", controller.UpdateCategory)
+			admin.POST("/MoveCategory", controller.MoveCategory)
+			admin.POST("/SortCategories", controller.SortCategories)
+			admin.POST("/DeleteCategory", controller.DeleteCategory)
+			admin.GET("/GetPolicies", controller.GetPolicies)
+			admin.POST("/ReloadPolicies", controller.ReloadPolicies)
+			admin.POST("/AddPolicy", controller.AddPolicy)
+			admin.POST("/RemovePolicy", controller.RemovePolicy)
+			admin.POST("/AddRoleInherit", controller.AddRoleInherit)
+			admin.POST("/RemoveRoleInherit", controller.RemoveRoleInherit)
+			admin.POST("/SetUserRole", controller.SetUserRole)
+			admin.GET("/QueryUsers", controller.QueryUsers)
+			admin.GET("/GetUserDetail", controller.GetUserDetail)
+			admin.POST("/SetUserStatus", controller.SetUserStatus)
+			admin.POST("/ForceLogout", controller.ForceLogout)
+			admin.POST("/ResetUserPassword", controller.ResetUserPassword)
+			admin.POST("/ImpersonateUser", controller.ImpersonateUser)
+			admin.GET("/GetAuditLogs", controller.GetAuditLogs)
+			admin.GET("/ExportAuditLogs", controller.ExportAuditLogs)
+			admin.GET("/GetModerationQueue", controller.GetModerationQueue)
+			admin.GET("/GetModerationCase", controller.GetModerationCase)
+			admin.POST("/ResolveModerationCase", controller.ResolveModerationCase)
+			admin.POST("/RestoreVideo", controller.RestoreVideo)
+			admin.GET("/GetFilterRules", controller.GetFilterRules)
+			admin.POST("/AddFilterRules", controller.AddFilterRules)
+			admin.POST("/UpdateFilterRule", controller.UpdateFilterRule)
+			admin.POST("/DeleteFilterRules", controller.DeleteFilterRules)
+			admin.POST("/ReloadFilterRules", controller.ReloadFilterRules)
+			admin.POST("/TestFilter", controller.TestFilter)
 		}
 	}
 	return router
@@ -0,0 +1,59 @@
+package serializer
+
+import "github.com/vidorg/vid_backend/internal/model"
+
+// APIToken 个人访问令牌序列化器，不含令牌明文
+type APIToken struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Hint      string   `json:"hint"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"expires_at"`
+	LastUsed  int64    `json:"last_used"`
+	LastIP    string   `json:"last_ip"`
+	Created   int64    `json:"created"`
+}
+
+// CreatedAPIToken 新建的个人访问令牌，明文只返回这一次
+type CreatedAPIToken struct {
+	*APIToken
+	Token string `json:"token"`
+}
+
+func buildAPIToken(token *model.APIToken) *APIToken {
+	return &APIToken{
+		ID:        token.ID,
+		Name:      token.Name,
+		Hint:      token.Hint,
+		Scopes:    token.ScopeList(),
+		ExpiresAt: token.ExpiresAt,
+		LastUsed:  token.LastUsed,
+		LastIP:    token.LastIP,
+		Created:   token.Created,
+	}
+}
+
+// BuildAPITokensResponse 序列化个人访问令牌列表
+func BuildAPITokensResponse(tokens []*model.APIToken) *Response {
+	res := make([]*APIToken, len(tokens))
+	for i, token := range tokens {
+		res[i] = buildAPIToken(token)
+	}
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: res,
+	}
+}
+
+// BuildCreatedAPITokenResponse 序列化新建的个人访问令牌
+func BuildCreatedAPITokenResponse(token *model.APIToken, plain string) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "创建成功",
+		Data: &CreatedAPIToken{
+			APIToken: buildAPIToken(token),
+			Token:    plain,
+		},
+	}
+}
@@ -42,7 +42,10 @@ func BuildListResponse(total int64, page int, limit int, items interface{}) *Res
 const (
 	CodeLoginError      = 401   // 未登录
 	CodeNoRightError    = 403   // 未授权访问
+	CodeTooManyRequests = 429   // 请求过于频繁
 	CodeParamError      = 40001 // 各种奇奇怪怪的参数错误
+	CodeTwoFactor       = 40002 // 密码正确，需要继续两步验证
+	CodeCaptcha         = 40003 // 需要完成人机验证，或验证未通过
 	CodeDBError         = 50001 // 数据库操作失败
 	CodeEncryptError    = 50002 // 加密失败
 	CodeServerError     = 50003 // 服务器端其他错误
@@ -81,6 +84,22 @@ func LoginExpiredErr() *Response {
 	return Err(CodeNoRightError, "登录过期", nil)
 }
 
+// TooManyRequestsErr 请求过于频繁
+func TooManyRequestsErr(msg string) *Response {
+	if msg == "" {
+		msg = "请求过于频繁，请稍后再试"
+	}
+	return Err(CodeTooManyRequests, msg, nil)
+}
+
+// CaptchaErr 需要人机验证
+func CaptchaErr(msg string) *Response {
+	if msg == "" {
+		msg = "请完成人机验证"
+	}
+	return Err(CodeCaptcha, msg, nil)
+}
+
 // UploadFileErr 上传文件出错
 func UploadFileErr(msg string, err error) *Response {
 	if msg == "" {
@@ -130,3 +149,21 @@ func ServerErr(msg string, err error) *Response {
 	}
 	return Err(CodeServerError, msg, err)
 }
+
+// CursorList 游标分页列表结构
+type CursorList struct {
+	NextCursor string      `json:"next_cursor"`
+	Items      interface{} `json:"items"`
+}
+
+// BuildCursorResponse 游标分页列表构建器，nextCursor为空表示没有更多数据
+func BuildCursorResponse(nextCursor string, items interface{}) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &CursorList{
+			NextCursor: nextCursor,
+			Items:      items,
+		},
+	}
+}
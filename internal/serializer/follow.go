@@ -0,0 +1,40 @@
+package serializer
+
+import "github.com/vidorg/vid_backend/internal/model"
+
+// Follow 关注列表中的用户
+type Follow struct {
+	ID       int64  `json:"id"`
+	UserName string `json:"username"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Mutual   bool   `json:"mutual"` // 是否互相关注
+}
+
+// FollowCount 关注数与粉丝数
+type FollowCount struct {
+	Followers int64 `json:"followers"`
+	Following int64 `json:"following"`
+}
+
+// FollowRelation 当前用户与目标用户的关注关系
+type FollowRelation struct {
+	Following  bool `json:"following"`   // 我关注了对方
+	FollowedBy bool `json:"followed_by"` // 对方关注了我
+	Mutual     bool `json:"mutual"`
+}
+
+// BuildFollows 序列化关注列表，mutual为互相关注的用户ID集合
+func BuildFollows(users []*model.User, mutual map[int64]bool) []*Follow {
+	res := make([]*Follow, len(users))
+	for i, user := range users {
+		res[i] = &Follow{
+			ID:       user.ID,
+			UserName: user.UserName,
+			Nickname: user.Nickname,
+			Avatar:   user.Avatar,
+			Mutual:   mutual[user.ID],
+		}
+	}
+	return res
+}
@@ -0,0 +1,15 @@
+package serializer
+
+// OAuthRedirect 需要跳转的提供方授权页
+type OAuthRedirect struct {
+	URL string `json:"url"`
+}
+
+// BuildOAuthRedirectResponse 序列化授权页地址
+func BuildOAuthRedirectResponse(url string) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &OAuthRedirect{URL: url},
+	}
+}
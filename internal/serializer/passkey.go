@@ -0,0 +1,19 @@
+package serializer
+
+// PasskeyOptions 传给navigator.credentials的参数，完成时需带回ceremony_id
+type PasskeyOptions struct {
+	CeremonyID string      `json:"ceremony_id"`
+	PublicKey  interface{} `json:"publicKey"`
+}
+
+// BuildPasskeyOptionsResponse 序列化WebAuthn参数
+func BuildPasskeyOptionsResponse(ceremonyID string, options interface{}) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &PasskeyOptions{
+			CeremonyID: ceremonyID,
+			PublicKey:  options,
+		},
+	}
+}
@@ -0,0 +1,29 @@
+package serializer
+
+import "github.com/vidorg/vid_backend/internal/model"
+
+// ModerationCase 管理员查看的审核工单详情
+type ModerationCase struct {
+	*model.ModerationCase
+	Author  *AdminUser      `json:"author"`          // 被举报内容的作者，账号已清除时为null
+	Video   *model.Video    `json:"video,omitempty"` // 被举报的视频，仅举报视频时返回
+	Reports []*model.Report `json:"reports"`
+}
+
+// BuildModerationCaseResponse 序列化审核工单详情
+func BuildModerationCaseResponse(mc *model.ModerationCase, author *model.User, video *model.Video,
+	reports []*model.Report) *Response {
+	res := &ModerationCase{
+		ModerationCase: mc,
+		Video:          video,
+		Reports:        reports,
+	}
+	if author != nil {
+		res.Author = buildAdminUser(author)
+	}
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: res,
+	}
+}
@@ -0,0 +1,39 @@
+package serializer
+
+import "github.com/vidorg/vid_backend/internal/model"
+
+// Session 登录会话序列化器
+type Session struct {
+	ID       string `json:"id"`
+	Device   string `json:"device"`
+	IP       string `json:"ip"`
+	Created  int64  `json:"created"`
+	LastSeen int64  `json:"last_seen"`
+	Current  bool   `json:"current"` // 是否为当前请求所用的会话
+	Support  bool   `json:"support"` // 是否为管理员代为登录的会话
+}
+
+func buildSessions(sessions []*model.Session, current string) []*Session {
+	res := make([]*Session, len(sessions))
+	for i, session := range sessions {
+		res[i] = &Session{
+			ID:       session.ID,
+			Device:   session.Device,
+			IP:       session.IP,
+			Created:  session.Created,
+			LastSeen: session.LastSeen,
+			Current:  session.ID == current,
+			Support:  session.Impersonator != 0,
+		}
+	}
+	return res
+}
+
+// BuildSessionsResponse 序列化会话列表
+func BuildSessionsResponse(sessions []*model.Session, current string) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: buildSessions(sessions, current),
+	}
+}
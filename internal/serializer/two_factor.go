@@ -0,0 +1,31 @@
+package serializer
+
+// TOTPEnroll 开启两步验证时返回的密钥信息
+type TOTPEnroll struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"` // data:image/png;base64,...
+}
+
+// RecoveryCodes 恢复码，只在生成时返回一次
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// Challenge 两步验证挑战
+type Challenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+}
+
+// BuildChallengeResponse 密码校验通过但需要两步验证
+func BuildChallengeResponse(token string, expiresIn int64) *Response {
+	return &Response{
+		Code: CodeTwoFactor,
+		Msg:  "需要两步验证",
+		Data: &Challenge{
+			ChallengeToken: token,
+			ExpiresIn:      expiresIn,
+		},
+	}
+}
@@ -4,34 +4,93 @@ import (
 	"github.com/vidorg/vid_backend/internal/model"
 )
 
-// User 用户序列化器
+// User 用户序列化器，包含邮箱等私人信息，只返回给本人或管理员
 type User struct {
 	ID        int64  `json:"id"`
 	UserName  string `json:"username"`
 	Nickname  string `json:"nickname"`
 	Status    string `json:"status"`
 	Email     string `json:"email"`
+	NewEmail  string `json:"new_email,omitempty"` // 待验证的新邮箱
 	Avatar    string `json:"avatar"`
 	Role      string `json:"role"`
+	Bio       string `json:"bio"`
+	Gender    int8   `json:"gender"`
+	Birthday  string `json:"birthday"`
+	Phone     string `json:"phone"`
 	CreatedAt int64  `json:"created_at"`
+
+	DeletionAt int64 `json:"deletion_at,omitempty"` // 计划注销时间，冷静期内可撤销
 }
 
-// BuildUserResponse 序列化用户响应
-func BuildUserResponse(user *model.User) *Response {
+// AdminUser 管理员查看的用户序列化器，附带封禁与安全设置
+type AdminUser struct {
+	*User
+	SuspendReason string `json:"suspend_reason"`
+	SuspendUntil  int64  `json:"suspend_until"` // 封禁到期时间，0表示永久
+	TOTPEnabled   bool   `json:"totp_enabled"`
+}
+
+// UserDetail 管理员查看的用户详情
+type UserDetail struct {
+	User       *AdminUser            `json:"user"`
+	Sessions   []*Session            `json:"sessions"`
+	Identities []*model.UserIdentity `json:"identities"`
+	APITokens  []*APIToken           `json:"api_tokens"`
+	AuditLogs  []*model.AuditLog     `json:"audit_logs"` // 最近的相关审计日志
+}
+
+// Profile 公开的用户资料序列化器
+type Profile struct {
+	ID        int64  `json:"id"`
+	UserName  string `json:"username"`
+	Nickname  string `json:"nickname"`
+	Avatar    string `json:"avatar"`
+	Bio       string `json:"bio"`
+	Gender    int8   `json:"gender"`
+	CreatedAt int64  `json:"created_at"`
+
+	RenamedFrom string `json:"renamed_from,omitempty"` // 按旧用户名查找时为该旧用户名，客户端应跳转到新用户名
+}
+
+// HandleHistory 改名记录序列化器
+type HandleHistory struct {
+	UserName   string `json:"username"`
+	ReleasedAt int64  `json:"released_at"`
+	Created    int64  `json:"created"`
+}
+
+func buildUser(user *model.User) *User {
 	res := &User{
 		ID:        user.ID,
 		UserName:  user.UserName,
 		Nickname:  user.Nickname,
 		Status:    user.Status,
+		NewEmail:  user.NewEmail,
 		Avatar:    user.Avatar,
-		Email:     *user.Email,
 		Role:      user.Role,
+		Bio:       user.Bio,
+		Gender:    user.Gender,
+		Phone:     user.Phone,
 		CreatedAt: user.Created,
+
+		DeletionAt: user.DeletionAt,
 	}
+	if user.Email != nil {
+		res.Email = *user.Email
+	}
+	if user.Birthday != nil {
+		res.Birthday = user.Birthday.Format(model.BirthdayLayout)
+	}
+	return res
+}
+
+// BuildUserResponse 序列化用户响应
+func BuildUserResponse(user *model.User) *Response {
 	return &Response{
 		Code: 200,
 		Msg:  "success",
-		Data: res,
+		Data: buildUser(user),
 	}
 }
 
@@ -39,39 +98,110 @@ func BuildUserResponse(user *model.User) *Response {
 func BuildUsersResponse(users []*model.User) []*User {
 	res := make([]*User, len(users))
 	for i, user := range users {
-		res[i] = &User{
-			ID:        user.ID,
-			UserName:  user.UserName,
-			Nickname:  user.Nickname,
-			Status:    user.Status,
-			Avatar:    user.Avatar,
-			Email:     *user.Email,
-			Role:      user.Role,
-			CreatedAt: user.Created,
-		}
+		res[i] = buildUser(user)
+	}
+	return res
+}
+
+func buildAdminUser(user *model.User) *AdminUser {
+	return &AdminUser{
+		User:          buildUser(user),
+		SuspendReason: user.SuspendReason,
+		SuspendUntil:  user.SuspendUntil,
+		TOTPEnabled:   user.TOTPEnabled,
+	}
+}
+
+// BuildAdminUsersResponse 序列化管理员查看的用户
+func BuildAdminUsersResponse(users []*model.User) []*AdminUser {
+	res := make([]*AdminUser, len(users))
+	for i, user := range users {
+		res[i] = buildAdminUser(user)
 	}
 	return res
 }
 
+// BuildUserDetailResponse 序列化管理员查看的用户详情
+func BuildUserDetailResponse(user *model.User, sessions []*model.Session, identities []*model.UserIdentity,
+	tokens []*model.APIToken, logs []*model.AuditLog) *Response {
+	res := &UserDetail{
+		User:       buildAdminUser(user),
+		Sessions:   buildSessions(sessions, ""),
+		Identities: identities,
+		APITokens:  make([]*APIToken, len(tokens)),
+		AuditLogs:  logs,
+	}
+	for i, token := range tokens {
+		res.APITokens[i] = buildAPIToken(token)
+	}
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: res,
+	}
+}
+
+// BuildProfileResponse 序列化公开的用户资料，renamedFrom为查找时使用的旧用户名
+func BuildProfileResponse(user *model.User, renamedFrom string) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &Profile{
+			ID:          user.ID,
+			UserName:    user.UserName,
+			Nickname:    user.Nickname,
+			Avatar:      user.Avatar,
+			Bio:         user.Bio,
+			Gender:      user.Gender,
+			CreatedAt:   user.Created,
+			RenamedFrom: renamedFrom,
+		},
+	}
+}
+
+// BuildHandleHistoryResponse 序列化改名记录
+func BuildHandleHistoryResponse(histories []*model.HandleHistory) *Response {
+	res := make([]*HandleHistory, len(histories))
+	for i, history := range histories {
+		res[i] = &HandleHistory{
+			UserName:   history.Handle,
+			ReleasedAt: history.ReleasedAt,
+			Created:    history.Created,
+		}
+	}
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: res,
+	}
+}
+
 // Login 登录序列化器
 type Login struct {
-	User  *User  `json:"user"`
-	Token string `json:"token"`
+	User *User `json:"user"`
+	*Token
+}
+
+// Token 令牌序列化器
+type Token struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token有效秒数
+}
+
+// BuildTokenResponse 序列化刷新令牌响应
+func BuildTokenResponse(token *Token) *Response {
+	return &Response{
+		Code: 200,
+		Msg:  "success",
+		Data: token,
+	}
 }
 
 // BuildLoginResponse 序列化登录响应
-func BuildLoginResponse(user *model.User, token string) *Response {
+func BuildLoginResponse(user *model.User, token *Token) *Response {
 	res := &Login{
-		User: &User{
-			ID:        user.ID,
-			UserName:  user.UserName,
-			Nickname:  user.Nickname,
-			Status:    user.Status,
-			Avatar:    user.Avatar,
-			Email:     *user.Email,
-			Role:      user.Role,
-			CreatedAt: user.Created,
-		},
+		User:  buildUser(user),
 		Token: token,
 	}
 	return &Response{
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var errExportLimit = errors.New("audit export limit reached")
+
+const (
+	exportBatch = 500    // 导出时每批读取的条数
+	exportLimit = 100000 // 单次最多导出的条数
+)
+
+// QueryAuditLogsService 管理员查询审计日志的服务
+type QueryAuditLogsService struct {
+	Page  int `form:"page" json:"page"`
+	Limit int `form:"limit" json:"limit" binding:"max=100"`
+	AuditFilter
+}
+
+// ExportAuditLogsService 导出审计日志的服务，按时间正序输出JSON Lines
+type ExportAuditLogsService struct {
+	AuditFilter
+}
+
+// AuditFilter 审计日志的筛选条件，Start与End为秒级时间戳
+type AuditFilter struct {
+	ActorID    int64  `form:"actor_id" json:"actor_id"`
+	Action     string `form:"action" json:"action" binding:"max=50"`
+	TargetType string `form:"target_type" json:"target_type" binding:"max=20"`
+	TargetID   string `form:"target_id" json:"target_id" binding:"max=64"`
+	RequestID  string `form:"request_id" json:"request_id" binding:"max=64"`
+	Start      int64  `form:"start" json:"start"`
+	End        int64  `form:"end" json:"end"`
+}
+
+// Record 记录当前请求的操作，补全操作者、IP与请求ID
+// before与after为修改前后的数据，新建时before为nil，删除时after为nil，不需要记录差异时都传nil
+func Record(c *gin.Context, log *model.AuditLog, before, after interface{}) {
+	if log.ActorID == 0 {
+		if user, ok := c.Get("user"); ok {
+			log.ActorID = user.(*model.User).ID
+		}
+	}
+	log.IP = c.ClientIP()
+	log.RequestID = c.GetString("request_id")
+	if before != nil || after != nil {
+		diff, err := model.NewAuditDiff(before, after)
+		if err != nil {
+			logger.Logger().Warn("[Audit] diff err", zap.String("action", log.Action), zap.Error(err))
+		}
+		log.Diff = diff
+	}
+	model.Audit(log)
+}
+
+func (f *AuditFilter) apply(tx *gorm.DB) *gorm.DB {
+	if f.ActorID != 0 {
+		tx = tx.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		tx = tx.Where("action = ?", f.Action)
+	}
+	if f.TargetType != "" {
+		tx = tx.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		tx = tx.Where("target_id = ?", f.TargetID)
+	}
+	if f.RequestID != "" {
+		tx = tx.Where("request_id = ?", f.RequestID)
+	}
+	if f.Start > 0 {
+		tx = tx.Where("created >= ?", f.Start)
+	}
+	if f.End > 0 {
+		tx = tx.Where("created < ?", f.End)
+	}
+	return tx
+}
+
+// Query 分页查询审计日志，按时间倒序
+func (s *QueryAuditLogsService) Query() *serializer.Response {
+	page, limit := s.Page, s.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	tx := s.apply(orm.DB().Model(&model.AuditLog{}))
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找审计日志错误", err)
+	}
+	logs := make([]*model.AuditLog, 0)
+	if err := tx.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error; err != nil {
+		return serializer.DBErr("查找审计日志错误", err)
+	}
+	return serializer.BuildListResponse(total, page, limit, logs)
+}
+
+// Export 将审计日志逐行写入响应，每行一个JSON对象
+// 按主键正序分批读取，单次最多导出exportLimit条
+// 响应头写出后无法再返回错误响应，出错时只记录日志并中断输出
+func (s *ExportAuditLogsService) Export(c *gin.Context) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-`+time.Now().Format("20060102150405")+`.jsonl"`)
+	c.Status(200)
+
+	enc := json.NewEncoder(c.Writer)
+	logs := make([]*model.AuditLog, 0, exportBatch)
+	written := 0
+	err := s.apply(orm.DB().Model(&model.AuditLog{})).
+		FindInBatches(&logs, exportBatch, func(tx *gorm.DB, batch int) error {
+			for _, log := range logs {
+				if written >= exportLimit {
+					return errExportLimit
+				}
+				if err := enc.Encode(log); err != nil {
+					return err
+				}
+				written++
+			}
+			c.Writer.Flush()
+			return nil
+		}).Error
+	if err != nil && !errors.Is(err, errExportLimit) {
+		logger.Logger().Warn("[Audit] export err", zap.Error(err))
+	}
+}
@@ -0,0 +1,93 @@
+package block
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/follow"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockService 拉黑/取消拉黑用户的服务
+type BlockService struct {
+	UserID int64 `form:"user_id" json:"user_id" binding:"required"`
+}
+
+// BlockListService 黑名单列表的服务
+type BlockListService struct {
+	Page  int `form:"page" json:"page" query:"page"`
+	Limit int `form:"limit" json:"limit" query:"limit"`
+}
+
+// Block 拉黑用户，同时解除双方的关注关系
+func (s *BlockService) Block(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if s.UserID == user.ID {
+		return serializer.ParamErr("不能拉黑自己", nil)
+	}
+	if _, err := model.GetUser(s.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Block{
+			FromUID: user.ID,
+			ToUID:   s.UserID,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("(user_id = ? AND fan_id = ?) OR (user_id = ? AND fan_id = ?)",
+			user.ID, s.UserID, s.UserID, user.ID).Delete(&model.UserFan{}).Error
+	})
+	if err != nil {
+		return serializer.DBErr("拉黑失败", err)
+	}
+	follow.InvalidateCount(user.ID, s.UserID)
+	follow.InvalidateCount(s.UserID, user.ID)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "拉黑成功",
+	}
+}
+
+// Unblock 取消拉黑
+func (s *BlockService) Unblock(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	err := orm.DB().Where("from_uid = ? AND to_uid = ?", user.ID, s.UserID).Delete(&model.Block{}).Error
+	if err != nil {
+		return serializer.DBErr("取消拉黑失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "取消拉黑成功",
+	}
+}
+
+// BlockList 分页查询我的黑名单，按拉黑时间倒序
+func (s *BlockListService) BlockList(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	var total int64
+	users := make([]*model.User, 0)
+
+	tx := orm.DB().Model(&model.User{}).
+		Joins("JOIN tb_block ON tb_block.to_uid = tb_user.id").
+		Where("tb_block.from_uid = ?", user.ID)
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找黑名单错误", err)
+	}
+	if err := orm.Pagination(tx, s.Page, s.Limit).
+		Order("tb_block.created DESC").
+		Find(&users).Error; err != nil {
+		return serializer.DBErr("查找黑名单错误", err)
+	}
+
+	return serializer.BuildListResponse(total, s.Page, s.Limit, serializer.BuildFollows(users, nil))
+}
@@ -0,0 +1,110 @@
+package captcha
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vidorg/vid_backend/internal/conf"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/captcha"
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// CreateCaptchaService 获取人机验证挑战的服务
+type CreateCaptchaService struct {
+	Type string `form:"type" json:"type" binding:"omitempty,oneof=image pow"`
+}
+
+const (
+	SceneLogin    = "login"
+	SceneRegister = "register"
+)
+
+var (
+	// 默认参数，工作量证明的难度为20比特，浏览器中约需1秒
+	options = &captcha.Options{Length: 5, Width: 160, Height: 60, Difficulty: 20, TTL: 5 * time.Minute}
+	// 各场景接受的挑战类型，工作量证明挡不住批量注册，注册默认只接受图片验证码
+	accepted = map[string][]string{
+		SceneLogin:    {captcha.TypeImage, captcha.TypePoW},
+		SceneRegister: {captcha.TypeImage},
+	}
+
+	manager *captcha.Manager
+)
+
+// loadConfig 使用配置覆盖默认参数，挑战类型错误时返回错误
+func loadConfig(c *conf.CaptchaConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.Length > 0 {
+		options.Length = c.Length
+	}
+	if c.Width > 0 && c.Height > 0 {
+		options.Width, options.Height = c.Width, c.Height
+	}
+	if c.Difficulty > 0 {
+		options.Difficulty = c.Difficulty
+	}
+	if c.TTL > 0 {
+		options.TTL = time.Duration(c.TTL) * time.Second
+	}
+	for scene, types := range map[string][]string{SceneLogin: c.Login, SceneRegister: c.Register} {
+		if len(types) == 0 {
+			continue
+		}
+		for _, typ := range types {
+			if typ != captcha.TypeImage && typ != captcha.TypePoW {
+				return fmt.Errorf("captcha: invalid type %s of %s", typ, scene)
+			}
+		}
+		accepted[scene] = types
+	}
+	return nil
+}
+
+// Init 加载配置并创建挑战管理器，需在启动时调用
+// redis可用时多实例共享挑战，否则使用内存
+func Init() error {
+	if err := loadConfig(conf.Config().Captcha); err != nil {
+		return err
+	}
+	var store captcha.Store = captcha.NewMemoryStore()
+	if redis.Enabled() {
+		store = &captcha.RedisStore{}
+	}
+	manager = captcha.New("vid:captcha:", options, store)
+	return nil
+}
+
+// Verify 校验人机验证的答案，挑战只能使用一次且必须为scene接受的类型，未通过时返回错误响应
+func Verify(scene, id, answer string) *serializer.Response {
+	if id == "" || answer == "" {
+		return serializer.CaptchaErr("")
+	}
+	ok, err := manager.Verify(id, answer, accepted[scene]...)
+	if err != nil {
+		return serializer.ServerErr("人机验证失败", err)
+	}
+	if !ok {
+		return serializer.CaptchaErr("人机验证未通过或已过期，请重新验证")
+	}
+	return nil
+}
+
+// Create 创建挑战，默认为图片验证码
+func (s *CreateCaptchaService) Create() *serializer.Response {
+	typ := s.Type
+	if typ == "" {
+		typ = captcha.TypeImage
+	}
+	ch, err := manager.Create(typ)
+	if err != nil {
+		return serializer.ServerErr("生成验证码失败", err)
+	}
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: ch,
+	}
+}
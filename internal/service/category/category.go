@@ -1,37 +1,360 @@
 package category
 
 import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/vidorg/vid_backend/internal/model"
 	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
 	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errCategoryNotFound = errors.New("category not found")
+	errParentNotFound   = errors.New("parent category not found")
+	errCategoryCycle    = errors.New("category moved under its own subtree")
+	errCategoryHasVideo = errors.New("category subtree has videos")
 )
 
 type GetCategoriesListService struct {
 	CategoryID int `form:"id" json:"category_id"`
 }
 
+// CreateCategoryService 创建分类的服务
+type CreateCategoryService struct {
+	Name       string `form:"name" json:"name" binding:"required,min=1,max=50"`
+	CategoryID int64  `form:"category_id" json:"category_id"`
+	Sort       int    `form:"sort" json:"sort"`
+}
+
+// UpdateCategoryService 修改分类的服务
+type UpdateCategoryService struct {
+	ID   int64   `form:"id" json:"id" binding:"required"`
+	Name *string `form:"name" json:"name" binding:"omitempty,min=1,max=50"`
+	Sort *int    `form:"sort" json:"sort"`
+}
+
+// MoveCategoryService 移动分类子树的服务
+type MoveCategoryService struct {
+	ID         int64 `form:"id" json:"id" binding:"required"`
+	CategoryID int64 `form:"category_id" json:"category_id"` // 新的父ID 0为一级
+}
+
+// SortCategoriesService 调整同级分类顺序的服务
+type SortCategoriesService struct {
+	CategoryID int64   `form:"category_id" json:"category_id"`          // 父ID
+	IDs        []int64 `form:"ids" json:"ids" binding:"required,min=1"` // 按新顺序排列的子分类ID
+}
+
+// DeleteCategoryService 删除分类的服务
+type DeleteCategoryService struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
+
 func (g *GetCategoriesListService) GetCategoriesList(c *gin.Context) *serializer.Response {
 	var categories []*model.Category
 
-	// 查指定分类ID
+	tx := orm.DB().Order("sort, id")
+	// 查指定分类ID及其所有后代
 	if g.CategoryID != 0 {
-		orm.DB().Where("id = ?", g.CategoryID).Preload("Categories").
-			Preload("Categories.Categories").Find(&categories)
-		return &serializer.Response{
-			Code: 200,
-			Msg:  "success",
-			Data: categories,
+		root := &model.Category{}
+		if err := orm.DB().First(root, g.CategoryID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return serializer.ParamErr("分类不存在", nil)
+		} else if err != nil {
+			return serializer.DBErr("查找分类错误", err)
 		}
+		tx = tx.Where("path LIKE ?", root.Path+"%")
+	}
+	if err := tx.Find(&categories).Error; err != nil {
+		return serializer.DBErr("查找分类错误", err)
 	}
 
-	// 查所有
-	orm.DB().Where("category_id = ?", g.CategoryID).Preload("Categories").
-		Preload("Categories.Categories").Find(&categories)
+	counts, err := videoCounts(categories)
+	if err != nil {
+		return serializer.DBErr("统计视频数错误", err)
+	}
 
 	return &serializer.Response{
 		Code: 200,
 		Msg:  "success",
-		Data: categories,
+		Data: model.BuildCategoryTree(categories, int64(g.CategoryID), counts),
+	}
+}
+
+// Create 创建分类
+func (s *CreateCategoryService) Create(c *gin.Context) *serializer.Response {
+	category := &model.Category{
+		Name:       s.Name,
+		CategoryID: s.CategoryID,
+		Sort:       s.Sort,
+	}
+
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		parentPath := ""
+		if s.CategoryID != 0 {
+			parent := &model.Category{}
+			if err := tx.First(parent, s.CategoryID).Error; err != nil {
+				return err
+			}
+			parentPath = parent.Path
+		}
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		category.Path = model.CategoryPath(parentPath, category.ID)
+		return tx.Model(category).Update("path", category.Path).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("父分类不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("创建分类失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditCategoryCreate,
+		TargetType: "category",
+		TargetID:   strconv.FormatInt(category.ID, 10),
+	}, nil, category)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "创建成功",
+		Data: category,
+	}
+}
+
+// Update 修改分类名称或排序
+func (s *UpdateCategoryService) Update(c *gin.Context) *serializer.Response {
+	category := &model.Category{}
+	if err := orm.DB().First(category, s.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("分类不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找分类错误", err)
+	}
+
+	before := *category
+	updates := map[string]interface{}{}
+	if s.Name != nil {
+		updates["name"] = *s.Name
+		category.Name = *s.Name
+	}
+	if s.Sort != nil {
+		updates["sort"] = *s.Sort
+		category.Sort = *s.Sort
+	}
+	if len(updates) == 0 {
+		return serializer.ParamErr("没有需要修改的字段", nil)
+	}
+	if err := orm.DB().Model(category).Updates(updates).Error; err != nil {
+		return serializer.DBErr("修改分类失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditCategoryUpdate,
+		TargetType: "category",
+		TargetID:   strconv.FormatInt(category.ID, 10),
+	}, &before, category)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "修改成功",
+		Data: category,
+	}
+}
+
+// Move 将分类连同子树移动到新的父分类下
+// 在事务中按ID顺序锁定分类与新的父分类后再检查，避免并发移动形成环
+func (s *MoveCategoryService) Move(c *gin.Context) *serializer.Response {
+	if s.CategoryID == s.ID {
+		return serializer.ParamErr("不能将分类移动到其子分类下", nil)
+	}
+	var before, category *model.Category
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		ids := []int64{s.ID}
+		if s.CategoryID != 0 {
+			ids = append(ids, s.CategoryID)
+		}
+		var locked []*model.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		var parent *model.Category
+		for _, row := range locked {
+			if row.ID == s.ID {
+				category = row
+			} else {
+				parent = row
+			}
+		}
+		if category == nil {
+			return errCategoryNotFound
+		}
+		if s.CategoryID != 0 && parent == nil {
+			return errParentNotFound
+		}
+		copied := *category
+		before = &copied
+		if category.CategoryID == s.CategoryID {
+			return nil
+		}
+
+		parentPath := ""
+		if parent != nil {
+			// 不能移动到自身或自身的后代之下
+			if parent.IsDescendantOf(category.Path) {
+				return errCategoryCycle
+			}
+			parentPath = parent.Path
+		}
+		oldPath := category.Path
+		category.CategoryID = s.CategoryID
+		category.Path = model.CategoryPath(parentPath, category.ID)
+		if err := tx.Model(category).Update("category_id", s.CategoryID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Category{}).
+			Where("path LIKE ?", oldPath+"%").
+			Update("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", category.Path, len(oldPath)+1)).Error
+	})
+	switch {
+	case errors.Is(err, errCategoryNotFound):
+		return serializer.ParamErr("分类不存在", nil)
+	case errors.Is(err, errParentNotFound):
+		return serializer.ParamErr("父分类不存在", nil)
+	case errors.Is(err, errCategoryCycle):
+		return serializer.ParamErr("不能将分类移动到其子分类下", nil)
+	case err != nil:
+		return serializer.DBErr("移动分类失败", err)
+	}
+	if before.CategoryID != category.CategoryID {
+		audit.Record(c, &model.AuditLog{
+			Action:     model.AuditCategoryMove,
+			TargetType: "category",
+			TargetID:   strconv.FormatInt(category.ID, 10),
+		}, before, category)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "移动成功",
+		Data: category,
+	}
+}
+
+// Sort 按给定顺序重排同级分类
+func (s *SortCategoriesService) Sort(c *gin.Context) *serializer.Response {
+	var count int64
+	err := orm.DB().Model(&model.Category{}).
+		Where("id IN ? AND category_id = ?", s.IDs, s.CategoryID).
+		Count(&count).Error
+	if err != nil {
+		return serializer.DBErr("查找分类错误", err)
+	}
+	if int(count) != len(s.IDs) {
+		return serializer.ParamErr("分类不属于同一父分类", nil)
+	}
+
+	err = orm.DB().Transaction(func(tx *gorm.DB) error {
+		for i, id := range s.IDs {
+			if err := tx.Model(&model.Category{}).Where("id = ?", id).Update("sort", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return serializer.DBErr("排序失败", err)
+	}
+	order, _ := json.Marshal(s.IDs)
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditCategorySort,
+		TargetType: "category",
+		TargetID:   strconv.FormatInt(s.CategoryID, 10),
+		Detail:     string(order),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "排序成功",
+	}
+}
+
+// Delete 删除分类及其子树，子树中仍有视频时拒绝删除
+// 统计与删除在同一事务中，并锁定子树各行，避免期间有分类被移入子树
+func (s *DeleteCategoryService) Delete(c *gin.Context) *serializer.Response {
+	category := &model.Category{}
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		// 先锁定分类本身，此后其路径不会再被移动修改
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(category, s.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errCategoryNotFound
+		} else if err != nil {
+			return err
+		}
+		var ids []int64
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Category{}).
+			Where("path LIKE ?", category.Path+"%").Order("id").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&model.Video{}).Where("category_id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errCategoryHasVideo
+		}
+		return tx.Where("id IN ?", ids).Delete(&model.Category{}).Error
+	})
+	switch {
+	case errors.Is(err, errCategoryNotFound):
+		return serializer.ParamErr("分类不存在", nil)
+	case errors.Is(err, errCategoryHasVideo):
+		return serializer.ParamErr("该分类下仍有视频，无法删除", nil)
+	case err != nil:
+		return serializer.DBErr("删除分类失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditCategoryDelete,
+		TargetType: "category",
+		TargetID:   strconv.FormatInt(category.ID, 10),
+		Detail:     "subtree " + category.Path,
+	}, category, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "删除成功",
+	}
+}
+
+// videoCounts 统计各分类自身的视频数
+func videoCounts(categories []*model.Category) (map[int64]int64, error) {
+	counts := make(map[int64]int64, len(categories))
+	if len(categories) == 0 {
+		return counts, nil
+	}
+	ids := make([]int64, len(categories))
+	for i, c := range categories {
+		ids[i] = c.ID
+	}
+
+	var rows []struct {
+		CategoryID int64
+		Count      int64
+	}
+	err := orm.DB().Model(&model.Video{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("category_id IN ?", ids).
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
 	}
+	return counts, nil
 }
@@ -1,10 +1,21 @@
 package channel
 
 import (
+	"errors"
+	"mime/multipart"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/vidorg/vid_backend/internal/model"
 	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/keyword"
+	"github.com/vidorg/vid_backend/internal/service/subscription"
+	"github.com/vidorg/vid_backend/pkg/filter"
 	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/upload"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GetChannelListService struct {
@@ -13,6 +24,56 @@ type GetChannelListService struct {
 	Limit      int `form:"limit" json:"limit" query:"limit"`
 }
 
+// CreateChannelService 创建频道的服务
+type CreateChannelService struct {
+	Name        string `form:"name" json:"name" binding:"required,min=1,max=50"`
+	Description string `form:"description" json:"description" binding:"max=500"`
+}
+
+// UpdateChannelService 修改频道信息的服务
+type UpdateChannelService struct {
+	ID          int64   `form:"id" json:"id" binding:"required"`
+	Name        *string `form:"name" json:"name" binding:"omitempty,min=1,max=50"`
+	Description *string `form:"description" json:"description" binding:"omitempty,max=500"`
+}
+
+// ChannelAuthorsService 查看频道作者列表的服务
+type ChannelAuthorsService struct {
+	ChannelID int64 `form:"channel_id" json:"channel_id" binding:"required"`
+}
+
+// SetChannelAuthorService 邀请或修改协作者角色的服务
+type SetChannelAuthorService struct {
+	ChannelID int64  `form:"channel_id" json:"channel_id" binding:"required"`
+	UserID    int64  `form:"user_id" json:"user_id" binding:"required"`
+	Role      string `form:"role" json:"role" binding:"required,oneof=editor viewer"`
+}
+
+// RemoveChannelAuthorService 移除协作者的服务
+type RemoveChannelAuthorService struct {
+	ChannelID int64 `form:"channel_id" json:"channel_id" binding:"required"`
+	UserID    int64 `form:"user_id" json:"user_id" binding:"required"`
+}
+
+// TransferChannelService 转让频道所有权的服务
+type TransferChannelService struct {
+	ChannelID int64 `form:"channel_id" json:"channel_id" binding:"required"`
+	UserID    int64 `form:"user_id" json:"user_id" binding:"required"`
+}
+
+// UploadChannelImageService 上传频道头像或横幅的服务
+type UploadChannelImageService struct {
+	ChannelID int64                 `form:"channel_id" binding:"required"`
+	Type      string                `form:"type" binding:"required,oneof=avatar banner"`
+	File      *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+// SetVideoChannelService 将视频归入频道的服务
+type SetVideoChannelService struct {
+	VideoID   int64 `form:"video_id" json:"video_id" binding:"required"`
+	ChannelID int64 `form:"channel_id" json:"channel_id"` // 0为移出频道
+}
+
 func (g *GetChannelListService) GetChannelList(c *gin.Context) *serializer.Response {
 
 	var channels []*model.Channel
@@ -33,3 +94,344 @@ func (g *GetChannelListService) GetChannelList(c *gin.Context) *serializer.Respo
 		},
 	}
 }
+
+// Create 创建频道，创建者成为所有者
+func (s *CreateChannelService) Create(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	name, res := keyword.Check("频道名称", s.Name)
+	if res != nil {
+		return res
+	}
+	description, res := keyword.Check("频道简介", s.Description)
+	if res != nil {
+		return res
+	}
+	channel := &model.Channel{
+		Name:        name.Text,
+		Description: description.Text,
+	}
+
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(channel).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.ChannelAuthor{
+			ChannelID: channel.ID,
+			UserID:    user.ID,
+			Role:      model.ChannelOwner,
+		}).Error
+	})
+	if err != nil {
+		return serializer.DBErr("创建频道失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditChannelCreate,
+		TargetType: "channel",
+		TargetID:   strconv.FormatInt(channel.ID, 10),
+	}, nil, channel)
+	keyword.Flag(model.ReportChannel, channel.ID, user.ID, map[string]*filter.Result{"频道名称": name, "频道简介": description})
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "创建成功",
+		Data: channel,
+	}
+}
+
+// Update 修改频道信息，需要编辑者及以上角色
+func (s *UpdateChannelService) Update(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	channel, res := authorizeChannel(c, s.ID, model.ChannelEditor)
+	if res != nil {
+		return res
+	}
+
+	before := map[string]interface{}{}
+	updates := map[string]interface{}{}
+	checked := map[string]*filter.Result{}
+	if s.Name != nil {
+		name, res := keyword.Check("频道名称", *s.Name)
+		if res != nil {
+			return res
+		}
+		checked["频道名称"] = name
+		before["name"] = channel.Name
+		updates["name"] = name.Text
+	}
+	if s.Description != nil {
+		description, res := keyword.Check("频道简介", *s.Description)
+		if res != nil {
+			return res
+		}
+		checked["频道简介"] = description
+		before["description"] = channel.Description
+		updates["description"] = description.Text
+	}
+	if len(updates) == 0 {
+		return serializer.ParamErr("没有需要修改的字段", nil)
+	}
+	if err := orm.DB().Model(channel).Updates(updates).Error; err != nil {
+		return serializer.DBErr("修改频道失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditChannelUpdate,
+		TargetType: "channel",
+		TargetID:   strconv.FormatInt(channel.ID, 10),
+	}, before, updates)
+	keyword.Flag(model.ReportChannel, channel.ID, user.ID, checked)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "修改成功",
+		Data: channel,
+	}
+}
+
+// Authors 查看频道作者列表，需要是频道作者
+func (s *ChannelAuthorsService) Authors(c *gin.Context) *serializer.Response {
+	if _, res := authorizeChannel(c, s.ChannelID, model.ChannelViewer); res != nil {
+		return res
+	}
+
+	var authors []*model.ChannelAuthor
+	if err := orm.DB().Where("channel_id = ?", s.ChannelID).Preload("User").Find(&authors).Error; err != nil {
+		return serializer.DBErr("查找频道作者错误", err)
+	}
+	for _, author := range authors {
+		if author.User != nil {
+			author.User.Password = ""
+		}
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: authors,
+	}
+}
+
+// SetAuthor 邀请协作者或修改其角色，仅所有者可操作
+func (s *SetChannelAuthorService) SetAuthor(c *gin.Context) *serializer.Response {
+	if _, res := authorizeChannel(c, s.ChannelID, model.ChannelOwner); res != nil {
+		return res
+	}
+	if s.UserID == c.MustGet("user").(*model.User).ID {
+		return serializer.ParamErr("不能修改自己的角色", nil)
+	}
+	if _, err := model.GetUser(s.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+
+	var before interface{}
+	if old, err := model.GetChannelAuthor(s.ChannelID, s.UserID); err == nil {
+		before = map[string]interface{}{"user_id": old.UserID, "role": old.Role}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.DBErr("查找频道作者错误", err)
+	}
+
+	author := &model.ChannelAuthor{
+		ChannelID: s.ChannelID,
+		UserID:    s.UserID,
+		Role:      s.Role,
+	}
+	err := orm.DB().Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(author).Error
+	if err != nil {
+		return serializer.DBErr("设置协作者失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditChannelSetAuthor,
+		TargetType: "channel",
+		TargetID:   strconv.FormatInt(s.ChannelID, 10),
+	}, before, map[string]interface{}{"user_id": s.UserID, "role": s.Role})
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "设置成功",
+		Data: author,
+	}
+}
+
+// RemoveAuthor 移除协作者，所有者可移除他人，协作者可移除自己
+func (s *RemoveChannelAuthorService) RemoveAuthor(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	min := model.ChannelOwner
+	if s.UserID == user.ID {
+		min = model.ChannelViewer
+	}
+	if _, res := authorizeChannel(c, s.ChannelID, min); res != nil {
+		return res
+	}
+
+	target, err := model.GetChannelAuthor(s.ChannelID, s.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("该用户不是频道作者", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找频道作者错误", err)
+	}
+	if target.Role == model.ChannelOwner {
+		return serializer.ParamErr("所有者需要先转让频道", nil)
+	}
+	if err := orm.DB().Where("channel_id = ? AND user_id = ?", s.ChannelID, s.UserID).
+		Delete(&model.ChannelAuthor{}).Error; err != nil {
+		return serializer.DBErr("移除协作者失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditChannelRemoveAuthor,
+		TargetType: "channel",
+		TargetID:   strconv.FormatInt(s.ChannelID, 10),
+	}, map[string]interface{}{"user_id": target.UserID, "role": target.Role}, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "移除成功",
+	}
+}
+
+// Transfer 将频道所有权转让给已有的协作者，原所有者降为编辑者
+func (s *TransferChannelService) Transfer(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if _, res := authorizeChannel(c, s.ChannelID, model.ChannelOwner); res != nil {
+		return res
+	}
+	if s.UserID == user.ID {
+		return serializer.ParamErr("不能转让给自己", nil)
+	}
+	if _, err := model.GetChannelAuthor(s.ChannelID, s.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("只能转让给频道协作者", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找频道作者错误", err)
+	}
+
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ChannelAuthor{}).
+			Where("channel_id = ? AND user_id = ?", s.ChannelID, s.UserID).
+			Update("role", model.ChannelOwner).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.ChannelAuthor{}).
+			Where("channel_id = ? AND user_id = ?", s.ChannelID, user.ID).
+			Update("role", model.ChannelEditor).Error
+	})
+	if err != nil {
+		return serializer.DBErr("转让频道失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditChannelTransfer,
+		TargetType: "channel",
+		TargetID:   strconv.FormatInt(s.ChannelID, 10),
+	}, map[string]interface{}{"owner": user.ID}, map[string]interface{}{"owner": s.UserID})
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "转让成功",
+	}
+}
+
+// Upload 上传频道头像或横幅，需要编辑者及以上角色
+func (s *UploadChannelImageService) Upload(c *gin.Context) *serializer.Response {
+	channel, res := authorizeChannel(c, s.ChannelID, model.ChannelEditor)
+	if res != nil {
+		return res
+	}
+
+	url, err := upload.SaveImage(s.File, "channel")
+	if errors.Is(err, upload.ErrTooLarge) {
+		return serializer.UploadFileErr("图片过大", nil)
+	} else if errors.Is(err, upload.ErrUnsupported) {
+		return serializer.UploadFileErr("不支持的图片格式", nil)
+	} else if err != nil {
+		return serializer.UploadFileErr("", err)
+	}
+
+	before := channel.Avatar
+	if s.Type == "banner" {
+		before = channel.Banner
+	}
+	if err := orm.DB().Model(channel).Update(s.Type, url).Error; err != nil {
+		return serializer.DBErr("保存图片失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditChannelImage,
+		TargetType: "channel",
+		TargetID:   strconv.FormatInt(channel.ID, 10),
+	}, map[string]interface{}{s.Type: before}, map[string]interface{}{s.Type: url})
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "上传成功",
+		Data: channel,
+	}
+}
+
+// SetVideoChannel 将自己的视频归入频道，需要是该频道的编辑者及以上角色
+func (s *SetVideoChannelService) SetVideoChannel(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	video := &model.Video{}
+	if err := orm.DB().First(video, s.VideoID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("视频不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找视频错误", err)
+	}
+	if video.UserID != user.ID {
+		return serializer.NoRightErr()
+	}
+	if video.Status == model.VideoRemoved {
+		return serializer.ParamErr("视频已下架", nil)
+	}
+
+	var channelID *int64
+	if s.ChannelID != 0 {
+		if _, res := authorizeChannel(c, s.ChannelID, model.ChannelEditor); res != nil {
+			return res
+		}
+		channelID = &s.ChannelID
+	}
+	before := map[string]interface{}{"channel_id": video.ChannelID}
+	if err := orm.DB().Model(video).Update("channel_id", channelID).Error; err != nil {
+		return serializer.DBErr("设置视频频道失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditVideoSetChannel,
+		TargetType: "video",
+		TargetID:   strconv.FormatInt(video.ID, 10),
+	}, before, map[string]interface{}{"channel_id": channelID})
+	video.ChannelID = channelID
+	if err := subscription.Retract(video.ID); err != nil {
+		return serializer.DBErr("更新订阅动态失败", err)
+	}
+	if err := subscription.Deliver(video); err != nil {
+		return serializer.DBErr("更新订阅动态失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "设置成功",
+		Data: video,
+	}
+}
+
+// authorizeChannel 查找频道并校验当前用户在频道中的角色不低于min
+func authorizeChannel(c *gin.Context, channelID int64, min string) (*model.Channel, *serializer.Response) {
+	user := c.MustGet("user").(*model.User)
+	channel := &model.Channel{}
+	if err := orm.DB().First(channel, channelID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, serializer.ParamErr("频道不存在", nil)
+	} else if err != nil {
+		return nil, serializer.DBErr("查找频道错误", err)
+	}
+
+	author, err := model.GetChannelAuthor(channelID, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, serializer.NoRightErr()
+	} else if err != nil {
+		return nil, serializer.DBErr("查找频道作者错误", err)
+	}
+	if !model.ChannelRoleAtLeast(author.Role, min) {
+		return nil, serializer.NoRightErr()
+	}
+	return channel, nil
+}
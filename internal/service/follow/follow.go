@@ -0,0 +1,286 @@
+package follow
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	followersKey = "vid:follow:followers:%d" // 粉丝数缓存
+	followingKey = "vid:follow:following:%d" // 关注数缓存
+	countExpire  = 24 * time.Hour
+)
+
+// FollowService 关注/取消关注用户的服务
+type FollowService struct {
+	UserID int64 `form:"user_id" json:"user_id" binding:"required"`
+}
+
+// FollowListService 粉丝/关注列表的服务
+type FollowListService struct {
+	UserID int64 `form:"user_id" json:"user_id" query:"user_id" binding:"required"`
+	Page   int   `form:"page" json:"page" query:"page"`
+	Limit  int   `form:"limit" json:"limit" query:"limit"`
+}
+
+// FollowCountService 粉丝数与关注数的服务
+type FollowCountService struct {
+	UserID int64 `form:"user_id" json:"user_id" query:"user_id" binding:"required"`
+}
+
+// FollowRelationService 查询与某用户关注关系的服务
+type FollowRelationService struct {
+	UserID int64 `form:"user_id" json:"user_id" query:"user_id" binding:"required"`
+}
+
+// FollowFeedService 关注用户新投稿的服务
+type FollowFeedService struct {
+	Cursor string `form:"cursor" json:"cursor" query:"cursor"`
+	Limit  int    `form:"limit" json:"limit" query:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// Follow 关注用户
+func (s *FollowService) Follow(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if s.UserID == user.ID {
+		return serializer.ParamErr("不能关注自己", nil)
+	}
+	if _, err := model.GetUser(s.UserID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if blocked, err := model.IsBlocked(s.UserID, user.ID); err != nil {
+		return serializer.DBErr("查找拉黑关系错误", err)
+	} else if blocked {
+		return serializer.NoRightErr()
+	}
+	if blocked, err := model.IsBlocked(user.ID, s.UserID); err != nil {
+		return serializer.DBErr("查找拉黑关系错误", err)
+	} else if blocked {
+		return serializer.ParamErr("请先将对方移出黑名单", nil)
+	}
+
+	err := orm.DB().Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserFan{
+		UserID: s.UserID,
+		FanID:  user.ID,
+	}).Error
+	if err != nil {
+		return serializer.DBErr("关注失败", err)
+	}
+	InvalidateCount(s.UserID, user.ID)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "关注成功",
+	}
+}
+
+// Unfollow 取消关注用户
+func (s *FollowService) Unfollow(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	err := orm.DB().Where("user_id = ? AND fan_id = ?", s.UserID, user.ID).Delete(&model.UserFan{}).Error
+	if err != nil {
+		return serializer.DBErr("取消关注失败", err)
+	}
+	InvalidateCount(s.UserID, user.ID)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "取消关注成功",
+	}
+}
+
+// Followers 分页查询用户的粉丝，mutual表示该用户也关注了对方
+func (s *FollowListService) Followers() *serializer.Response {
+	return s.list("fan_id", "user_id")
+}
+
+// Following 分页查询用户关注的人，mutual表示对方也关注了该用户
+func (s *FollowListService) Following() *serializer.Response {
+	return s.list("user_id", "fan_id")
+}
+
+// list 以column为结果用户、by为当前用户查询关注关系
+func (s *FollowListService) list(column, by string) *serializer.Response {
+	var total int64
+	users := make([]*model.User, 0)
+
+	tx := orm.DB().Model(&model.User{}).
+		Joins("JOIN tb_user_fans ON tb_user_fans."+column+" = tb_user.id").
+		Where("tb_user_fans."+by+" = ?", s.UserID)
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找关注列表错误", err)
+	}
+	if err := orm.Pagination(tx, s.Page, s.Limit).
+		Order("tb_user_fans.created DESC").
+		Find(&users).Error; err != nil {
+		return serializer.DBErr("查找关注列表错误", err)
+	}
+
+	ids := make([]int64, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	mutual, err := reverseFollows(s.UserID, ids, by == "user_id")
+	if err != nil {
+		return serializer.DBErr("查找关注列表错误", err)
+	}
+
+	return serializer.BuildListResponse(total, s.Page, s.Limit, serializer.BuildFollows(users, mutual))
+}
+
+// Count 查询粉丝数与关注数，优先读取缓存
+func (s *FollowCountService) Count() *serializer.Response {
+	followers, err := cachedCount(fmt.Sprintf(followersKey, s.UserID), "user_id = ?", s.UserID)
+	if err != nil {
+		return serializer.DBErr("统计粉丝数错误", err)
+	}
+	following, err := cachedCount(fmt.Sprintf(followingKey, s.UserID), "fan_id = ?", s.UserID)
+	if err != nil {
+		return serializer.DBErr("统计关注数错误", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &serializer.FollowCount{
+			Followers: followers,
+			Following: following,
+		},
+	}
+}
+
+// Relation 查询当前用户与目标用户的关注关系
+func (s *FollowRelationService) Relation(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	var rows []*model.UserFan
+	err := orm.DB().
+		Where("(user_id = ? AND fan_id = ?) OR (user_id = ? AND fan_id = ?)", s.UserID, user.ID, user.ID, s.UserID).
+		Find(&rows).Error
+	if err != nil {
+		return serializer.DBErr("查找关注关系错误", err)
+	}
+
+	relation := &serializer.FollowRelation{}
+	for _, row := range rows {
+		if row.FanID == user.ID {
+			relation.Following = true
+		} else {
+			relation.FollowedBy = true
+		}
+	}
+	relation.Mutual = relation.Following && relation.FollowedBy
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: relation,
+	}
+}
+
+// Feed 按时间倒序返回关注用户的新投稿
+func (s *FollowFeedService) Feed(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	cursor, err := orm.ParseCursor(s.Cursor)
+	if err != nil {
+		return serializer.ParamErr("游标错误", err)
+	}
+	limit := s.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	following := orm.DB().Model(&model.UserFan{}).Select("user_id").Where("fan_id = ?", user.ID)
+	videos := make([]*model.Video, 0)
+	if err := cursor.Before(orm.DB(), "id").
+		Preload("Author").
+		Where("user_id IN (?)", following).
+		Where("status = ?", model.VideoNormal).
+		Where("user_id NOT IN (?)", model.BlockedUIDs(user.ID)).
+		Order("created DESC, id DESC").Limit(limit).
+		Find(&videos).Error; err != nil {
+		return serializer.DBErr("查找动态错误", err)
+	}
+	for _, video := range videos {
+		video.Author.Password = ""
+	}
+
+	next := ""
+	if len(videos) == limit {
+		last := videos[len(videos)-1]
+		next = (&orm.Cursor{Created: last.Created, ID: last.ID}).String()
+	}
+	return serializer.BuildCursorResponse(next, videos)
+}
+
+// reverseFollows 查找ids中与userID存在反向关注的用户
+// followers为true时ids为userID的粉丝，查userID是否关注了他们；否则查他们是否关注了userID
+func reverseFollows(userID int64, ids []int64, followers bool) (map[int64]bool, error) {
+	mutual := make(map[int64]bool, len(ids))
+	if len(ids) == 0 {
+		return mutual, nil
+	}
+	var rows []*model.UserFan
+	tx := orm.DB().Where("fan_id = ? AND user_id IN ?", userID, ids)
+	if !followers {
+		tx = orm.DB().Where("user_id = ? AND fan_id IN ?", userID, ids)
+	}
+	if err := tx.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if followers {
+			mutual[row.UserID] = true
+		} else {
+			mutual[row.FanID] = true
+		}
+	}
+	return mutual, nil
+}
+
+// cachedCount 读取计数缓存，未命中时查库并回填
+func cachedCount(key string, query string, args ...interface{}) (int64, error) {
+	if redis.Enabled() {
+		if v, err := redis.Get(key); err == nil {
+			if count, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return count, nil
+			}
+		}
+	}
+
+	var count int64
+	if err := orm.DB().Model(&model.UserFan{}).Where(query, args...).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if redis.Enabled() {
+		if err := redis.Set(key, count, countExpire); err != nil {
+			logger.Logger().Warn("[Follow] cache count err", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return count, nil
+}
+
+// InvalidateCount 关注关系变化后清除双方的计数缓存
+func InvalidateCount(userID, fanID int64) {
+	if !redis.Enabled() {
+		return
+	}
+	for _, key := range []string{fmt.Sprintf(followersKey, userID), fmt.Sprintf(followingKey, fanID)} {
+		if err := redis.Delete(key); err != nil {
+			logger.Logger().Warn("[Follow] invalidate count err", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package keyword
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/pkg/filter"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const reloadChannel = "vid:filter:reload" // 规则变更通知频道
+
+// QueryRulesService 分页查询过滤规则的服务
+type QueryRulesService struct {
+	Page    int    `form:"page" json:"page"`
+	Limit   int    `form:"limit" json:"limit" binding:"max=100"`
+	Keyword string `form:"keyword" json:"keyword" binding:"max=50"`
+	Action  string `form:"action" json:"action" binding:"omitempty,oneof=block mask flag"`
+}
+
+// AddRulesService 批量添加过滤规则的服务，已存在的词会被跳过
+type AddRulesService struct {
+	Words  []string `form:"words" json:"words" binding:"required,min=1,max=500,dive,max=50"`
+	Action string   `form:"action" json:"action" binding:"required,oneof=block mask flag"`
+	Note   string   `form:"note" json:"note" binding:"max=255"`
+}
+
+// UpdateRuleService 修改过滤规则的服务
+type UpdateRuleService struct {
+	ID     int64  `form:"id" json:"id" binding:"required"`
+	Action string `form:"action" json:"action" binding:"required,oneof=block mask flag"`
+	Note   string `form:"note" json:"note" binding:"max=255"`
+}
+
+// DeleteRulesService 批量删除过滤规则的服务
+type DeleteRulesService struct {
+	IDs []int64 `form:"ids" json:"ids" binding:"required,min=1,max=500"`
+}
+
+// TestFilterService 用当前规则检查一段文本的服务，用于调试规则
+type TestFilterService struct {
+	Text string `form:"text" json:"text" binding:"required,max=5000"`
+}
+
+// ReloadRulesService 重新加载过滤规则的服务
+type ReloadRulesService struct{}
+
+// Init 写入默认规则并加载，redis可用时订阅其他实例的规则变更
+func Init() error {
+	if err := model.SeedFilterRules(); err != nil {
+		return err
+	}
+	if err := load(); err != nil {
+		return err
+	}
+	if redis.Enabled() {
+		go func() {
+			for range redis.SubscribeChan(reloadChannel) {
+				if err := load(); err != nil {
+					logger.Logger().Warn("[Filter] reload rules err", zap.Error(err))
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func load() error {
+	rules, err := model.ListFilterRules()
+	if err != nil {
+		return err
+	}
+	filter.Load(rules)
+	return nil
+}
+
+// reload 从数据库重新加载规则，并通知其他实例重新加载
+func reload() error {
+	if err := load(); err != nil {
+		return err
+	}
+	if redis.Enabled() {
+		return redis.Publish(reloadChannel, "update")
+	}
+	return nil
+}
+
+// Check 过滤用户提交的内容，命中拒绝规则时返回错误响应，name为提示中使用的字段名
+// 返回结果中的Text为替换后的文本，应保存替换后的内容
+// 用于其他用户可见的文本：昵称、个人简介、频道名称与简介，用户名另由handle.Validate检查
+// 举报说明、令牌名称等只有本人或管理员可见的文本不做过滤，新增公开文本字段时需同样调用
+func Check(name, text string) (*filter.Result, *serializer.Response) {
+	res := filter.Check(text)
+	if res.Blocked() {
+		return nil, serializer.ParamErr(name+"包含不允许发布的内容", nil)
+	}
+	return res, nil
+}
+
+// Flag 内容保存后调用，有字段命中转人工审核的规则时提交审核工单，举报人为系统
+// results的key为字段名，记录在举报说明中，提交失败只记录日志
+func Flag(targetType string, targetID, authorID int64, results map[string]*filter.Result) {
+	fields := make([]string, 0, len(results))
+	for name, res := range results {
+		if res != nil && len(res.Words(filter.ActionFlag)) > 0 {
+			fields = append(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	sort.Strings(fields)
+	details := make([]string, len(fields))
+	for i, name := range fields {
+		details[i] = name + "：" + strings.Join(results[name].Words(filter.ActionFlag), "、")
+	}
+	detail := []rune(strings.Join(details, "；"))
+	if len(detail) > 500 {
+		detail = detail[:500]
+	}
+
+	err := model.CreateReport(targetType, targetID, authorID, &model.Report{
+		Reason: model.ReportKeyword,
+		Detail: string(detail),
+	}, 1)
+	if err != nil && !errors.Is(err, model.ErrReportDuplicate) {
+		logger.Logger().Warn("[Filter] flag content err", zap.String("target_type", targetType),
+			zap.Int64("target_id", targetID), zap.Error(err))
+	}
+}
+
+// Query 分页查询过滤规则
+func (s *QueryRulesService) Query() *serializer.Response {
+	page, limit := s.Page, s.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	tx := orm.DB().Model(&model.FilterRule{})
+	// 规范化后只剩字母、数字与汉字，不需要转义LIKE通配符
+	if keyword := filter.Normalize(s.Keyword); keyword != "" {
+		tx = tx.Where("word LIKE ?", "%"+keyword+"%")
+	}
+	if s.Action != "" {
+		tx = tx.Where("action = ?", s.Action)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找规则错误", err)
+	}
+	rules := make([]*model.FilterRule, 0)
+	if err := tx.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&rules).Error; err != nil {
+		return serializer.DBErr("查找规则错误", err)
+	}
+	return serializer.BuildListResponse(total, page, limit, rules)
+}
+
+// Add 批量添加规则，词按规范化后的形式保存，添加后立即生效
+func (s *AddRulesService) Add(c *gin.Context) *serializer.Response {
+	seen := map[string]bool{}
+	rules := make([]*model.FilterRule, 0, len(s.Words))
+	for _, word := range s.Words {
+		word = filter.Normalize(word)
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		rules = append(rules, &model.FilterRule{Word: word, Action: s.Action, Note: s.Note})
+	}
+	if len(rules) == 0 {
+		return serializer.ParamErr("没有有效的词", nil)
+	}
+
+	rdb := orm.DB().Clauses(clause.OnConflict{DoNothing: true}).Create(&rules)
+	if rdb.Error != nil {
+		return serializer.DBErr("添加规则失败", rdb.Error)
+	}
+	if err := reload(); err != nil {
+		return serializer.ServerErr("加载规则失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditFilterAdd,
+		TargetType: "filter_rule",
+		Detail:     "action=" + s.Action + " added=" + strconv.FormatInt(rdb.RowsAffected, 10) + " words=" + strings.Join(s.Words, ","),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "添加成功",
+		Data: rdb.RowsAffected,
+	}
+}
+
+// Update 修改规则的处理方式与备注
+func (s *UpdateRuleService) Update(c *gin.Context) *serializer.Response {
+	rule := &model.FilterRule{}
+	if err := orm.DB().First(rule, s.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("规则不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找规则错误", err)
+	}
+	before := map[string]interface{}{"action": rule.Action, "note": rule.Note}
+	after := map[string]interface{}{"action": s.Action, "note": s.Note}
+	if err := orm.DB().Model(rule).Updates(after).Error; err != nil {
+		return serializer.DBErr("修改规则失败", err)
+	}
+	if err := reload(); err != nil {
+		return serializer.ServerErr("加载规则失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditFilterUpdate,
+		TargetType: "filter_rule",
+		TargetID:   strconv.FormatInt(rule.ID, 10),
+		Detail:     rule.Word,
+	}, before, after)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "修改成功",
+		Data: rule,
+	}
+}
+
+// Delete 批量删除规则
+func (s *DeleteRulesService) Delete(c *gin.Context) *serializer.Response {
+	words := make([]string, 0)
+	if err := orm.DB().Model(&model.FilterRule{}).Where("id IN ?", s.IDs).Pluck("word", &words).Error; err != nil {
+		return serializer.DBErr("查找规则错误", err)
+	}
+	if err := orm.DB().Where("id IN ?", s.IDs).Delete(&model.FilterRule{}).Error; err != nil {
+		return serializer.DBErr("删除规则失败", err)
+	}
+	if err := reload(); err != nil {
+		return serializer.ServerErr("加载规则失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditFilterDelete,
+		TargetType: "filter_rule",
+		Detail:     "words=" + strings.Join(words, ","),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "删除成功",
+		Data: len(words),
+	}
+}
+
+// Reload 从数据库重新加载规则，用于直接修改数据库后使其生效
+func (s *ReloadRulesService) Reload(c *gin.Context) *serializer.Response {
+	if err := reload(); err != nil {
+		return serializer.ServerErr("加载规则失败", err)
+	}
+	audit.Record(c, &model.AuditLog{Action: model.AuditFilterReload, TargetType: "filter_rule"}, nil, nil)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "加载成功",
+	}
+}
+
+// Test 用当前规则检查文本，返回命中的规则与替换后的文本
+func (s *TestFilterService) Test() *serializer.Response {
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: filter.Check(s.Text),
+	}
+}
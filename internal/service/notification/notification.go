@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/orm"
+)
+
+// NotificationsService 查看站内通知的服务
+type NotificationsService struct {
+	Page   int  `form:"page" json:"page"`
+	Limit  int  `form:"limit" json:"limit" binding:"max=100"`
+	Unread bool `form:"unread" json:"unread"` // 只看未读
+}
+
+// ReadNotificationsService 标记通知为已读的服务，不传ids时标记全部
+type ReadNotificationsService struct {
+	IDs []int64 `form:"ids" json:"ids" binding:"max=100"`
+}
+
+// UnreadCountService 查看未读通知数的服务
+type UnreadCountService struct{}
+
+// List 分页查看当前用户的通知，按时间倒序
+func (s *NotificationsService) List(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	page, limit := s.Page, s.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	tx := orm.DB().Model(&model.Notification{}).Where("user_id = ?", user.ID)
+	if s.Unread {
+		tx = tx.Where("is_read = ?", false)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找通知错误", err)
+	}
+	notifications := make([]*model.Notification, 0)
+	if err := tx.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&notifications).Error; err != nil {
+		return serializer.DBErr("查找通知错误", err)
+	}
+	return serializer.BuildListResponse(total, page, limit, notifications)
+}
+
+// Read 标记通知为已读
+func (s *ReadNotificationsService) Read(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if err := model.ReadNotifications(user.ID, s.IDs); err != nil {
+		return serializer.DBErr("标记已读失败", err)
+	}
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+	}
+}
+
+// UnreadCount 当前用户的未读通知数
+func (s *UnreadCountService) UnreadCount(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	count, err := model.CountUnread(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找通知错误", err)
+	}
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: count,
+	}
+}
@@ -0,0 +1,337 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/subscription"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var errCaseHandled = errors.New("moderation case already handled")
+
+// ModerationQueueService 管理员查看审核队列的服务
+type ModerationQueueService struct {
+	Page       int    `form:"page" json:"page"`
+	Limit      int    `form:"limit" json:"limit" binding:"max=100"`
+	Status     string `form:"status" json:"status" binding:"omitempty,oneof=pending resolved dismissed"` // 默认只看待处理
+	TargetType string `form:"target_type" json:"target_type" binding:"omitempty,oneof=video user channel"`
+}
+
+// ModerationCaseService 按ID操作审核工单的服务
+type ModerationCaseService struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
+
+// ResolveCaseService 处理审核工单的服务
+type ResolveCaseService struct {
+	ID     int64  `form:"id" json:"id" binding:"required"`
+	Action string `form:"action" json:"action" binding:"required,oneof=dismiss hide remove warn suspend"`
+	Note   string `form:"note" json:"note" binding:"max=500"`        // 处理说明，警告与封禁时必填，会发给作者
+	Days   int    `form:"days" json:"days" binding:"min=0,max=3650"` // 封禁天数，0表示永久封禁
+}
+
+// RestoreVideoService 恢复被隐藏视频的服务
+type RestoreVideoService struct {
+	ID   int64  `form:"id" json:"id" binding:"required"`
+	Note string `form:"note" json:"note" binding:"max=500"` // 恢复原因，会发给作者
+}
+
+// Queue 分页查看审核工单，待处理工单按严重程度、举报数倒序，同等情况下先举报的先处理
+func (s *ModerationQueueService) Queue() *serializer.Response {
+	page, limit := s.Page, s.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	status := s.Status
+	if status == "" {
+		status = model.CasePending
+	}
+
+	tx := orm.DB().Model(&model.ModerationCase{}).Where("status = ?", status)
+	if s.TargetType != "" {
+		tx = tx.Where("target_type = ?", s.TargetType)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找工单错误", err)
+	}
+	if status == model.CasePending {
+		tx = tx.Order("severity DESC, report_count DESC, created, id")
+	} else {
+		tx = tx.Order("resolved DESC, id DESC")
+	}
+	cases := make([]*model.ModerationCase, 0)
+	if err := tx.Offset((page - 1) * limit).Limit(limit).Find(&cases).Error; err != nil {
+		return serializer.DBErr("查找工单错误", err)
+	}
+	return serializer.BuildListResponse(total, page, limit, cases)
+}
+
+// Detail 查看工单详情，包括被举报的内容、作者与全部举报
+func (s *ModerationCaseService) Detail() *serializer.Response {
+	mc, res := findCase(s.ID)
+	if res != nil {
+		return res
+	}
+	reports := make([]*model.Report, 0)
+	if err := orm.DB().Where("case_id = ?", mc.ID).Order("id").Find(&reports).Error; err != nil {
+		return serializer.DBErr("查找举报错误", err)
+	}
+	author, err := model.GetUser(mc.AuthorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		author = nil
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	var video *model.Video
+	if mc.TargetType == model.ReportVideo {
+		video = &model.Video{}
+		if err := orm.DB().Unscoped().First(video, mc.TargetID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			video = nil
+		} else if err != nil {
+			return serializer.DBErr("查找视频错误", err)
+		}
+	}
+	return serializer.BuildModerationCaseResponse(mc, author, video, reports)
+}
+
+func findCase(id int64) (*model.ModerationCase, *serializer.Response) {
+	mc := &model.ModerationCase{}
+	if err := orm.DB().First(mc, id).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, serializer.ParamErr("工单不存在", nil)
+	} else if err != nil {
+		return nil, serializer.DBErr("查找工单错误", err)
+	}
+	return mc, nil
+}
+
+// Resolve 处理工单：驳回、隐藏或下架视频、警告或封禁作者，处理后通知举报人与作者
+func (s *ResolveCaseService) Resolve(c *gin.Context) *serializer.Response {
+	moderator := c.MustGet("user").(*model.User)
+	mc, res := findCase(s.ID)
+	if res != nil {
+		return res
+	}
+	if mc.Status != model.CasePending {
+		return serializer.ParamErr("工单已处理", nil)
+	}
+	if !model.ModerationAllowed(mc.TargetType, s.Action) {
+		return serializer.ParamErr("该处理方式不适用于此类内容", nil)
+	}
+	if (s.Action == model.ModerateWarn || s.Action == model.ModerateSuspend) && s.Note == "" {
+		return serializer.ParamErr("请填写处理说明", nil)
+	}
+
+	var video *model.Video
+	if mc.TargetType == model.ReportVideo && s.Action != model.ModerateDismiss {
+		video = &model.Video{}
+		if err := orm.DB().First(video, mc.TargetID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return serializer.ParamErr("视频已删除，请驳回该工单", nil)
+		} else if err != nil {
+			return serializer.DBErr("查找视频错误", err)
+		}
+	}
+	var author *model.User
+	if s.Action == model.ModerateSuspend {
+		var err error
+		author, err = model.GetUser(mc.AuthorID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && author.Status == model.UserDeleted {
+			return serializer.ParamErr("作者账号已注销", nil)
+		} else if err != nil {
+			return serializer.DBErr("查找用户错误", err)
+		}
+		if author.Role == model.RoleAdmin {
+			return serializer.NoRightErr()
+		}
+	}
+
+	status := model.CaseResolved
+	if s.Action == model.ModerateDismiss {
+		status = model.CaseDismissed
+	}
+	var before, after map[string]interface{}
+	now := time.Now().Unix()
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		rdb := tx.Model(&model.ModerationCase{}).Where("id = ? AND archive = 0", mc.ID).Updates(map[string]interface{}{
+			"status":       status,
+			"action":       s.Action,
+			"moderator_id": moderator.ID,
+			"note":         s.Note,
+			"resolved":     now,
+			"archive":      mc.ID,
+		})
+		if rdb.Error != nil {
+			return rdb.Error
+		}
+		if rdb.RowsAffected == 0 {
+			return errCaseHandled
+		}
+
+		switch s.Action {
+		case model.ModerateHide, model.ModerateRemove:
+			next := model.VideoHidden
+			if s.Action == model.ModerateRemove {
+				next = model.VideoRemoved
+			}
+			before, after = map[string]interface{}{"status": video.Status}, map[string]interface{}{"status": next}
+			return tx.Model(&model.Video{}).Where("id = ?", video.ID).Update("status", next).Error
+		case model.ModerateSuspend:
+			var until int64
+			if s.Days > 0 {
+				until = time.Now().AddDate(0, 0, s.Days).Unix()
+			}
+			before = map[string]interface{}{"status": author.Status, "suspend_reason": author.SuspendReason, "suspend_until": author.SuspendUntil}
+			after = map[string]interface{}{"status": model.UserSuspend, "suspend_reason": s.Note, "suspend_until": until}
+			return model.SuspendUser(tx, author.ID, s.Note, until)
+		}
+		return nil
+	})
+	if errors.Is(err, errCaseHandled) {
+		return serializer.ParamErr("工单已处理", nil)
+	} else if err != nil {
+		return serializer.DBErr("处理工单失败", err)
+	}
+
+	if s.Action == model.ModerateRemove {
+		if err := subscription.Retract(video.ID); err != nil {
+			return serializer.DBErr("更新订阅动态失败", err)
+		}
+	}
+	if s.Action == model.ModerateSuspend {
+		if err := model.Sessions().RevokeUserSessions(author.ID); err != nil {
+			return serializer.DBErr("注销会话失败", err)
+		}
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditModerationResolve,
+		TargetType: mc.TargetType,
+		TargetID:   strconv.FormatInt(mc.TargetID, 10),
+		Detail:     fmt.Sprintf("case=%d action=%s note=%s", mc.ID, s.Action, s.Note),
+	}, before, after)
+	s.notify(mc, video)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "处理成功",
+	}
+}
+
+// notify 通知举报人处理结果，内容被处理时通知作者，发送失败不影响处理结果
+func (s *ResolveCaseService) notify(mc *model.ModerationCase, video *model.Video) {
+	reporters, err := model.CaseReporters(mc.ID)
+	if err != nil {
+		logger.Logger().Warn("[Moderation] find reporters err", zap.Int64("case_id", mc.ID), zap.Error(err))
+	}
+	content := "经审核，你举报的内容存在违规，已按社区规范处理，感谢你的反馈。"
+	if s.Action == model.ModerateDismiss {
+		content = "经审核，你举报的内容暂未发现违规，感谢你的反馈。"
+	}
+	notifications := make([]*model.Notification, 0, len(reporters)+1)
+	for _, id := range reporters {
+		notifications = append(notifications, &model.Notification{
+			UserID:     id,
+			Type:       model.NotifyReportResult,
+			Title:      "你的举报已处理",
+			Content:    content,
+			TargetType: mc.TargetType,
+			TargetID:   mc.TargetID,
+		})
+	}
+
+	if s.Action != model.ModerateDismiss {
+		var title, content string
+		switch s.Action {
+		case model.ModerateHide:
+			title, content = "你的视频已被隐藏", fmt.Sprintf("你的视频「%s」因违反社区规范已被隐藏。", video.Title)
+		case model.ModerateRemove:
+			title, content = "你的视频已被下架", fmt.Sprintf("你的视频「%s」因违反社区规范已被下架。", video.Title)
+		case model.ModerateWarn:
+			title, content = "你收到一次警告", "你发布的内容被举报并经审核存在违规，请遵守社区规范，多次违规将被封禁。"
+		case model.ModerateSuspend:
+			title, content = "你的账号已被封禁", "你的账号因违反社区规范已被封禁。"
+			if s.Days > 0 {
+				content = fmt.Sprintf("你的账号因违反社区规范已被封禁%d天。", s.Days)
+			}
+		}
+		if s.Note != "" {
+			content += "处理说明：" + s.Note
+		}
+		notifications = append(notifications, &model.Notification{
+			UserID:     mc.AuthorID,
+			Type:       model.NotifyModeration,
+			Title:      title,
+			Content:    content,
+			TargetType: mc.TargetType,
+			TargetID:   mc.TargetID,
+		})
+	}
+	if err := model.Notify(notifications...); err != nil {
+		logger.Logger().Warn("[Moderation] notify err", zap.Int64("case_id", mc.ID), zap.Error(err))
+	}
+}
+
+// Restore 将被隐藏的视频恢复展示，例如申诉成功或误判，下架的视频不能恢复
+func (s *RestoreVideoService) Restore(c *gin.Context) *serializer.Response {
+	video := &model.Video{}
+	if err := orm.DB().First(video, s.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("视频不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找视频错误", err)
+	}
+	if video.Status != model.VideoHidden {
+		return serializer.ParamErr("只能恢复被隐藏的视频", nil)
+	}
+	rdb := orm.DB().Model(&model.Video{}).Where("id = ? AND status = ?", video.ID, model.VideoHidden).
+		Update("status", model.VideoNormal)
+	if rdb.Error != nil {
+		return serializer.DBErr("恢复视频失败", rdb.Error)
+	}
+	if rdb.RowsAffected == 0 {
+		return serializer.ParamErr("只能恢复被隐藏的视频", nil)
+	}
+	// 恢复视为重新发布，投递到订阅者的收件箱，已有的记录不受影响
+	video.Status = model.VideoNormal
+	if err := subscription.Deliver(video); err != nil {
+		return serializer.DBErr("更新订阅动态失败", err)
+	}
+
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditModerationRestore,
+		TargetType: model.ReportVideo,
+		TargetID:   strconv.FormatInt(video.ID, 10),
+		Detail:     "note=" + s.Note,
+	}, map[string]interface{}{"status": model.VideoHidden}, map[string]interface{}{"status": model.VideoNormal})
+
+	content := fmt.Sprintf("你的视频「%s」经复核已恢复展示。", video.Title)
+	if s.Note != "" {
+		content += "说明：" + s.Note
+	}
+	if err := model.Notify(&model.Notification{
+		UserID:     video.UserID,
+		Type:       model.NotifyModeration,
+		Title:      "你的视频已恢复",
+		Content:    content,
+		TargetType: model.ReportVideo,
+		TargetID:   video.ID,
+	}); err != nil {
+		logger.Logger().Warn("[Moderation] notify err", zap.Int64("video_id", video.ID), zap.Error(err))
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "恢复成功",
+	}
+}
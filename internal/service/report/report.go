@@ -0,0 +1,106 @@
+package report
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+// ReportService 举报视频、用户或频道的服务
+type ReportService struct {
+	TargetType string `form:"target_type" json:"target_type" binding:"required,oneof=video user channel"`
+	TargetID   int64  `form:"target_id" json:"target_id" binding:"required"`
+	Reason     string `form:"reason" json:"reason" binding:"required"`
+	Detail     string `form:"detail" json:"detail" binding:"max=500"`
+}
+
+// ReportReasonsService 查看举报理由的服务
+type ReportReasonsService struct{}
+
+// Reasons 可选的举报理由
+func (s *ReportReasonsService) Reasons() *serializer.Response {
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: model.ReportReasons,
+	}
+}
+
+// reportTarget 查找被举报的对象，返回其作者
+func reportTarget(targetType string, targetID int64) (int64, *serializer.Response) {
+	switch targetType {
+	case model.ReportVideo:
+		video := &model.Video{}
+		if err := orm.DB().First(video, targetID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, serializer.ParamErr("视频不存在", nil)
+		} else if err != nil {
+			return 0, serializer.DBErr("查找视频错误", err)
+		}
+		if video.Status == model.VideoRemoved {
+			return 0, serializer.ParamErr("视频已下架", nil)
+		}
+		return video.UserID, nil
+	case model.ReportUser:
+		user, err := model.GetUser(targetID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && user.Status == model.UserDeleted {
+			return 0, serializer.ParamErr("用户不存在", nil)
+		} else if err != nil {
+			return 0, serializer.DBErr("查找用户错误", err)
+		}
+		return user.ID, nil
+	case model.ReportChannel:
+		owner := &model.ChannelAuthor{}
+		if err := orm.DB().Where("channel_id = ? AND role = ?", targetID, model.ChannelOwner).First(owner).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, serializer.ParamErr("频道不存在", nil)
+		} else if err != nil {
+			return 0, serializer.DBErr("查找频道错误", err)
+		}
+		return owner.UserID, nil
+	}
+	return 0, serializer.ParamErr("不支持举报该类内容", nil)
+}
+
+// Report 举报内容，同一对象的举报合并到同一工单，同一用户在工单处理前只能举报一次
+func (s *ReportService) Report(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	reason := model.FindReportReason(s.Reason)
+	if reason == nil {
+		return serializer.ParamErr("举报理由不存在", nil)
+	}
+	authorID, res := reportTarget(s.TargetType, s.TargetID)
+	if res != nil {
+		return res
+	}
+	if authorID == user.ID {
+		return serializer.ParamErr("不能举报自己的内容", nil)
+	}
+
+	count, err := model.CountRecentReports(user.ID, 24*time.Hour)
+	if err != nil {
+		return serializer.DBErr("查找举报记录错误", err)
+	}
+	if count >= model.ReportDailyLimit {
+		return serializer.TooManyRequestsErr("举报过于频繁，请稍后再试")
+	}
+
+	err = model.CreateReport(s.TargetType, s.TargetID, authorID, &model.Report{
+		ReporterID: user.ID,
+		Reason:     reason.Key,
+		Detail:     s.Detail,
+	}, reason.Severity)
+	if errors.Is(err, model.ErrReportDuplicate) {
+		return serializer.ParamErr("你已举报过该内容，请等待处理", nil)
+	} else if err != nil {
+		return serializer.DBErr("举报失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "举报成功，我们会尽快处理",
+	}
+}
@@ -0,0 +1,153 @@
+package role
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/user"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/rbac"
+)
+
+// NoParamsService 无参数的服务
+type NoParamsService struct{}
+
+// PolicyService 添加/删除访问策略的服务
+type PolicyService struct {
+	Role   string `form:"role" json:"role" binding:"required,max=100"`
+	Path   string `form:"path" json:"path" binding:"required,startswith=/,max=100"`
+	Method string `form:"method" json:"method" binding:"required,oneof=GET POST PUT PATCH DELETE *"`
+}
+
+// InheritService 添加/删除角色继承的服务
+type InheritService struct {
+	Role   string `form:"role" json:"role" binding:"required,max=100"`
+	Parent string `form:"parent" json:"parent" binding:"required,max=100,nefield=Role"`
+}
+
+// SetUserRoleService 修改用户角色的服务
+type SetUserRoleService struct {
+	UserID int64  `form:"user_id" json:"user_id" binding:"required"`
+	Role   string `form:"role" json:"role" binding:"required,max=10"`
+	Reason string `form:"reason" json:"reason" binding:"max=255"`
+}
+
+// Policies 查询全部策略与角色继承
+func (s *NoParamsService) Policies() *serializer.Response {
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: map[string]interface{}{
+			"policies":  rbac.Enforcer().GetPolicy(),
+			"groupings": rbac.Enforcer().GetGroupingPolicy(),
+		},
+	}
+}
+
+// Reload 从数据库重新加载策略并通知其他实例
+func (s *NoParamsService) Reload(c *gin.Context) *serializer.Response {
+	if err := rbac.Reload(); err != nil {
+		return serializer.ServerErr("加载策略失败", err)
+	}
+	audit.Record(c, &model.AuditLog{Action: model.AuditPoliciesReload, TargetType: "policy"}, nil, nil)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "加载成功",
+	}
+}
+
+// Add 添加策略
+func (s *PolicyService) Add(c *gin.Context) *serializer.Response {
+	if ok, err := rbac.Enforcer().AddPolicy(s.Role, s.Path, s.Method); err != nil {
+		return serializer.DBErr("添加策略失败", err)
+	} else if !ok {
+		return serializer.ParamErr("策略已存在", nil)
+	}
+	audit.Record(c, &model.AuditLog{Action: model.AuditPolicyAdd, TargetType: "policy", TargetID: s.Role}, nil, s)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "添加成功",
+	}
+}
+
+// Remove 删除策略
+func (s *PolicyService) Remove(c *gin.Context) *serializer.Response {
+	if ok, err := rbac.Enforcer().RemovePolicy(s.Role, s.Path, s.Method); err != nil {
+		return serializer.DBErr("删除策略失败", err)
+	} else if !ok {
+		return serializer.ParamErr("策略不存在", nil)
+	}
+	audit.Record(c, &model.AuditLog{Action: model.AuditPolicyRemove, TargetType: "policy", TargetID: s.Role}, s, nil)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "删除成功",
+	}
+}
+
+// Add 添加角色继承，Role拥有Parent的全部权限
+func (s *InheritService) Add(c *gin.Context) *serializer.Response {
+	if ok, err := rbac.Enforcer().AddGroupingPolicy(s.Role, s.Parent); err != nil {
+		return serializer.DBErr("添加角色继承失败", err)
+	} else if !ok {
+		return serializer.ParamErr("角色继承已存在", nil)
+	}
+	audit.Record(c, &model.AuditLog{Action: model.AuditInheritAdd, TargetType: "role", TargetID: s.Role}, nil, s)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "添加成功",
+	}
+}
+
+// Remove 删除角色继承
+func (s *InheritService) Remove(c *gin.Context) *serializer.Response {
+	if ok, err := rbac.Enforcer().RemoveGroupingPolicy(s.Role, s.Parent); err != nil {
+		return serializer.DBErr("删除角色继承失败", err)
+	} else if !ok {
+		return serializer.ParamErr("角色继承不存在", nil)
+	}
+	audit.Record(c, &model.AuditLog{Action: model.AuditInheritRemove, TargetType: "role", TargetID: s.Role}, s, nil)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "删除成功",
+	}
+}
+
+// knownRole 角色须出现在访问策略或角色继承中
+func knownRole(role string) bool {
+	for _, roles := range [][]string{rbac.Enforcer().GetAllSubjects(), rbac.Enforcer().GetAllRoles()} {
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetRole 修改用户角色，与其他管理操作相同，不能修改自己或其他管理员的角色
+func (s *SetUserRoleService) SetRole(c *gin.Context) *serializer.Response {
+	if !knownRole(s.Role) {
+		return serializer.ParamErr("角色不存在", nil)
+	}
+	target, res := user.AdminTarget(c, s.UserID)
+	if res != nil {
+		return res
+	}
+	oldRole := target.Role
+	if err := orm.DB().Model(target).Update("role", s.Role).Error; err != nil {
+		return serializer.DBErr("修改角色失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditAdminSetRole,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(target.ID, 10),
+		Detail:     s.Reason,
+	}, map[string]interface{}{"role": oldRole}, map[string]interface{}{"role": s.Role})
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "修改成功",
+	}
+}
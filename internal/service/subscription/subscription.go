@@ -0,0 +1,264 @@
+package subscription
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SubscribeService 订阅/取消订阅频道的服务
+type SubscribeService struct {
+	ChannelID int64 `form:"channel_id" json:"channel_id" binding:"required"`
+}
+
+// MySubscriptionsService 我的订阅列表的服务
+type MySubscriptionsService struct {
+	Page  int `form:"page" json:"page" query:"page"`
+	Limit int `form:"limit" json:"limit" query:"limit"`
+}
+
+// FeedService 订阅动态的服务
+type FeedService struct {
+	Cursor string `form:"cursor" json:"cursor" query:"cursor"`
+	Limit  int    `form:"limit" json:"limit" query:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// Subscribe 订阅频道
+func (s *SubscribeService) Subscribe(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	channel := &model.Channel{}
+	if err := orm.DB().First(channel, s.ChannelID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("频道不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找频道错误", err)
+	}
+
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		rdb := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ChannelSubscriber{
+			ChannelID: s.ChannelID,
+			UserID:    user.ID,
+		})
+		if rdb.Error != nil || rdb.RowsAffected == 0 {
+			return rdb.Error
+		}
+		if err := tx.Model(channel).UpdateColumn("subscribed", gorm.Expr("subscribed + 1")).Error; err != nil {
+			return err
+		}
+		subscribed, err := subscribedCount(tx, s.ChannelID)
+		if err != nil || subscribed >= model.FanOutLimit {
+			return err
+		}
+		return backfill(tx, user.ID, s.ChannelID)
+	})
+	if err != nil {
+		return serializer.DBErr("订阅失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "订阅成功",
+	}
+}
+
+// Unsubscribe 取消订阅频道
+func (s *SubscribeService) Unsubscribe(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		rdb := tx.Where("channel_id = ? AND user_id = ?", s.ChannelID, user.ID).Delete(&model.ChannelSubscriber{})
+		if rdb.Error != nil || rdb.RowsAffected == 0 {
+			return rdb.Error
+		}
+		if err := tx.Model(&model.Channel{}).Where("id = ? AND subscribed > 0", s.ChannelID).
+			UpdateColumn("subscribed", gorm.Expr("subscribed - 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND channel_id = ?", user.ID, s.ChannelID).Delete(&model.FeedItem{}).Error; err != nil {
+			return err
+		}
+		// 降到阈值以下时改回写扩散，补入之前按需拉取的视频
+		subscribed, err := subscribedCount(tx, s.ChannelID)
+		if err != nil || subscribed != model.FanOutLimit-1 {
+			return err
+		}
+		return model.BackfillFeed(tx, s.ChannelID)
+	})
+	if err != nil {
+		return serializer.DBErr("取消订阅失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "取消订阅成功",
+	}
+}
+
+// MySubscriptions 分页查询我订阅的频道，按订阅时间倒序
+func (s *MySubscriptionsService) MySubscriptions(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	var total int64
+	channels := make([]*model.Channel, 0)
+
+	tx := orm.DB().Model(&model.Channel{}).
+		Joins("JOIN tb_channel_subscriber ON tb_channel_subscriber.channel_id = tb_channel.id").
+		Where("tb_channel_subscriber.user_id = ?", user.ID)
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找订阅错误", err)
+	}
+	if err := orm.Pagination(tx, s.Page, s.Limit).
+		Order("tb_channel_subscriber.created DESC").
+		Find(&channels).Error; err != nil {
+		return serializer.DBErr("查找订阅错误", err)
+	}
+
+	return serializer.BuildListResponse(total, s.Page, s.Limit, channels)
+}
+
+// Feed 按时间倒序返回订阅频道的新视频
+// 小频道的视频在发布时已写入收件箱，大频道的视频在此处按需拉取后合并
+func (s *FeedService) Feed(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	cursor, err := orm.ParseCursor(s.Cursor)
+	if err != nil {
+		return serializer.ParamErr("游标错误", err)
+	}
+	limit := s.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	// 收件箱
+	var inbox []*model.FeedItem
+	if err := cursor.Before(orm.DB().Where("user_id = ?", user.ID), "video_id").
+		Order("created DESC, video_id DESC").Limit(limit).
+		Find(&inbox).Error; err != nil {
+		return serializer.DBErr("查找动态错误", err)
+	}
+	entries := make([]*orm.Cursor, 0, len(inbox)+limit)
+	for _, item := range inbox {
+		entries = append(entries, &orm.Cursor{Created: item.Created, ID: item.VideoID})
+	}
+
+	// 大频道
+	large := orm.DB().Model(&model.ChannelSubscriber{}).Select("channel_id").
+		Joins("JOIN tb_channel ON tb_channel.id = tb_channel_subscriber.channel_id").
+		Where("tb_channel_subscriber.user_id = ? AND tb_channel.subscribed >= ?", user.ID, model.FanOutLimit)
+	var pulled []*orm.Cursor
+	if err := cursor.Before(orm.DB().Model(&model.Video{}), "id").
+		Select("created, id").
+		Where("channel_id IN (?)", large).
+		Where("status = ?", model.VideoNormal).
+		Order("created DESC, id DESC").Limit(limit).
+		Scan(&pulled).Error; err != nil {
+		return serializer.DBErr("查找动态错误", err)
+	}
+	entries = append(entries, pulled...)
+
+	entries = mergeEntries(entries, limit)
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	videos, err := loadVideos(ids, user.ID)
+	if err != nil {
+		return serializer.DBErr("查找视频错误", err)
+	}
+
+	next := ""
+	if len(entries) == limit {
+		next = entries[len(entries)-1].String()
+	}
+	return serializer.BuildCursorResponse(next, videos)
+}
+
+// Deliver 视频发布到频道后调用，小频道写扩散到所有订阅者的收件箱，已下架的视频不投递
+func Deliver(video *model.Video) error {
+	if video.ChannelID == nil || video.Status == model.VideoRemoved {
+		return nil
+	}
+	channel := &model.Channel{}
+	if err := orm.DB().First(channel, *video.ChannelID).Error; err != nil {
+		return err
+	}
+	if channel.Subscribed >= model.FanOutLimit {
+		return nil
+	}
+	return orm.DB().Exec(
+		"INSERT IGNORE INTO tb_feed_item (user_id, video_id, channel_id, created) "+
+			"SELECT user_id, ?, channel_id, ? FROM tb_channel_subscriber WHERE channel_id = ?",
+		video.ID, video.Created, channel.ID).Error
+}
+
+// Retract 视频移出频道或删除后调用，从收件箱中移除
+func Retract(videoID int64) error {
+	return orm.DB().Where("video_id = ?", videoID).Delete(&model.FeedItem{}).Error
+}
+
+// subscribedCount 事务中读取频道当前的订阅数
+func subscribedCount(tx *gorm.DB, channelID int64) (int64, error) {
+	var subscribed int64
+	err := tx.Model(&model.Channel{}).Select("subscribed").Where("id = ?", channelID).Scan(&subscribed).Error
+	return subscribed, err
+}
+
+// backfill 新订阅小频道时把最近的视频补入收件箱
+func backfill(tx *gorm.DB, userID, channelID int64) error {
+	return tx.Exec(
+		"INSERT IGNORE INTO tb_feed_item (user_id, video_id, channel_id, created) "+
+			"SELECT ?, id, channel_id, created FROM tb_video "+
+			"WHERE channel_id = ? AND status <> ? AND deleted_at IS NULL ORDER BY created DESC LIMIT ?",
+		userID, channelID, model.VideoRemoved, model.FeedBackfill).Error
+}
+
+// mergeEntries 合并收件箱与拉取的结果，去重后按(created, id)倒序取前limit条
+func mergeEntries(entries []*orm.Cursor, limit int) []*orm.Cursor {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Created != entries[j].Created {
+			return entries[i].Created > entries[j].Created
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	merged := make([]*orm.Cursor, 0, limit)
+	for i, e := range entries {
+		if i > 0 && e.ID == entries[i-1].ID {
+			continue
+		}
+		if len(merged) == limit {
+			break
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// loadVideos 按给定顺序加载视频及作者，过滤掉viewer拉黑的作者
+func loadVideos(ids []int64, viewer int64) ([]*model.Video, error) {
+	videos := make([]*model.Video, 0, len(ids))
+	if len(ids) == 0 {
+		return videos, nil
+	}
+	var found []*model.Video
+	if err := orm.DB().Preload("Author").
+		Where("id IN ?", ids).
+		Where("status = ?", model.VideoNormal).
+		Where("user_id NOT IN (?)", model.BlockedUIDs(viewer)).
+		Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*model.Video, len(found))
+	for _, v := range found {
+		v.Author.Password = ""
+		byID[v.ID] = v
+	}
+	for _, id := range ids {
+		if v, ok := byID[id]; ok {
+			videos = append(videos, v)
+		}
+	}
+	return videos, nil
+}
@@ -0,0 +1,245 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/pkg/jwt"
+	"github.com/vidorg/vid_backend/pkg/mail"
+	"github.com/vidorg/vid_backend/pkg/oauth"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/useragent"
+	"gorm.io/gorm"
+)
+
+const (
+	impersonateExpire = 30 * time.Minute // 代为登录的会话有效期，不签发refresh token，到期后需重新申请
+	adminAuditLimit   = 20               // 用户详情中展示的审计日志条数
+)
+
+// AdminUserService 管理员操作指定用户的服务
+type AdminUserService struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
+
+// SetUserStatusService 管理员修改用户状态的服务，封禁时需填写原因
+type SetUserStatusService struct {
+	ID     int64  `form:"id" json:"id" binding:"required"`
+	Status string `form:"status" json:"status" binding:"required,oneof=active inactive suspend"`
+	Reason string `form:"reason" json:"reason" binding:"max=255"`
+	Days   int    `form:"days" json:"days" binding:"min=0,max=3650"` // 封禁天数，0表示永久封禁
+}
+
+// AdminTarget 查找被操作的用户，管理员不能操作自己、其他管理员或已注销的用户
+// 修改角色等其他管理接口同样使用
+func AdminTarget(c *gin.Context, id int64) (*model.User, *serializer.Response) {
+	admin := c.MustGet("user").(*model.User)
+	if id == admin.ID {
+		return nil, serializer.ParamErr("不能对自己执行该操作", nil)
+	}
+	user, err := model.GetUser(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return nil, serializer.DBErr("查找用户错误", err)
+	}
+	if user.Role == model.RoleAdmin {
+		return nil, serializer.NoRightErr()
+	}
+	if user.Status == model.UserDeleted {
+		return nil, serializer.ParamErr("用户已注销", nil)
+	}
+	return user, nil
+}
+
+// auditAdmin 记录管理员对用户的操作
+func auditAdmin(c *gin.Context, action string, userID int64, detail string) {
+	audit.Record(c, &model.AuditLog{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(userID, 10),
+		Detail:     detail,
+	}, nil, nil)
+}
+
+// Detail 查看用户详情，包括登录设备、绑定的外部身份、访问令牌与最近的审计日志
+func (s *AdminUserService) Detail() *serializer.Response {
+	user, err := model.GetUser(s.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	sessions, err := model.Sessions().ListUserSessions(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找会话错误", err)
+	}
+	identities := make([]*model.UserIdentity, 0)
+	if err := orm.DB().Where("user_id = ?", user.ID).Order("id").Find(&identities).Error; err != nil {
+		return serializer.DBErr("查找绑定错误", err)
+	}
+	tokens, err := model.ListAPITokens(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找访问令牌错误", err)
+	}
+	logs := make([]*model.AuditLog, 0)
+	if err := orm.DB().Where("actor_id = ? OR (target_type = 'user' AND target_id = ?)", user.ID, strconv.FormatInt(user.ID, 10)).
+		Order("id DESC").Limit(adminAuditLimit).Find(&logs).Error; err != nil {
+		return serializer.DBErr("查找审计日志错误", err)
+	}
+	return serializer.BuildUserDetailResponse(user, sessions, identities, tokens, logs)
+}
+
+// SetStatus 修改用户状态，封禁后立即吊销该用户的全部会话
+func (s *SetUserStatusService) SetStatus(c *gin.Context) *serializer.Response {
+	user, res := AdminTarget(c, s.ID)
+	if res != nil {
+		return res
+	}
+	before := map[string]interface{}{"status": user.Status, "suspend_reason": user.SuspendReason, "suspend_until": user.SuspendUntil}
+	updates := map[string]interface{}{"status": s.Status, "suspend_reason": "", "suspend_until": int64(0)}
+	if s.Status == model.UserSuspend {
+		if s.Reason == "" {
+			return serializer.ParamErr("请填写封禁原因", nil)
+		}
+		updates["suspend_reason"] = s.Reason
+		if s.Days > 0 {
+			updates["suspend_until"] = time.Now().AddDate(0, 0, s.Days).Unix()
+		}
+	}
+
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		return serializer.DBErr("修改状态失败", err)
+	}
+	if s.Status != model.UserActive {
+		if err := model.Sessions().RevokeUserSessions(user.ID); err != nil {
+			return serializer.DBErr("注销会话失败", err)
+		}
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditAdminSetStatus,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+		Detail:     s.Reason,
+	}, before, updates)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "修改成功",
+	}
+}
+
+// ForceLogout 吊销用户的全部登录会话，个人访问令牌不受影响
+func (s *AdminUserService) ForceLogout(c *gin.Context) *serializer.Response {
+	user, res := AdminTarget(c, s.ID)
+	if res != nil {
+		return res
+	}
+	if err := model.Sessions().RevokeUserSessions(user.ID); err != nil {
+		return serializer.DBErr("注销会话失败", err)
+	}
+	auditAdmin(c, model.AuditAdminLogout, user.ID, "")
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "已强制下线",
+	}
+}
+
+// ResetPassword 将用户密码重置为随机值并吊销会话与访问令牌，用户通过邮件设置新密码
+// 管理员不会看到任何可用的密码
+func (s *AdminUserService) ResetPassword(c *gin.Context) *serializer.Response {
+	user, res := AdminTarget(c, s.ID)
+	if res != nil {
+		return res
+	}
+	random, err := oauth.RandomString()
+	if err != nil {
+		return serializer.EncryptErr("密码生成失败", err)
+	}
+	if err := user.SetPassword(random); err != nil {
+		return serializer.EncryptErr("密码加密失败", err)
+	}
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Update("password", user.Password).Error; err != nil {
+		return serializer.DBErr("重置密码失败", err)
+	}
+	if err := model.Sessions().RevokeUserSessions(user.ID); err != nil {
+		return serializer.DBErr("注销会话失败", err)
+	}
+	if err := model.DeleteUserAPITokens(user.ID); err != nil {
+		return serializer.DBErr("删除访问令牌失败", err)
+	}
+
+	sent := false
+	if user.Email != nil && *user.Email != "" && mail.Enabled() {
+		if err := sendTokenEmail(user, model.TokenResetPassword, "重置你的 Vid 密码", "/reset-password"); err != nil {
+			return serializer.ServerErr("发送邮件失败", err)
+		}
+		sent = true
+	}
+	auditAdmin(c, model.AuditAdminResetPwd, user.ID, "email_sent="+strconv.FormatBool(sent))
+
+	msg := "已重置密码并发送邮件"
+	if !sent {
+		msg = "已重置密码，用户未绑定邮箱，需通过其他登录方式登录后修改"
+	}
+	return &serializer.Response{
+		Code: 200,
+		Msg:  msg,
+		Data: sent,
+	}
+}
+
+// Impersonate 管理员代为登录用户账号以排查问题
+// 会话在用户的设备列表中可见，期间的写操作均记录审计日志
+func (s *AdminUserService) Impersonate(c *gin.Context) *serializer.Response {
+	user, res := AdminTarget(c, s.ID)
+	if res != nil {
+		return res
+	}
+	if !user.IsActive() {
+		return serializer.UserStatusErr("账号状态异常")
+	}
+	admin := c.MustGet("user").(*model.User)
+	token, sid, err := issueImpersonation(c, user.ID, admin.ID)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	auditAdmin(c, model.AuditImpersonate, user.ID, "session="+sid)
+
+	return serializer.BuildLoginResponse(user, token)
+}
+
+// issueImpersonation 创建管理员代为登录的会话，只签发access token
+func issueImpersonation(c *gin.Context, userID, adminID int64) (*serializer.Token, string, error) {
+	sid, err := model.NewSessionID()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := &model.Session{
+		ID:           sid,
+		UserID:       userID,
+		Device:       useragent.DeviceName(c.Request.UserAgent()),
+		IP:           c.ClientIP(),
+		ExpiresAt:    now.Add(impersonateExpire).Unix(),
+		LastSeen:     now.Unix(),
+		Impersonator: adminID,
+	}
+	if err := model.Sessions().CreateSession(session); err != nil {
+		return nil, "", err
+	}
+	access, err := jwt.GenerateSessionToken(userID, sid, impersonateExpire)
+	if err != nil {
+		return nil, "", err
+	}
+	return &serializer.Token{
+		Token:     string(access),
+		ExpiresIn: int64(impersonateExpire / time.Second),
+	}, sid, nil
+}
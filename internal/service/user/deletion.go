@@ -0,0 +1,153 @@
+package user
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/follow"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"go.uber.org/zap"
+)
+
+const purgeBatch = 100 // 每轮最多清除的账号数
+
+// DeleteAccountService 申请注销账号的服务，开启两步验证时需要验证码
+type DeleteAccountService struct {
+	Password string `form:"password" json:"password" binding:"required"`
+	Code     string `form:"code" json:"code"`
+}
+
+// RequestDeletion 申请注销账号，冷静期结束后清除个人数据
+// 申请后吊销个人访问令牌，冷静期内仍可登录并撤销
+func (s *DeleteAccountService) RequestDeletion(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if user.DeletionAt > 0 {
+		return serializer.ParamErr("已申请注销", nil)
+	}
+	if ok, err := user.MatchPassword(s.Password); err != nil {
+		return serializer.EncryptErr("密码校验失败", err)
+	} else if !ok {
+		return serializer.ParamErr("密码或验证码错误", nil)
+	}
+	if user.TOTPEnabled {
+		if ok, err := user.VerifySecondFactor(s.Code); err != nil {
+			return serializer.DBErr("校验验证码失败", err)
+		} else if !ok {
+			return serializer.ParamErr("密码或验证码错误", nil)
+		}
+	}
+
+	deletionAt := time.Now().Add(model.DeletionGrace).Unix()
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Update("deletion_at", deletionAt).Error; err != nil {
+		return serializer.DBErr("申请注销失败", err)
+	}
+	if err := model.DeleteUserAPITokens(user.ID); err != nil {
+		return serializer.DBErr("删除访问令牌失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    user.ID,
+		Action:     model.AuditDeletionRequest,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "已申请注销，冷静期内可撤销",
+		Data: deletionAt,
+	}
+}
+
+// CancelDeletion 撤销注销申请
+func (s *NoParamsService) CancelDeletion(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	rdb := orm.DB().Model(&model.User{}).Where("id = ? AND deletion_at > 0", user.ID).Update("deletion_at", 0)
+	if rdb.Error != nil {
+		return serializer.DBErr("撤销注销失败", rdb.Error)
+	}
+	if rdb.RowsAffected == 0 {
+		return serializer.ParamErr("未申请注销", nil)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    user.ID,
+		Action:     model.AuditDeletionCancel,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "已撤销注销",
+	}
+}
+
+// StartMaintenance 定期清除冷静期已结束的账号并清理过期的导出文件
+func StartMaintenance(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			maintain(time.Now().Unix())
+			<-ticker.C
+		}
+	}()
+}
+
+// maintain 执行一轮清理，panic时记录日志并等待下一轮，避免导致进程退出
+func maintain(now int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Logger().Error("[Deletion] maintenance panic", zap.Any("panic", r))
+		}
+	}()
+	purgeAccounts(now)
+	cleanExports(now)
+}
+
+// purgeAccounts 清除冷静期已结束账号的个人数据
+func purgeAccounts(now int64) {
+	ids, err := model.DueDeletions(now, purgeBatch)
+	if err != nil {
+		logger.Logger().Warn("[Deletion] find due deletions err", zap.Error(err))
+		return
+	}
+	for _, id := range ids {
+		purged, err := model.PurgeUser(id, now)
+		if err != nil {
+			logger.Logger().Warn("[Deletion] purge user err", zap.Int64("user_id", id), zap.Error(err))
+			continue
+		}
+		if purged == nil {
+			continue
+		}
+
+		// 会话存储在redis时不在事务内
+		if err := model.Sessions().RevokeUserSessions(id); err != nil {
+			logger.Logger().Warn("[Deletion] revoke sessions err", zap.Int64("user_id", id), zap.Error(err))
+		}
+		for _, fan := range purged.Fans {
+			follow.InvalidateCount(id, fan)
+		}
+		for _, target := range purged.Following {
+			follow.InvalidateCount(target, id)
+		}
+		for _, file := range purged.Exports {
+			removeExportFile(file)
+		}
+		detail := "deleted"
+		if purged.Anonymized {
+			detail = "anonymized"
+		}
+		model.Audit(&model.AuditLog{
+			Action:     model.AuditAccountPurged,
+			TargetType: "user",
+			TargetID:   strconv.FormatInt(id, 10),
+			Detail:     detail,
+		})
+	}
+}
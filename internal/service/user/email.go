@@ -0,0 +1,214 @@
+package user
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/conf"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/mail"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const defaultEmailExpire = time.Hour // 邮件链接默认有效期
+
+// VerifyEmailService 验证邮箱的服务
+type VerifyEmailService struct {
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+// SendEmailService 按邮箱发送验证或重置密码邮件的服务
+type SendEmailService struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+}
+
+// ResetPasswordByTokenService 通过邮件令牌重置密码的服务
+type ResetPasswordByTokenService struct {
+	Token    string `form:"token" json:"token" binding:"required"`
+	Password string `form:"password" json:"password" binding:"required,max=72"`
+}
+
+// emailExpire 邮件链接有效期，取自conf
+func emailExpire() time.Duration {
+	if c := conf.Config().Email; c != nil && c.Expire > 0 {
+		return time.Duration(c.Expire) * time.Second
+	}
+	return defaultEmailExpire
+}
+
+// sendTokenEmail 生成一次性令牌并异步发送邮件，path为前端处理该令牌的页面
+func sendTokenEmail(user *model.User, purpose, subject, path string) error {
+	if user.Email == nil || *user.Email == "" {
+		return nil
+	}
+	return sendTokenEmailTo(user, *user.Email, purpose, subject, path)
+}
+
+// sendTokenEmailTo 同sendTokenEmail，发往指定地址，用于验证新邮箱
+func sendTokenEmailTo(user *model.User, to, purpose, subject, path string) error {
+	token, err := model.NewUserToken(user.ID, purpose, emailExpire())
+	if err != nil {
+		return err
+	}
+	link := strings.TrimRight(conf.Config().Meta.SiteURL, "/") + path + "?token=" + url.QueryEscape(token)
+	msg, err := mail.Render(to, subject, purpose, map[string]interface{}{
+		"Nickname":      user.Nickname,
+		"Link":          link,
+		"ExpireMinutes": int64(emailExpire() / time.Minute),
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := mail.Send(msg); err != nil {
+			logger.Logger().Warn("[Mail] send failed", zap.Int64("user_id", user.ID),
+				zap.String("purpose", purpose), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// sendVerifyEmail 发送注册验证邮件
+func sendVerifyEmail(user *model.User) error {
+	return sendTokenEmail(user, model.TokenVerifyEmail, "验证你的 Vid 邮箱", "/verify-email")
+}
+
+// VerifyEmail 验证邮箱，未激活的用户随之激活
+func (s *VerifyEmailService) VerifyEmail(c *gin.Context) *serializer.Response {
+	token, err := model.UseUserToken(orm.DB(), s.Token, model.TokenVerifyEmail)
+	if errors.Is(err, model.ErrUserTokenInvalid) {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("验证邮箱失败", err)
+	}
+
+	err = orm.DB().Model(&model.User{}).
+		Where("id = ? AND status = ?", token.UserID, model.UserInactive).
+		Update("status", model.UserActive).Error
+	if err != nil {
+		return serializer.DBErr("激活用户失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    token.UserID,
+		Action:     model.AuditUserVerifyEmail,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(token.UserID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "验证成功",
+	}
+}
+
+// ResendVerifyEmail 重新发送验证邮件，无论邮箱是否存在都返回成功，避免泄露注册信息
+func (s *SendEmailService) ResendVerifyEmail() *serializer.Response {
+	if !mail.Enabled() {
+		return serializer.ServerErr("未配置邮件服务", nil)
+	}
+	user := &model.User{}
+	err := orm.DB().Where("email = ? AND status = ?", s.Email, model.UserInactive).First(user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if err == nil {
+		if err := sendVerifyEmail(user); err != nil {
+			return serializer.ServerErr("发送邮件失败", err)
+		}
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "邮件已发送",
+	}
+}
+
+// ForgotPassword 发送重置密码邮件，无论邮箱是否存在都返回成功
+func (s *SendEmailService) ForgotPassword() *serializer.Response {
+	if !mail.Enabled() {
+		return serializer.ServerErr("未配置邮件服务", nil)
+	}
+	user := &model.User{}
+	err := orm.DB().Where("email = ?", s.Email).First(user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if err == nil {
+		if err := sendTokenEmail(user, model.TokenResetPassword, "重置你的 Vid 密码", "/reset-password"); err != nil {
+			return serializer.ServerErr("发送邮件失败", err)
+		}
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "邮件已发送",
+	}
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码，并注销该用户的所有会话与访问令牌
+func (s *ResetPasswordByTokenService) ResetPassword(c *gin.Context) *serializer.Response {
+	if res := checkPassword(s.Password, ""); res != nil {
+		return res
+	}
+	// 先校验新密码再消费令牌，密码不符合要求时令牌仍可重试
+	token, err := model.GetUserToken(s.Token, model.TokenResetPassword)
+	if errors.Is(err, model.ErrUserTokenInvalid) {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("重置密码失败", err)
+	}
+
+	user, err := model.GetUser(token.UserID)
+	if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if res := checkPassword(s.Password, user.UserName); res != nil {
+		return res
+	}
+	if err := user.SetPassword(s.Password); err != nil {
+		return serializer.EncryptErr("密码加密失败", err)
+	}
+	err = orm.DB().Transaction(func(tx *gorm.DB) error {
+		if _, err := model.UseUserToken(tx, s.Token, model.TokenResetPassword); err != nil {
+			return err
+		}
+		// 能收到邮件即证明拥有该邮箱，未激活的用户一并激活
+		return tx.Model(&model.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password": user.Password,
+			"status":   gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", model.UserInactive, model.UserActive),
+		}).Error
+	})
+	if errors.Is(err, model.ErrUserTokenInvalid) {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("重置密码失败", err)
+	}
+	if err := model.Sessions().RevokeUserSessions(token.UserID); err != nil {
+		return serializer.DBErr("注销会话失败", err)
+	}
+	// 找回密码说明账号可能已泄露，个人访问令牌一并作废
+	if err := model.DeleteUserAPITokens(token.UserID); err != nil {
+		return serializer.DBErr("删除访问令牌失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    token.UserID,
+		Action:     model.AuditUserPasswordReset,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(token.UserID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "重置成功",
+	}
+}
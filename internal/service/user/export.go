@@ -0,0 +1,284 @@
+package user
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/conf"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/oauth"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const defaultExportPath = "./export/"
+
+// exportReadme 导出包中的说明
+const exportReadme = `Vid 个人数据导出
+
+profile.json            账号资料
+videos.json             上传的视频信息
+channels.json           参与的频道及角色
+following.json          关注的用户
+followers.json          粉丝
+subscriptions.json      订阅的频道
+blocks.json             拉黑的用户
+identities.json         绑定的第三方账号
+passkeys.json           通行密钥
+api_tokens.json         个人访问令牌（不含令牌本身）
+sessions.json           登录设备
+username_history.json   用户名修改记录
+notifications.json      站内通知
+reports.json            提交的举报
+audit_logs.json         与账号相关的安全记录
+
+本服务不保存评论、观看历史与收藏，因此导出包中没有这些数据。
+`
+
+// ExportService 操作数据导出任务的服务
+type ExportService struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
+
+// exportSection 导出包中的一个文件，where中以@uid与@sid引用用户ID的数值与字符串形式
+type exportSection struct {
+	name    string
+	model   interface{}
+	columns string
+	where   string
+}
+
+var exportSections = []exportSection{
+	{"videos.json", &model.Video{}, "id, title, description, url, cover, category_id, channel_id, created, updated_at", "user_id = @uid"},
+	{"channels.json", &model.ChannelAuthor{}, "channel_id, role, created", "user_id = @uid"},
+	{"following.json", &model.UserFan{}, "user_id, created", "fan_id = @uid"},
+	{"followers.json", &model.UserFan{}, "fan_id, created", "user_id = @uid"},
+	{"subscriptions.json", &model.ChannelSubscriber{}, "channel_id, created", "user_id = @uid"},
+	{"blocks.json", &model.Block{}, "to_uid, created", "from_uid = @uid"},
+	{"identities.json", &model.UserIdentity{}, "provider, username, email, created", "user_id = @uid"},
+	{"passkeys.json", &model.Passkey{}, "name, aaguid, attestation, last_used, created", "user_id = @uid"},
+	{"api_tokens.json", &model.APIToken{}, "name, hint, scopes, expires_at, last_used, last_ip, created", "user_id = @uid"},
+	{"username_history.json", &model.HandleHistory{}, "handle, released_at, created", "user_id = @uid"},
+	{"notifications.json", &model.Notification{}, "type, title, content, target_type, target_id, is_read, created", "user_id = @uid"},
+	{"reports.json", &model.Report{}, "case_id, reason, detail, created", "reporter_id = @uid"},
+	{"audit_logs.json", &model.AuditLog{}, "action, target_type, target_id, ip, created", "actor_id = @uid OR (target_type = 'user' AND target_id = @sid)"},
+}
+
+// exportDir 导出文件目录，取自conf
+func exportDir() string {
+	if path := conf.Config().Meta.ExportPath; path != "" {
+		return path
+	}
+	return defaultExportPath
+}
+
+// RequestExport 申请导出个人数据，导出包在后台生成
+func (s *NoParamsService) RequestExport(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	last, err := model.LastExport(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找导出任务错误", err)
+	}
+	if last != nil && time.Since(time.Unix(last.Created, 0)) < model.ExportInterval {
+		return serializer.TooManyRequestsErr("每天只能导出一次数据")
+	}
+
+	export := &model.DataExport{UserID: user.ID, Status: model.ExportRunning}
+	if err := orm.DB().Create(export).Error; err != nil {
+		return serializer.DBErr("创建导出任务失败", err)
+	}
+	go buildExport(export, user)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "导出任务已创建，完成后可在导出列表中下载",
+		Data: export,
+	}
+}
+
+// Exports 查看当前用户的导出任务
+func (s *NoParamsService) Exports(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	exports, err := model.ListExports(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找导出任务错误", err)
+	}
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: exports,
+	}
+}
+
+// ExportFile 返回可下载的导出文件路径，不可下载时返回错误响应
+func (s *ExportService) ExportFile(c *gin.Context) (string, *serializer.Response) {
+	user := c.MustGet("user").(*model.User)
+	export := &model.DataExport{}
+	err := orm.DB().Where("id = ? AND user_id = ?", s.ID, user.ID).First(export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", serializer.ParamErr("导出任务不存在", nil)
+	} else if err != nil {
+		return "", serializer.DBErr("查找导出任务错误", err)
+	}
+	if export.Status != model.ExportDone || export.ExpiresAt <= time.Now().Unix() {
+		return "", serializer.ParamErr("导出文件尚未生成或已过期", nil)
+	}
+	return filepath.Join(exportDir(), export.File), nil
+}
+
+// buildExport 生成导出包，失败时标记任务失败，任务已被删除时删除生成的文件
+func buildExport(export *model.DataExport, user *model.User) {
+	name, size, err := safeWriteExport(export, user)
+	updates := map[string]interface{}{"finished": time.Now().Unix()}
+	if err != nil {
+		logger.Logger().Warn("[Export] build export err", zap.Int64("user_id", user.ID), zap.Error(err))
+		updates["status"] = model.ExportFailed
+	} else {
+		updates["status"] = model.ExportDone
+		updates["file"] = name
+		updates["size"] = size
+		updates["expires_at"] = time.Now().Add(model.ExportExpire).Unix()
+	}
+	rdb := orm.DB().Model(export).Updates(updates)
+	if rdb.Error != nil {
+		logger.Logger().Warn("[Export] update export err", zap.Int64("export_id", export.ID), zap.Error(rdb.Error))
+	} else if rdb.RowsAffected == 0 && name != "" {
+		// 生成期间用户已被清除，任务记录随之删除，文件不再有记录指向
+		removeExportFile(name)
+	}
+}
+
+// safeWriteExport 生成过程中panic时按失败处理，避免后台任务导致进程退出
+func safeWriteExport(export *model.DataExport, user *model.User) (name string, size int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("export panic: %v", r)
+		}
+	}()
+	return writeExport(export, user)
+}
+
+// writeExport 将用户数据写入zip，文件名带随机串，返回文件名与大小
+func writeExport(export *model.DataExport, user *model.User) (string, int64, error) {
+	random, err := oauth.RandomString()
+	if err != nil {
+		return "", 0, err
+	}
+	name := strconv.FormatInt(export.ID, 10) + "_" + random[:16] + ".zip"
+	if err := os.MkdirAll(exportDir(), 0700); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(exportDir(), name)
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(path + ".tmp")
+
+	zw := zip.NewWriter(f)
+	err = writeExportFiles(zw, user)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return name, info.Size(), nil
+}
+
+func writeExportFiles(zw *zip.Writer, user *model.User) error {
+	w, err := zw.Create("README.txt")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(exportReadme)); err != nil {
+		return err
+	}
+
+	// 重新读取，避免导出申请之后修改的资料缺失
+	current, err := model.GetUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "profile.json", serializer.BuildUsersResponse([]*model.User{current})[0]); err != nil {
+		return err
+	}
+	sessions, err := model.Sessions().ListUserSessions(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "sessions.json", serializer.BuildSessionsResponse(sessions, "").Data); err != nil {
+		return err
+	}
+
+	for _, section := range exportSections {
+		rows := make([]map[string]interface{}, 0)
+		args := map[string]interface{}{"uid": user.ID, "sid": strconv.FormatInt(user.ID, 10)}
+		if err := orm.DB().Model(section.model).Select(section.columns).Where(section.where, args).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if err := writeJSON(zw, section.name, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// cleanExports 删除过期的导出文件，并将生成超时的任务标记为失败
+func cleanExports(now int64) {
+	exports, err := model.ExpiredExports(now)
+	if err != nil {
+		logger.Logger().Warn("[Export] find expired exports err", zap.Error(err))
+		return
+	}
+	for _, export := range exports {
+		if export.File != "" {
+			removeExportFile(export.File)
+		}
+		if export.Status == model.ExportRunning {
+			err = orm.DB().Model(export).Updates(map[string]interface{}{"status": model.ExportFailed, "finished": now}).Error
+		} else {
+			err = orm.DB().Delete(export).Error
+		}
+		if err != nil {
+			logger.Logger().Warn("[Export] clean export err", zap.Int64("export_id", export.ID), zap.Error(err))
+		}
+	}
+}
+
+func removeExportFile(name string) {
+	if err := os.Remove(filepath.Join(exportDir(), filepath.Base(name))); err != nil && !os.IsNotExist(err) {
+		logger.Logger().Warn("[Export] remove export file err", zap.String("file", name), zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/pkg/handle"
+)
+
+// UserNameService 按用户名操作的服务
+type UserNameService struct {
+	UserName string `form:"username" json:"username" binding:"required"`
+}
+
+// checkHandle 检查用户名是否合法且可用，userID为0表示注册新用户
+func checkHandle(name string, userID int64) *serializer.Response {
+	switch err := handle.Validate(name); {
+	case errors.Is(err, handle.ErrFormat):
+		return serializer.ParamErr(fmt.Sprintf("用户名须为%d-%d位字母、数字或下划线", handle.MinLength, handle.MaxLength), nil)
+	case errors.Is(err, handle.ErrReserved):
+		return serializer.ParamErr("该用户名为系统保留", nil)
+	case errors.Is(err, handle.ErrProfane):
+		return serializer.ParamErr("用户名包含不允许使用的词", nil)
+	case err != nil:
+		return serializer.ParamErr("用户名不合法", err)
+	}
+	ok, err := model.HandleAvailable(name, userID)
+	if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if !ok {
+		return serializer.ParamErr("用户名已被使用", nil)
+	}
+	return nil
+}
+
+// CheckUserName 检查用户名是否可以注册或改用
+func (s *UserNameService) CheckUserName(c *gin.Context) *serializer.Response {
+	var userID int64
+	if user, ok := c.Get("user"); ok {
+		userID = user.(*model.User).ID
+	}
+	if res := checkHandle(s.UserName, userID); res != nil {
+		return res
+	}
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "用户名可用",
+	}
+}
+
+// ChangeUserName 修改当前用户的用户名，旧用户名在保留期内重定向到新用户名
+func (s *UserNameService) ChangeUserName(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if s.UserName == user.UserName {
+		return serializer.ParamErr("新用户名与当前用户名相同", nil)
+	}
+
+	last, err := model.LastRename(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找改名记录错误", err)
+	}
+	if next := time.Unix(last, 0).Add(model.HandleCooldown); last > 0 && time.Now().Before(next) {
+		return serializer.ParamErr("改名过于频繁，请于"+next.Format("2006-01-02 15:04")+"后再试", nil)
+	}
+	if res := checkHandle(s.UserName, user.ID); res != nil {
+		return res
+	}
+
+	if err := user.Rename(s.UserName); err != nil {
+		return serializer.DBErr("修改用户名失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditUserRename,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, map[string]interface{}{"username": user.UserName}, map[string]interface{}{"username": s.UserName})
+	user.UserName = s.UserName
+	return serializer.BuildUserResponse(user)
+}
+
+// HandleHistory 查看当前用户的改名记录
+func (s *NoParamsService) HandleHistory(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	histories, err := model.ListHandleHistory(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找改名记录错误", err)
+	}
+	return serializer.BuildHandleHistoryResponse(histories)
+}
@@ -0,0 +1,250 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/captcha"
+	"github.com/vidorg/vid_backend/pkg/lockout"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/redis"
+	"go.uber.org/zap"
+)
+
+// UnlockAccountService 通过邮件解除登录锁定的服务
+type UnlockAccountService struct {
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+var (
+	// 同一账号连续失败5次锁定1分钟，此后每次失败翻倍，最长1小时
+	accountPolicy = &lockout.Policy{Threshold: 5, Base: time.Minute, Max: time.Hour, Window: 24 * time.Hour}
+	// 同一IP尝试多个账号时的限制，阈值更高以容忍NAT后的多个用户
+	ipPolicy = &lockout.Policy{Threshold: 20, Base: time.Minute, Max: time.Hour, Window: time.Hour}
+
+	// 锁定之前，账号或IP的失败次数达到以下阈值后登录需要人机验证
+	captchaAccountFails int64 = 2
+	captchaIPFails      int64 = 10
+
+	accountLimiter *loginLimiter
+	ipLimiter      *loginLimiter
+	limiterOnce    sync.Once
+)
+
+// loginLimiter 登录失败限制器，存储出错时改用进程内计数，避免故障期间不限制
+type loginLimiter struct {
+	limiter  *lockout.Limiter
+	fallback *lockout.Limiter // redis出错时使用的进程内计数
+}
+
+func newLoginLimiter(prefix string, policy *lockout.Policy, store lockout.Store) *loginLimiter {
+	return &loginLimiter{
+		limiter:  lockout.New(prefix, policy, store),
+		fallback: lockout.New(prefix, policy, lockout.NewMemoryStore()),
+	}
+}
+
+// Check 返回key剩余的锁定时间
+func (l *loginLimiter) Check(key string) (time.Duration, error) {
+	retryAfter, err := l.limiter.Check(key)
+	if err != nil {
+		logger.Logger().Warn("[Lockout] check err", zap.Error(err))
+		return l.fallback.Check(key)
+	}
+	return retryAfter, nil
+}
+
+// Count 返回key近期的失败次数
+func (l *loginLimiter) Count(key string) (int64, error) {
+	count, err := l.limiter.Count(key)
+	if err != nil {
+		logger.Logger().Warn("[Lockout] count err", zap.Error(err))
+		return l.fallback.Count(key)
+	}
+	return count, nil
+}
+
+// Fail 记录一次失败，返回锁定时间与是否因本次失败而锁定
+func (l *loginLimiter) Fail(key string) (time.Duration, bool, error) {
+	retryAfter, locked, err := l.limiter.Fail(key)
+	if err != nil {
+		logger.Logger().Warn("[Lockout] record failure err", zap.Error(err))
+		return l.fallback.Fail(key)
+	}
+	return retryAfter, locked, nil
+}
+
+// Reset 清除key的记录，进程内计数同时清除
+func (l *loginLimiter) Reset(key string) error {
+	_ = l.fallback.Reset(key)
+	return l.limiter.Reset(key)
+}
+
+// limiters 登录失败限制器，redis可用时多实例共享计数，否则使用内存
+func limiters() (*loginLimiter, *loginLimiter) {
+	limiterOnce.Do(func() {
+		var store lockout.Store = lockout.NewMemoryStore()
+		if redis.Enabled() {
+			store = &lockout.RedisStore{}
+		}
+		accountLimiter = newLoginLimiter("vid:login_fail:user:", accountPolicy, store)
+		ipLimiter = newLoginLimiter("vid:login_fail:ip:", ipPolicy, store)
+	})
+	return accountLimiter, ipLimiter
+}
+
+func accountKey(username string) string {
+	return strings.ToLower(username)
+}
+
+func lockedErr(retryAfter time.Duration) *serializer.Response {
+	minutes := int((retryAfter + time.Minute - 1) / time.Minute)
+	return serializer.TooManyRequestsErr(fmt.Sprintf("登录失败次数过多，请%d分钟后再试", minutes))
+}
+
+// checkLockout 账号或IP处于锁定期时返回错误
+func checkLockout(c *gin.Context, username string) *serializer.Response {
+	account, ip := limiters()
+	for _, check := range []struct {
+		limiter *loginLimiter
+		key     string
+	}{{account, accountKey(username)}, {ip, c.ClientIP()}} {
+		retryAfter, err := check.limiter.Check(check.key)
+		if err != nil {
+			logger.Logger().Warn("[Lockout] check err", zap.Error(err))
+			continue
+		}
+		if retryAfter > 0 {
+			return lockedErr(retryAfter)
+		}
+	}
+	return nil
+}
+
+// captchaRequired 账号或IP近期的失败次数达到阈值时需要人机验证
+func captchaRequired(c *gin.Context, username string) bool {
+	account, ip := limiters()
+	if count, err := account.Count(accountKey(username)); err == nil && count >= captchaAccountFails {
+		return true
+	}
+	count, err := ip.Count(c.ClientIP())
+	return err == nil && count >= captchaIPFails
+}
+
+// checkCaptcha 需要人机验证时校验答案
+func checkCaptcha(c *gin.Context, username, id, answer string) *serializer.Response {
+	if !captchaRequired(c, username) {
+		return nil
+	}
+	return captcha.Verify(captcha.SceneLogin, id, answer)
+}
+
+// loginFailed 记录一次登录失败，user为nil表示用户名不存在，同样计数以免泄露用户是否存在
+// 账号首次被锁定时记录审计日志并发送解锁邮件
+func loginFailed(c *gin.Context, username string, user *model.User, msg string) *serializer.Response {
+	account, ip := limiters()
+	res := serializer.ParamErr(msg, nil)
+
+	retryAfter, locked, err := account.Fail(accountKey(username))
+	if err == nil && retryAfter > 0 {
+		res = lockedErr(retryAfter)
+		if locked && user != nil {
+			audit.Record(c, &model.AuditLog{
+				Action:     model.AuditAccountLocked,
+				TargetType: "user",
+				TargetID:   fmt.Sprint(user.ID),
+				Detail:     fmt.Sprintf("locked for %s after %d failed attempts", retryAfter, accountPolicy.Threshold),
+			}, nil, nil)
+			if err := sendTokenEmail(user, model.TokenUnlockAccount, "你的 Vid 账号已被临时锁定", "/unlock-account"); err != nil {
+				logger.Logger().Warn("[Mail] unlock email not sent", zap.Int64("user_id", user.ID), zap.Error(err))
+			}
+		}
+	}
+
+	retryAfter, locked, err = ip.Fail(c.ClientIP())
+	if err == nil && retryAfter > 0 {
+		res = lockedErr(retryAfter)
+		if locked {
+			audit.Record(c, &model.AuditLog{
+				Action:     model.AuditIPLocked,
+				TargetType: "ip",
+				TargetID:   c.ClientIP(),
+				Detail:     fmt.Sprintf("locked for %s after %d failed attempts", retryAfter, ipPolicy.Threshold),
+			}, nil, nil)
+		}
+	}
+	// 未锁定但下次登录需要人机验证时提示客户端
+	if res.Code == serializer.CodeParamError && captchaRequired(c, username) {
+		res = serializer.CaptchaErr(msg + "，请完成人机验证后重试")
+	}
+	return res
+}
+
+// loginSucceeded 登录成功后清除账号的失败记录，IP的记录保留
+func loginSucceeded(username string) {
+	account, _ := limiters()
+	if err := account.Reset(accountKey(username)); err != nil {
+		logger.Logger().Warn("[Lockout] reset err", zap.Error(err))
+	}
+}
+
+// checkStatus 被封禁或未激活的用户不能登录
+func checkStatus(user *model.User) *serializer.Response {
+	if user.IsActive() {
+		return nil
+	}
+	switch user.Status {
+	case model.UserInactive:
+		return serializer.UserStatusErr("账号未激活，请先验证邮箱")
+	case model.UserSuspend:
+		msg := "账号已被封禁"
+		if user.SuspendReason != "" {
+			msg += "，原因：" + user.SuspendReason
+		}
+		if user.SuspendUntil > 0 {
+			msg += "，解封时间：" + time.Unix(user.SuspendUntil, 0).Format("2006-01-02 15:04")
+		}
+		return serializer.UserStatusErr(msg)
+	case model.UserDeleted:
+		return serializer.UserStatusErr("账号已注销")
+	}
+	return serializer.UserStatusErr("账号状态异常")
+}
+
+// Unlock 使用邮件中的令牌解除账号的登录锁定
+func (s *UnlockAccountService) Unlock(c *gin.Context) *serializer.Response {
+	token, err := model.UseUserToken(orm.DB(), s.Token, model.TokenUnlockAccount)
+	if errors.Is(err, model.ErrUserTokenInvalid) {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("解锁失败", err)
+	}
+	user := &model.User{}
+	if err := orm.DB().First(user, token.UserID).Error; err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+
+	account, _ := limiters()
+	if err := account.Reset(accountKey(user.UserName)); err != nil {
+		return serializer.ServerErr("解锁失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    user.ID,
+		Action:     model.AuditAccountUnlocked,
+		TargetType: "user",
+		TargetID:   fmt.Sprint(user.ID),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "解锁成功",
+	}
+}
@@ -0,0 +1,343 @@
+package user
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/keyword"
+	"github.com/vidorg/vid_backend/pkg/filter"
+	"github.com/vidorg/vid_backend/pkg/handle"
+	"github.com/vidorg/vid_backend/pkg/oauth"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"gorm.io/gorm"
+)
+
+const oauthStateExpire = 10 * time.Minute // 用户在提供方授权页停留的最长时间
+
+// OAuthStartService 跳转到提供方授权页的服务
+type OAuthStartService struct {
+	Provider string `form:"provider" json:"provider" binding:"required"`
+}
+
+// OAuthCallbackService 提供方回调的服务
+type OAuthCallbackService struct {
+	Provider string `form:"provider" json:"provider" binding:"required"`
+	Code     string `form:"code" json:"code" binding:"required"`
+	State    string `form:"state" json:"state" binding:"required"`
+}
+
+var usernameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// authorize 保存state并生成授权页地址，userID不为0时回调后绑定到该用户
+func (s *OAuthStartService) authorize(userID int64) *serializer.Response {
+	provider, err := oauth.Get(s.Provider)
+	if err != nil {
+		return serializer.ParamErr("不支持的登录方式", nil)
+	}
+
+	state := &model.OAuthState{Provider: s.Provider, UserID: userID}
+	for _, v := range []*string{&state.ID, &state.Verifier, &state.Nonce} {
+		if *v, err = oauth.RandomString(); err != nil {
+			return serializer.ServerErr("生成state失败", err)
+		}
+	}
+	if err := model.SaveOAuthState(state, oauthStateExpire); err != nil {
+		return serializer.DBErr("保存state失败", err)
+	}
+	return serializer.BuildOAuthRedirectResponse(provider.AuthCodeURL(state.ID, oauth.S256Challenge(state.Verifier), state.Nonce))
+}
+
+// Login 使用外部身份登录
+func (s *OAuthStartService) Login() *serializer.Response {
+	return s.authorize(0)
+}
+
+// Link 为当前用户绑定外部身份
+func (s *OAuthStartService) Link(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	return s.authorize(user.ID)
+}
+
+// Callback 校验state并换取外部身份，按state完成登录或绑定
+func (s *OAuthCallbackService) Callback(c *gin.Context) *serializer.Response {
+	provider, err := oauth.Get(s.Provider)
+	if err != nil {
+		return serializer.ParamErr("不支持的登录方式", nil)
+	}
+	state, err := model.TakeOAuthState(s.State, s.Provider)
+	if errors.Is(err, model.ErrOAuthStateNotFound) {
+		return serializer.ParamErr("授权已过期，请重新登录", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找state错误", err)
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), s.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		return serializer.ParamErr("第三方授权失败", err)
+	}
+
+	if state.UserID != 0 {
+		// 绑定的回调必须由发起绑定的用户完成，防止把他人的外部账号诱导绑定到攻击者账号上
+		if c.GetInt64("user_id") != state.UserID {
+			return serializer.NoRightErr()
+		}
+		return link(c, state.UserID, identity)
+	}
+	return oauthLogin(c, identity)
+}
+
+// link 绑定外部身份，同一外部身份只能绑定一个用户
+func link(c *gin.Context, userID int64, identity *oauth.Identity) *serializer.Response {
+	exist := &model.UserIdentity{}
+	err := orm.DB().Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(exist).Error
+	if err == nil {
+		if exist.UserID == userID {
+			return &serializer.Response{Code: 200, Msg: "绑定成功", Data: exist}
+		}
+		return serializer.ParamErr("该账号已绑定其他用户", nil)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.DBErr("查找绑定错误", err)
+	}
+
+	var count int64
+	if err := orm.DB().Model(&model.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, identity.Provider).Count(&count).Error; err != nil {
+		return serializer.DBErr("查找绑定错误", err)
+	}
+	if count > 0 {
+		return serializer.ParamErr("已绑定该登录方式，请先解绑", nil)
+	}
+
+	row := newIdentity(userID, identity)
+	if err := orm.DB().Create(row).Error; err != nil {
+		return serializer.DBErr("绑定失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    userID,
+		Action:     model.AuditIdentityLink,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(userID, 10),
+		Detail:     identity.Provider,
+	}, nil, nil)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "绑定成功",
+		Data: row,
+	}
+}
+
+// oauthLogin 已绑定时登录，未绑定时自动注册新用户
+func oauthLogin(c *gin.Context, identity *oauth.Identity) *serializer.Response {
+	row := &model.UserIdentity{}
+	err := orm.DB().Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return oauthRegister(c, identity)
+	} else if err != nil {
+		return serializer.DBErr("查找绑定错误", err)
+	}
+
+	user, err := model.GetUser(row.UserID)
+	if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if res := checkStatus(user); res != nil {
+		return res
+	}
+	// 外部登录不能绕过两步验证
+	if user.TOTPEnabled {
+		return challenge(user)
+	}
+	token, err := issueSession(c, user.ID)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	return serializer.BuildLoginResponse(user, token)
+}
+
+// oauthRegister 使用外部身份自动注册
+// 邮箱已被其他用户使用时不自动绑定，避免通过外部账号接管已有用户
+func oauthRegister(c *gin.Context, identity *oauth.Identity) *serializer.Response {
+	// 提供方未返回已验证的邮箱时不保存邮箱，空字符串会占用唯一索引
+	var email *string
+	if identity.Email != "" && identity.EmailVerified {
+		email = &identity.Email
+		var count int64
+		orm.DB().Model(&model.User{}).Where("email = ?", identity.Email).Count(&count)
+		if count > 0 {
+			return serializer.ParamErr("该邮箱已注册，请登录后在账号设置中绑定", nil)
+		}
+	}
+
+	username, err := availableUsername(identity)
+	if err != nil {
+		return serializer.DBErr("生成用户名失败", err)
+	}
+	nickname := []rune(identity.Name)
+	if len(nickname) == 0 {
+		nickname = []rune(username)
+	}
+	if len(nickname) > 15 {
+		nickname = nickname[:15]
+	}
+	// 外部昵称命中拒绝规则时改用用户名
+	checked, res := keyword.Check("昵称", string(nickname))
+	if res != nil {
+		checked = &filter.Result{Text: username}
+	}
+	user := &model.User{
+		UserName: username,
+		Nickname: checked.Text,
+		Status:   model.UserActive,
+		Email:    email,
+		Role:     model.RoleNormal,
+	}
+	if identity.Avatar != "" && len(identity.Avatar) <= 1000 {
+		user.Avatar = identity.Avatar
+	}
+	// 随机密码，用户需要时可通过邮箱重置
+	password, err := oauth.RandomString()
+	if err != nil {
+		return serializer.EncryptErr("密码生成失败", err)
+	}
+	if err := user.SetPassword(password); err != nil {
+		return serializer.EncryptErr("密码加密失败", err)
+	}
+
+	err = orm.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(newIdentity(user.ID, identity)).Error
+	})
+	if err != nil {
+		return serializer.DBErr("注册失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    user.ID,
+		Action:     model.AuditUserRegister,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+		Detail:     identity.Provider,
+	}, nil, user)
+	keyword.Flag(model.ReportUser, user.ID, user.ID, map[string]*filter.Result{"昵称": checked})
+
+	token, err := issueSession(c, user.ID)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	return serializer.BuildLoginResponse(user, token)
+}
+
+// availableUsername 由外部用户名或邮箱生成未被占用的用户名
+func availableUsername(identity *oauth.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = usernameInvalid.ReplaceAllString(base, "")
+	if len(base) > 8 {
+		base = base[:8]
+	}
+	if len(base) < 3 {
+		base = "user" + base
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		if handle.Validate(candidate) == nil {
+			ok, err := model.HandleAvailable(candidate, 0)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return candidate, nil
+			}
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, n.Int64())
+	}
+	return "", errors.New("no available username")
+}
+
+func newIdentity(userID int64, identity *oauth.Identity) *model.UserIdentity {
+	return &model.UserIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Username: identity.Username,
+		Email:    identity.Email,
+	}
+}
+
+// Identities 列出当前用户绑定的外部身份
+func (s *NoParamsService) Identities(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	identities := make([]*model.UserIdentity, 0)
+	if err := orm.DB().Where("user_id = ?", user.ID).Order("id").Find(&identities).Error; err != nil {
+		return serializer.DBErr("查找绑定错误", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: identities,
+	}
+}
+
+// Unlink 解绑外部身份，没有邮箱找回密码、其他外部身份或通行密钥时拒绝，避免账号无法登录
+func (s *OAuthStartService) Unlink(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+
+	if user.Email == nil || *user.Email == "" {
+		var identities, passkeys int64
+		if err := orm.DB().Model(&model.UserIdentity{}).Where("user_id = ? AND provider <> ?", user.ID, s.Provider).Count(&identities).Error; err != nil {
+			return serializer.DBErr("查找绑定错误", err)
+		}
+		if err := orm.DB().Model(&model.Passkey{}).Where("user_id = ?", user.ID).Count(&passkeys).Error; err != nil {
+			return serializer.DBErr("查找凭证错误", err)
+		}
+		if identities == 0 && passkeys == 0 {
+			return serializer.ParamErr("请先绑定邮箱或其他登录方式", nil)
+		}
+	}
+
+	rdb := orm.DB().Where("user_id = ? AND provider = ?", user.ID, s.Provider).Delete(&model.UserIdentity{})
+	if rdb.Error != nil {
+		return serializer.DBErr("解绑失败", rdb.Error)
+	}
+	if rdb.RowsAffected == 0 {
+		return serializer.ParamErr("未绑定该登录方式", nil)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditIdentityUnlink,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+		Detail:     s.Provider,
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "解绑成功",
+	}
+}
+
+// OAuthProviders 列出可用的外部登录方式
+func (s *NoParamsService) OAuthProviders() *serializer.Response {
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: oauth.Names(),
+	}
+}
@@ -0,0 +1,320 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/conf"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/webauthn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	ceremonyExpire = 5 * time.Minute // 注册与登录挑战的有效期
+	maxPasskeys    = 10              // 每个用户最多注册的凭证数
+)
+
+// PasskeyRegisterService 完成凭证注册的服务
+type PasskeyRegisterService struct {
+	CeremonyID string                        `json:"ceremony_id" binding:"required"`
+	Name       string                        `json:"name" binding:"max=50"`
+	Credential *webauthn.AttestationResponse `json:"credential" binding:"required"`
+}
+
+// PasskeyLoginBeginService 开始凭证登录的服务，不填用户名时使用可发现凭证
+type PasskeyLoginBeginService struct {
+	UserName string `form:"username" json:"username" binding:"omitempty,min=3,max=20"`
+}
+
+// PasskeyLoginService 完成凭证登录的服务
+type PasskeyLoginService struct {
+	CeremonyID string                      `json:"ceremony_id" binding:"required"`
+	Credential *webauthn.AssertionResponse `json:"credential" binding:"required"`
+}
+
+// DeletePasskeyService 删除凭证的服务
+type DeletePasskeyService struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
+
+// relyingParty WebAuthn依赖方配置，未配置时返回nil
+func relyingParty() *webauthn.Config {
+	c := conf.Config().WebAuthn
+	if c == nil || c.RPID == "" || len(c.Origins) == 0 {
+		return nil
+	}
+	return &webauthn.Config{
+		RPID:        c.RPID,
+		RPName:      c.RPName,
+		Origins:     c.Origins,
+		Attestation: c.Attestation,
+		Timeout:     int(ceremonyExpire / time.Millisecond),
+	}
+}
+
+// userHandle WebAuthn中的用户句柄，使用不含个人信息的用户ID
+func userHandle(userID int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(userID))
+	return b
+}
+
+// credentialIDs 用户已注册的凭证ID
+func credentialIDs(userID int64) ([][]byte, error) {
+	var ids []string
+	if err := orm.DB().Model(&model.Passkey{}).Where("user_id = ?", userID).Pluck("credential_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	res := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		if b, err := base64.RawURLEncoding.DecodeString(id); err == nil {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+
+// newCeremony 生成并保存挑战
+func newCeremony(typ string, userID int64) (*model.PasskeyCeremony, error) {
+	id, err := model.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	ceremony := &model.PasskeyCeremony{ID: id, Type: typ, UserID: userID, Challenge: challenge}
+	return ceremony, model.SaveCeremony(ceremony, ceremonyExpire)
+}
+
+// BeginPasskeyRegistration 生成注册凭证所需的参数
+func (s *NoParamsService) BeginPasskeyRegistration(c *gin.Context) *serializer.Response {
+	rp := relyingParty()
+	if rp == nil {
+		return serializer.ServerErr("未配置WebAuthn", nil)
+	}
+	user := c.MustGet("user").(*model.User)
+
+	exclude, err := credentialIDs(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找凭证错误", err)
+	}
+	if len(exclude) >= maxPasskeys {
+		return serializer.ParamErr("凭证数量已达上限", nil)
+	}
+	ceremony, err := newCeremony(model.CeremonyRegister, user.ID)
+	if err != nil {
+		return serializer.ServerErr("生成挑战失败", err)
+	}
+
+	options := rp.NewCreationOptions(ceremony.Challenge, userHandle(user.ID), user.UserName, user.Nickname, exclude)
+	return serializer.BuildPasskeyOptionsResponse(ceremony.ID, options)
+}
+
+// Register 校验认证器的注册响应并保存凭证
+func (s *PasskeyRegisterService) Register(c *gin.Context) *serializer.Response {
+	rp := relyingParty()
+	if rp == nil {
+		return serializer.ServerErr("未配置WebAuthn", nil)
+	}
+	user := c.MustGet("user").(*model.User)
+
+	ceremony, err := model.TakeCeremony(s.CeremonyID, model.CeremonyRegister)
+	if errors.Is(err, model.ErrCeremonyNotFound) || (err == nil && ceremony.UserID != user.ID) {
+		return serializer.ParamErr("挑战无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找挑战错误", err)
+	}
+
+	cred, err := rp.VerifyRegistration(ceremony.Challenge, s.Credential)
+	if err != nil {
+		return serializer.ParamErr("凭证校验失败", err)
+	}
+
+	name := s.Name
+	if name == "" {
+		name = "Passkey " + time.Now().Format("2006-01-02")
+	}
+	passkey := &model.Passkey{
+		UserID:       user.ID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(cred.ID),
+		PublicKey:    cred.PublicKey,
+		Alg:          cred.Alg,
+		SignCount:    cred.SignCount,
+		AAGUID:       hex.EncodeToString(cred.AAGUID),
+		Attestation:  cred.Attestation,
+		Name:         name,
+	}
+	var count int64
+	if err := orm.DB().Model(&model.Passkey{}).Where("credential_id = ?", passkey.CredentialID).Count(&count).Error; err != nil {
+		return serializer.DBErr("查找凭证错误", err)
+	}
+	if count > 0 {
+		return serializer.ParamErr("该凭证已注册", nil)
+	}
+	if err := orm.DB().Create(passkey).Error; err != nil {
+		return serializer.DBErr("保存凭证失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditPasskeyCreate,
+		TargetType: "passkey",
+		TargetID:   strconv.FormatInt(passkey.ID, 10),
+	}, nil, passkey)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "注册成功",
+		Data: passkey,
+	}
+}
+
+// Passkeys 列出当前用户的凭证
+func (s *NoParamsService) Passkeys(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	passkeys := make([]*model.Passkey, 0)
+	if err := orm.DB().Where("user_id = ?", user.ID).Order("id").Find(&passkeys).Error; err != nil {
+		return serializer.DBErr("查找凭证错误", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: passkeys,
+	}
+}
+
+// Delete 删除当前用户的凭证
+func (s *DeletePasskeyService) Delete(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	rdb := orm.DB().Where("id = ? AND user_id = ?", s.ID, user.ID).Delete(&model.Passkey{})
+	if rdb.Error != nil {
+		return serializer.DBErr("删除凭证失败", rdb.Error)
+	}
+	if rdb.RowsAffected == 0 {
+		return serializer.ParamErr("凭证不存在", nil)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditPasskeyDelete,
+		TargetType: "passkey",
+		TargetID:   strconv.FormatInt(s.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "删除成功",
+	}
+}
+
+// Begin 生成登录参数，指定用户名时只允许该用户的凭证
+func (s *PasskeyLoginBeginService) Begin() *serializer.Response {
+	rp := relyingParty()
+	if rp == nil {
+		return serializer.ServerErr("未配置WebAuthn", nil)
+	}
+
+	var userID int64
+	var allow [][]byte
+	if s.UserName != "" {
+		user := &model.User{}
+		err := orm.DB().Where("username = ?", s.UserName).First(user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return serializer.DBErr("查找用户错误", err)
+		}
+		// 用户不存在时按无凭证处理，不暴露用户名是否注册
+		if err == nil {
+			userID = user.ID
+			if allow, err = credentialIDs(user.ID); err != nil {
+				return serializer.DBErr("查找凭证错误", err)
+			}
+		}
+	}
+
+	ceremony, err := newCeremony(model.CeremonyLogin, userID)
+	if err != nil {
+		return serializer.ServerErr("生成挑战失败", err)
+	}
+	return serializer.BuildPasskeyOptionsResponse(ceremony.ID, rp.NewRequestOptions(ceremony.Challenge, allow))
+}
+
+// Login 校验认证器的登录响应并签发令牌
+// 凭证登录要求认证器完成用户验证，本身即为多因素，不再要求TOTP
+func (s *PasskeyLoginService) Login(c *gin.Context) *serializer.Response {
+	rp := relyingParty()
+	if rp == nil {
+		return serializer.ServerErr("未配置WebAuthn", nil)
+	}
+	ceremony, err := model.TakeCeremony(s.CeremonyID, model.CeremonyLogin)
+	if errors.Is(err, model.ErrCeremonyNotFound) {
+		return serializer.ParamErr("挑战无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找挑战错误", err)
+	}
+
+	if len(s.Credential.RawID) == 0 {
+		s.Credential.RawID, _ = base64.RawURLEncoding.DecodeString(s.Credential.ID)
+	}
+	passkey := &model.Passkey{}
+	err = orm.DB().Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(s.Credential.RawID)).First(passkey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("凭证不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找凭证错误", err)
+	}
+	if ceremony.UserID != 0 && ceremony.UserID != passkey.UserID {
+		return serializer.ParamErr("凭证不存在", nil)
+	}
+	if handle := s.Credential.Response.UserHandle; len(handle) > 0 && string(handle) != string(userHandle(passkey.UserID)) {
+		return serializer.ParamErr("凭证不存在", nil)
+	}
+
+	cred := &webauthn.Credential{
+		ID:        s.Credential.RawID,
+		PublicKey: passkey.PublicKey,
+		Alg:       passkey.Alg,
+		SignCount: passkey.SignCount,
+	}
+	count, err := rp.VerifyAssertion(ceremony.Challenge, cred, s.Credential)
+	if errors.Is(err, webauthn.ErrSignCount) {
+		logger.Logger().Warn("[Passkey] sign count regressed, credential may be cloned",
+			zap.Int64("user_id", passkey.UserID), zap.Int64("passkey_id", passkey.ID))
+		return serializer.ParamErr("凭证校验失败", err)
+	} else if err != nil {
+		return serializer.ParamErr("凭证校验失败", err)
+	}
+
+	// 并发登录时只有一方能更新计数，不支持计数的认证器始终为0
+	rdb := orm.DB().Model(&model.Passkey{}).
+		Where("id = ? AND sign_count = ?", passkey.ID, passkey.SignCount).
+		Updates(map[string]interface{}{"sign_count": count, "last_used": time.Now().Unix()})
+	if rdb.Error != nil {
+		return serializer.DBErr("更新凭证失败", rdb.Error)
+	}
+	if rdb.RowsAffected == 0 && count != 0 {
+		return serializer.ParamErr("凭证校验失败", nil)
+	}
+
+	user, err := model.GetUser(passkey.UserID)
+	if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if res := checkStatus(user); res != nil {
+		return res
+	}
+	token, err := issueSession(c, user.ID)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	return serializer.BuildLoginResponse(user, token)
+}
@@ -0,0 +1,303 @@
+package user
+
+import (
+	"errors"
+	"image"
+	"mime/multipart"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/keyword"
+	"github.com/vidorg/vid_backend/pkg/filter"
+	"github.com/vidorg/vid_backend/pkg/mail"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/upload"
+	"gorm.io/gorm"
+)
+
+const avatarSize = 256 // 头像边长，单位像素
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{5,19}$`)
+
+// UpdateProfileService 修改个人资料的服务，只更新传入的字段
+type UpdateProfileService struct {
+	Nickname *string `form:"nickname" json:"nickname" binding:"omitempty,min=3,max=15"`
+	Bio      *string `form:"bio" json:"bio" binding:"omitempty,max=255"`
+	Gender   *int8   `form:"gender" json:"gender" binding:"omitempty,oneof=0 1 2"`
+	Birthday *string `form:"birthday" json:"birthday"` // YYYY-MM-DD，空字符串表示清除
+	Phone    *string `form:"phone" json:"phone" binding:"omitempty,max=20"`
+}
+
+// UpdateEmailService 修改邮箱的服务
+type UpdateEmailService struct {
+	Email    string `form:"email" json:"email" binding:"required,email,max=255"`
+	Password string `form:"password" json:"password" binding:"required,max=72"` // 当前密码
+}
+
+// UploadAvatarService 上传头像的服务，可指定正方形裁剪区域，不指定时居中裁剪
+type UploadAvatarService struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+	X    int                   `form:"x" binding:"min=0"`
+	Y    int                   `form:"y" binding:"min=0"`
+	Size int                   `form:"size" binding:"min=0"` // 裁剪区域边长，0表示不指定
+}
+
+// GetProfileService 查看用户公开资料的服务，按id或用户名查找
+type GetProfileService struct {
+	ID       int64  `form:"id" json:"id"`
+	UserName string `form:"username" json:"username"`
+}
+
+// UpdateProfile 修改当前用户的个人资料
+func (s *UpdateProfileService) UpdateProfile(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	updates := map[string]interface{}{}
+	checked := map[string]*filter.Result{}
+
+	if s.Nickname != nil {
+		nickname, res := keyword.Check("昵称", *s.Nickname)
+		if res != nil {
+			return res
+		}
+		checked["昵称"] = nickname
+		updates["nickname"] = nickname.Text
+	}
+	if s.Bio != nil {
+		bio, res := keyword.Check("个人简介", *s.Bio)
+		if res != nil {
+			return res
+		}
+		checked["个人简介"] = bio
+		updates["bio"] = bio.Text
+	}
+	if s.Gender != nil {
+		updates["gender"] = *s.Gender
+	}
+	if s.Birthday != nil {
+		if *s.Birthday == "" {
+			updates["birthday"] = nil
+		} else {
+			birthday, err := time.ParseInLocation(model.BirthdayLayout, *s.Birthday, time.Local)
+			if err != nil || birthday.After(time.Now()) || birthday.Year() < 1900 {
+				return serializer.ParamErr("生日格式错误", nil)
+			}
+			updates["birthday"] = birthday
+		}
+	}
+	if s.Phone != nil {
+		if *s.Phone != "" && !phonePattern.MatchString(*s.Phone) {
+			return serializer.ParamErr("手机号格式错误", nil)
+		}
+		updates["phone"] = *s.Phone
+	}
+	if len(updates) == 0 {
+		return serializer.ParamErr("没有需要修改的字段", nil)
+	}
+
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		return serializer.DBErr("修改资料失败", err)
+	}
+	updated, err := model.GetUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditUserUpdate,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, user, updated)
+	keyword.Flag(model.ReportUser, user.ID, user.ID, checked)
+	return serializer.BuildUserResponse(updated)
+}
+
+// UpdateEmail 校验密码后修改当前用户的邮箱
+// 先发送验证邮件到新邮箱，确认后才生效，未配置邮件服务时不能修改
+func (s *UpdateEmailService) UpdateEmail(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if !mail.Enabled() {
+		return serializer.ServerErr("未配置邮件服务", nil)
+	}
+	if ok, err := user.MatchPassword(s.Password); err != nil {
+		return serializer.EncryptErr("密码校验失败", err)
+	} else if !ok {
+		return serializer.ParamErr("密码错误", nil)
+	}
+	if user.Email != nil && *user.Email == s.Email {
+		return serializer.ParamErr("新邮箱与当前邮箱相同", nil)
+	}
+	if res := checkEmailAvailable(s.Email, user.ID); res != nil {
+		return res
+	}
+
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Update("new_email", s.Email).Error; err != nil {
+		return serializer.DBErr("修改邮箱失败", err)
+	}
+	if err := sendTokenEmailTo(user, s.Email, model.TokenChangeEmail, "确认修改 Vid 邮箱", "/confirm-email"); err != nil {
+		return serializer.ServerErr("发送邮件失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditUserEmailRequest,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "验证邮件已发送至新邮箱",
+	}
+}
+
+// ConfirmEmail 使用新邮箱收到的令牌确认修改邮箱
+func (s *VerifyEmailService) ConfirmEmail(c *gin.Context) *serializer.Response {
+	// 先检查新邮箱是否可用再消费令牌，不可用时令牌保持有效
+	token, err := model.GetUserToken(s.Token, model.TokenChangeEmail)
+	if errors.Is(err, model.ErrUserTokenInvalid) {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("修改邮箱失败", err)
+	}
+
+	user, err := model.GetUser(token.UserID)
+	if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if user.NewEmail == "" {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	}
+	// 发送验证邮件后邮箱可能已被他人使用
+	if res := checkEmailAvailable(user.NewEmail, user.ID); res != nil {
+		return res
+	}
+	err = orm.DB().Transaction(func(tx *gorm.DB) error {
+		if _, err := model.UseUserToken(tx, s.Token, model.TokenChangeEmail); err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"email":     user.NewEmail,
+			"new_email": "",
+		}).Error
+	})
+	if errors.Is(err, model.ErrUserTokenInvalid) {
+		return serializer.ParamErr("链接无效或已过期", nil)
+	} else if err != nil {
+		return serializer.DBErr("修改邮箱失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    user.ID,
+		Action:     model.AuditUserEmailChange,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "修改成功",
+	}
+}
+
+// checkEmailAvailable 邮箱是否未被其他用户使用
+func checkEmailAvailable(email string, userID int64) *serializer.Response {
+	var count int64
+	if err := orm.DB().Model(&model.User{}).Where("email = ? AND id <> ?", email, userID).Count(&count).Error; err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if count > 0 {
+		return serializer.ParamErr("邮箱已经注册", nil)
+	}
+	return nil
+}
+
+// UploadAvatar 上传头像，裁剪为正方形并缩放后保存
+func (s *UploadAvatarService) UploadAvatar(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+
+	data, err := upload.ReadImage(s.File)
+	if errors.Is(err, upload.ErrTooLarge) {
+		return serializer.UploadFileErr("图片过大", nil)
+	} else if errors.Is(err, upload.ErrUnsupported) {
+		return serializer.UploadFileErr("不支持的图片格式", nil)
+	} else if err != nil {
+		return serializer.UploadFileErr("", err)
+	}
+
+	crop := image.Rectangle{}
+	if s.Size > 0 {
+		crop = image.Rect(s.X, s.Y, s.X+s.Size, s.Y+s.Size)
+	}
+	out, ext, err := upload.SquareImage(data, crop, avatarSize)
+	if errors.Is(err, upload.ErrCrop) {
+		return serializer.ParamErr("裁剪区域超出图片范围", nil)
+	} else if errors.Is(err, upload.ErrTooManyPixels) {
+		return serializer.UploadFileErr("图片尺寸过大", nil)
+	} else if errors.Is(err, upload.ErrUnsupported) {
+		return serializer.UploadFileErr("不支持的图片格式", nil)
+	} else if err != nil {
+		return serializer.UploadFileErr("", err)
+	}
+
+	url, err := upload.Save(out, "avatar", ext)
+	if err != nil {
+		return serializer.UploadFileErr("", err)
+	}
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Update("avatar", url).Error; err != nil {
+		return serializer.DBErr("保存头像失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditUserAvatar,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, map[string]interface{}{"avatar": user.Avatar}, map[string]interface{}{"avatar": url})
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "上传成功",
+		Data: url,
+	}
+}
+
+// GetProfile 查看用户的公开资料，未激活或被封禁的用户不可见
+// 按保留期内的旧用户名查找时返回改名后的用户
+func (s *GetProfileService) GetProfile() *serializer.Response {
+	tx := orm.DB().Where("status = ?", model.UserActive)
+	switch {
+	case s.ID > 0:
+		tx = tx.Where("id = ?", s.ID)
+	case s.UserName != "":
+		tx = tx.Where("username = ?", s.UserName)
+	default:
+		return serializer.ParamErr("缺少用户id或用户名", nil)
+	}
+
+	user := &model.User{}
+	err := tx.First(user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && s.ID == 0 {
+		return s.redirect()
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	return serializer.BuildProfileResponse(user, "")
+}
+
+// redirect 按旧用户名查找改名后的用户
+func (s *GetProfileService) redirect() *serializer.Response {
+	history, err := model.RedirectHandle(s.UserName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	user := &model.User{}
+	err = orm.DB().Where("id = ? AND status = ?", history.UserID, model.UserActive).First(user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return serializer.ParamErr("用户不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	return serializer.BuildProfileResponse(user, s.UserName)
+}
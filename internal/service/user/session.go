@@ -0,0 +1,70 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+)
+
+// RevokeSessionService 吊销指定会话的服务
+type RevokeSessionService struct {
+	SessionID string `form:"session_id" json:"session_id" binding:"required,len=32"`
+}
+
+// Sessions 查看当前用户的登录设备
+func (s *NoParamsService) Sessions(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	sessions, err := model.Sessions().ListUserSessions(user.ID)
+	if err != nil {
+		return serializer.DBErr("查找会话错误", err)
+	}
+	return serializer.BuildSessionsResponse(sessions, c.GetString("session_id"))
+}
+
+// Revoke 吊销当前用户的指定会话，该会话的令牌立即失效
+func (s *RevokeSessionService) Revoke(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	session, err := model.Sessions().GetSession(s.SessionID)
+	if errors.Is(err, model.ErrSessionNotFound) {
+		return serializer.ParamErr("会话不存在", nil)
+	} else if err != nil {
+		return serializer.DBErr("查找会话错误", err)
+	}
+	if session.UserID != user.ID {
+		return serializer.ParamErr("会话不存在", nil)
+	}
+	if err := model.Sessions().RevokeSession(session.ID); err != nil {
+		return serializer.DBErr("吊销会话失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditSessionRevoke,
+		TargetType: "session",
+		TargetID:   session.ID,
+		Detail:     session.Device,
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "吊销成功",
+	}
+}
+
+// revokeOtherSessions 吊销用户除keep以外的全部会话
+func revokeOtherSessions(userID int64, keep string) error {
+	sessions, err := model.Sessions().ListUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+		if err := model.Sessions().RevokeSession(session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
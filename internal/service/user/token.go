@@ -0,0 +1,89 @@
+package user
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/conf"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/pkg/jwt"
+	"github.com/vidorg/vid_backend/pkg/useragent"
+)
+
+const (
+	defaultAccessExpire  = 15 * time.Minute   // access token默认有效期
+	defaultRefreshExpire = 7 * 24 * time.Hour // refresh token默认有效期，每次轮换后顺延
+)
+
+// accessExpire access token有效期，取自conf
+func accessExpire() time.Duration {
+	if e := conf.Config().Jwt.Expire; e > 0 {
+		return time.Duration(e) * time.Second
+	}
+	return defaultAccessExpire
+}
+
+// refreshExpire refresh token有效期，取自conf
+func refreshExpire() time.Duration {
+	if e := conf.Config().Jwt.RefreshExpire; e > 0 {
+		return time.Duration(e) * time.Second
+	}
+	return defaultRefreshExpire
+}
+
+// issueSession 创建新的登录会话并签发令牌，记录登录设备
+func issueSession(c *gin.Context, userID int64) (*serializer.Token, error) {
+	sid, err := model.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	session := &model.Session{
+		ID:        sid,
+		UserID:    userID,
+		Device:    useragent.DeviceName(userAgent),
+		UserAgent: userAgent,
+		IP:        c.ClientIP(),
+		ExpiresAt: now.Add(refreshExpire()).Unix(),
+		LastSeen:  now.Unix(),
+	}
+	if err := model.Sessions().CreateSession(session); err != nil {
+		return nil, err
+	}
+	return issueTokens(userID, sid)
+}
+
+// issueTokens 在已有会话中签发新的access token与refresh token
+func issueTokens(userID int64, sid string) (*serializer.Token, error) {
+	plain, hash, err := model.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(refreshExpire()).Unix()
+	if err := model.Sessions().SaveRefreshToken(&model.RefreshToken{
+		Hash:      hash,
+		SessionID: sid,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, err
+	}
+	if err := model.Sessions().TouchSession(sid, expiresAt); err != nil {
+		return nil, err
+	}
+
+	access, err := jwt.GenerateSessionToken(userID, sid, accessExpire())
+	if err != nil {
+		return nil, err
+	}
+	return &serializer.Token{
+		Token:        string(access),
+		RefreshToken: plain,
+		ExpiresIn:    int64(accessExpire() / time.Second),
+	}, nil
+}
@@ -0,0 +1,232 @@
+package user
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vidorg/vid_backend/internal/model"
+	"github.com/vidorg/vid_backend/internal/serializer"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/pkg/jwt"
+	"github.com/vidorg/vid_backend/pkg/orm"
+	"github.com/vidorg/vid_backend/pkg/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer      = "Vid"           // 验证器App中显示的服务名
+	challengeExpire = 5 * time.Minute // 两步验证挑战有效期
+	challenge2FA    = "2fa"
+)
+
+// TOTPCodeService 确认开启两步验证的服务
+type TOTPCodeService struct {
+	Code string `form:"code" json:"code" binding:"required,len=6,numeric"`
+}
+
+// TwoFactorAuthService 需要重新验证身份的两步验证操作，code可以是TOTP验证码或恢复码
+type TwoFactorAuthService struct {
+	Password string `form:"password" json:"password" binding:"required"`
+	Code     string `form:"code" json:"code" binding:"required"`
+}
+
+// LoginTOTPService 登录第二步的服务
+type LoginTOTPService struct {
+	ChallengeToken string `form:"challenge_token" json:"challenge_token" binding:"required"`
+	Code           string `form:"code" json:"code" binding:"required"`
+}
+
+// challenge 密码校验通过后签发两步验证挑战
+func challenge(user *model.User) *serializer.Response {
+	token, err := jwt.GenerateChallengeToken(user.ID, challenge2FA, challengeExpire)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	return serializer.BuildChallengeResponse(string(token), int64(challengeExpire/time.Second))
+}
+
+// EnrollTOTP 生成TOTP密钥，需调用ConfirmTOTP验证后才生效
+func (s *NoParamsService) EnrollTOTP(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if user.TOTPEnabled {
+		return serializer.ParamErr("已开启两步验证", nil)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return serializer.EncryptErr("密钥生成失败", err)
+	}
+	uri := totp.URI(totpIssuer, user.UserName, secret)
+	png, err := totp.QRCode(uri, 256)
+	if err != nil {
+		return serializer.ServerErr("二维码生成失败", err)
+	}
+	if err := orm.DB().Model(user).Update("totp_secret", secret).Error; err != nil {
+		return serializer.DBErr("保存密钥失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &serializer.TOTPEnroll{
+			Secret: secret,
+			URI:    uri,
+			QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		},
+	}
+}
+
+// ConfirmTOTP 验证App生成的验证码后开启两步验证，并返回恢复码
+func (s *TOTPCodeService) ConfirmTOTP(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if user.TOTPEnabled {
+		return serializer.ParamErr("已开启两步验证", nil)
+	}
+	if user.TOTPSecret == "" {
+		return serializer.ParamErr("请先获取密钥", nil)
+	}
+	if ok, err := user.VerifyTOTP(s.Code); err != nil {
+		return serializer.DBErr("校验验证码失败", err)
+	} else if !ok {
+		return serializer.ParamErr("验证码错误", nil)
+	}
+
+	codes, err := model.NewRecoveryCodes()
+	if err != nil {
+		return serializer.EncryptErr("恢复码生成失败", err)
+	}
+	err = orm.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		return model.ReplaceRecoveryCodes(tx, user.ID, codes)
+	})
+	if err != nil {
+		return serializer.DBErr("开启两步验证失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditTOTPEnable,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "开启成功",
+		Data: &serializer.RecoveryCodes{Codes: codes},
+	}
+}
+
+// reauth 校验密码与第二因素
+func (s *TwoFactorAuthService) reauth(user *model.User) *serializer.Response {
+	if !user.TOTPEnabled {
+		return serializer.ParamErr("未开启两步验证", nil)
+	}
+	if ok, err := user.MatchPassword(s.Password); err != nil {
+		return serializer.EncryptErr("密码校验失败", err)
+	} else if !ok {
+		return serializer.ParamErr("密码或验证码错误", nil)
+	}
+	if ok, err := user.VerifySecondFactor(s.Code); err != nil {
+		return serializer.DBErr("校验验证码失败", err)
+	} else if !ok {
+		return serializer.ParamErr("密码或验证码错误", nil)
+	}
+	return nil
+}
+
+// DisableTOTP 关闭两步验证，需要重新验证密码与验证码
+func (s *TwoFactorAuthService) DisableTOTP(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if res := s.reauth(user); res != nil {
+		return res
+	}
+
+	err := orm.DB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":  "",
+			"totp_enabled": false,
+			"totp_counter": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return serializer.DBErr("关闭两步验证失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditTOTPDisable,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "关闭成功",
+	}
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，原有恢复码全部失效
+func (s *TwoFactorAuthService) RegenerateRecoveryCodes(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if res := s.reauth(user); res != nil {
+		return res
+	}
+
+	codes, err := model.NewRecoveryCodes()
+	if err != nil {
+		return serializer.EncryptErr("恢复码生成失败", err)
+	}
+	if err := model.ReplaceRecoveryCodes(orm.DB(), user.ID, codes); err != nil {
+		return serializer.DBErr("保存恢复码失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditRecoveryCodes,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "success",
+		Data: &serializer.RecoveryCodes{Codes: codes},
+	}
+}
+
+// Login 使用挑战令牌与TOTP验证码或恢复码完成登录
+func (s *LoginTOTPService) Login(c *gin.Context) *serializer.Response {
+	claims, err := jwt.ParseChallengeToken([]byte(s.ChallengeToken), challenge2FA)
+	if err != nil {
+		return serializer.LoginExpiredErr()
+	}
+	user, err := model.GetUser(claims.UID)
+	if err != nil {
+		return serializer.LoginExpiredErr()
+	}
+	if !user.TOTPEnabled {
+		return serializer.LoginExpiredErr()
+	}
+	if res := checkLockout(c, user.UserName); res != nil {
+		return res
+	}
+	if res := checkStatus(user); res != nil {
+		return res
+	}
+
+	if ok, err := user.VerifySecondFactor(s.Code); err != nil {
+		return serializer.DBErr("校验验证码失败", err)
+	} else if !ok {
+		return loginFailed(c, user.UserName, user, "验证码错误")
+	}
+	loginSucceeded(user.UserName)
+
+	token, err := issueSession(c, user.ID)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	return serializer.BuildLoginResponse(user, token)
+}
@@ -1,19 +1,37 @@
 package user
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/vidorg/vid_backend/internal/model"
 	"github.com/vidorg/vid_backend/internal/serializer"
-	"github.com/vidorg/vid_backend/pkg/jwt"
+	"github.com/vidorg/vid_backend/internal/service/audit"
+	"github.com/vidorg/vid_backend/internal/service/captcha"
+	"github.com/vidorg/vid_backend/internal/service/keyword"
+	"github.com/vidorg/vid_backend/pkg/filter"
+	"github.com/vidorg/vid_backend/pkg/logger"
+	"github.com/vidorg/vid_backend/pkg/mail"
 	"github.com/vidorg/vid_backend/pkg/orm"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/vidorg/vid_backend/pkg/password"
+	"go.uber.org/zap"
 	"time"
 )
 
 // LoginService 管理用户登录的服务
 type LoginService struct {
-	UserName string `form:"username" json:"username" binding:"required,min=3,max=12"`
-	Password string `form:"password" json:"password" binding:"required,min=3,max=20"`
+	UserName      string `form:"username" json:"username" binding:"required,min=3,max=20"`
+	Password      string `form:"password" json:"password" binding:"required,max=72"`
+	CaptchaID     string `form:"captcha_id" json:"captcha_id"`         // 失败次数过多后需要
+	CaptchaAnswer string `form:"captcha_answer" json:"captcha_answer"` // 图片中的文字或工作量证明的nonce
+}
+
+// RefreshService 刷新令牌的服务
+type RefreshService struct {
+	RefreshToken string `form:"refresh_token" json:"refresh_token" binding:"required"`
 }
 
 // NoParamsService 无参数的服务
@@ -21,54 +39,155 @@ type NoParamsService struct{}
 
 // QueryUsersService 用户分页查询的服务
 type QueryUsersService struct {
-	Page  int `form:"page" json:"page" query:"page"`
-	Limit int `form:"limit" json:"limit" query:"limit"`
+	Page    int    `form:"page" json:"page" query:"page"`
+	Limit   int    `form:"limit" json:"limit" query:"limit" binding:"max=100"`
+	Keyword string `form:"keyword" json:"keyword" binding:"max=50"` // 按ID、用户名、昵称或邮箱查找
+	Status  string `form:"status" json:"status" binding:"omitempty,oneof=active inactive suspend deleted"`
+	Role    string `form:"role" json:"role" binding:"max=10"`
+	Order   string `form:"order" json:"order" binding:"omitempty,oneof=id_desc id_asc"`
 }
 
 // RegisterService 管理用户注册的服务
 type RegisterService struct {
-	UserName string `form:"username" json:"username" binding:"required,min=3,max=12"`
-	Password string `form:"password" json:"password" binding:"required,min=3,max=20"`
-	NickName string `form:"nickname" json:"nickname" binding:"required,min=3,max=20"`
-	Email    string `form:"email" json:"email" binding:"required,min=3"`
+	UserName      string `form:"username" json:"username" binding:"required"`
+	Password      string `form:"password" json:"password" binding:"required,max=72"`
+	NickName      string `form:"nickname" json:"nickname" binding:"required,min=3,max=20"`
+	Email         string `form:"email" json:"email" binding:"required,email"`
+	CaptchaID     string `form:"captcha_id" json:"captcha_id" binding:"required"`
+	CaptchaAnswer string `form:"captcha_answer" json:"captcha_answer" binding:"required,max=64"`
 }
 
-// ResetPasswordService 管理用户重新设置密码的服务
+// ResetPasswordService 已登录用户修改密码的服务
 type ResetPasswordService struct {
-	UserName    string `form:"username" json:"username" binding:"required,min=3,max=12"`
-	Password    string `form:"password" json:"password" binding:"required,min=3,max=20"`
-	NewPassword string `form:"new_password" json:"new_password" binding:"required,min=3,max=20"`
+	Password    string `form:"password" json:"password" binding:"required,max=72"`
+	NewPassword string `form:"new_password" json:"new_password" binding:"required,max=72"`
 }
 
 // Login 用户登录
-func (u *LoginService) Login() *serializer.Response {
+func (u *LoginService) Login(c *gin.Context) *serializer.Response {
 	user := &model.User{}
 
+	// 失败次数过多时暂时锁定
+	if res := checkLockout(c, u.UserName); res != nil {
+		return res
+	}
+	if res := checkCaptcha(c, u.UserName, u.CaptchaID, u.CaptchaAnswer); res != nil {
+		return res
+	}
+
 	// 查找用户
-	if rdb := orm.DB().Where("username = ?", u.UserName).First(user); rdb.RowsAffected == 0 {
-		return serializer.ParamErr("账号或密码错误", nil)
-	} else if err := rdb.Error; err != nil {
-		return serializer.DBErr("查找用户错误", err)
+	if rdb := orm.DB().Where("username = ?", u.UserName).Limit(1).Find(user); rdb.Error != nil {
+		return serializer.DBErr("查找用户错误", rdb.Error)
+	} else if rdb.RowsAffected == 0 {
+		password.VerifyDummy(u.Password)
+		return loginFailed(c, u.UserName, nil, "账号或密码错误")
 	}
 
-	// 检查密码
+	// 检查密码，只绑定了第三方账号的用户没有密码，同样按密码错误处理
+	if user.Password == "" {
+		password.VerifyDummy(u.Password)
+		return loginFailed(c, u.UserName, user, "账号或密码错误")
+	}
 	if ok, err := user.MatchPassword(u.Password); err != nil {
 		return serializer.EncryptErr("密码校验失败", err)
 	} else if !ok {
-		return serializer.ParamErr("账号或密码错误", nil)
+		return loginFailed(c, u.UserName, user, "账号或密码错误")
 	}
+	// 哈希参数已过时则顺带升级，失败不影响登录
+	if err := user.RehashPassword(u.Password); err != nil {
+		logger.Logger().Warn("[Password] rehash err", zap.Int64("user_id", user.ID), zap.Error(err))
+	}
+
+	// 检查账号状态
+	if res := checkStatus(user); res != nil {
+		return res
+	}
+
+	// 两步验证，通过后才清除失败记录
+	if user.TOTPEnabled {
+		return challenge(user)
+	}
+	loginSucceeded(u.UserName)
 
 	// JWT
-	token, err := jwt.GenerateToken(user.ID, time.Hour)
+	token, err := issueSession(c, user.ID)
 	if err != nil {
 		return serializer.EncryptErr("令牌生成失败", err)
 	}
 
-	return serializer.BuildLoginResponse(user, string(token))
+	return serializer.BuildLoginResponse(user, token)
+}
+
+// Refresh 使用refresh token换取新的令牌，旧的refresh token随即失效
+// 已使用过的refresh token再次出现说明可能被盗用，吊销整个会话
+func (r *RefreshService) Refresh(c *gin.Context) *serializer.Response {
+	token, first, err := model.Sessions().UseRefreshToken(model.HashRefreshToken(r.RefreshToken))
+	if errors.Is(err, model.ErrTokenNotFound) {
+		return serializer.LoginExpiredErr()
+	} else if err != nil {
+		return serializer.DBErr("查找令牌错误", err)
+	}
+
+	session, err := model.Sessions().GetSession(token.SessionID)
+	if errors.Is(err, model.ErrSessionNotFound) {
+		return serializer.LoginExpiredErr()
+	} else if err != nil {
+		return serializer.DBErr("查找会话错误", err)
+	}
+	now := time.Now().Unix()
+	if !session.Active(now) {
+		return serializer.LoginExpiredErr()
+	}
+	if !first {
+		if err := model.Sessions().RevokeSession(session.ID); err != nil {
+			return serializer.DBErr("吊销会话失败", err)
+		}
+		logger.Logger().Warn("[Auth] refresh token reused, session revoked",
+			zap.Int64("user_id", session.UserID), zap.String("session_id", session.ID))
+		return serializer.LoginExpiredErr()
+	}
+	if token.ExpiresAt <= now {
+		return serializer.LoginExpiredErr()
+	}
+	if user, err := model.GetUser(session.UserID); err != nil {
+		return serializer.LoginExpiredErr()
+	} else if res := checkStatus(user); res != nil {
+		return res
+	}
+
+	res, err := issueTokens(session.UserID, session.ID)
+	if err != nil {
+		return serializer.EncryptErr("令牌生成失败", err)
+	}
+	if err := model.Sessions().SeenSession(session.ID, c.ClientIP(), now); err != nil {
+		return serializer.DBErr("更新会话失败", err)
+	}
+	return serializer.BuildTokenResponse(res)
 }
 
-// Logout 用户登出
+// Logout 用户登出，吊销当前会话
 func (s *NoParamsService) Logout(c *gin.Context) *serializer.Response {
+	if err := model.Sessions().RevokeSession(c.GetString("session_id")); err != nil {
+		return serializer.DBErr("注销失败", err)
+	}
+
+	return &serializer.Response{
+		Code: 200,
+		Msg:  "注销成功",
+	}
+}
+
+// LogoutAll 退出所有设备，吊销用户的全部会话
+func (s *NoParamsService) LogoutAll(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
+	if err := model.Sessions().RevokeUserSessions(user.ID); err != nil {
+		return serializer.DBErr("注销失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditSessionRevokeAll,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
 
 	return &serializer.Response{
 		Code: 200,
@@ -85,21 +204,40 @@ func (s *NoParamsService) Auth(c *gin.Context) *serializer.Response {
 }
 
 // Register 用户注册
-func (u *RegisterService) Register() *serializer.Response {
+func (u *RegisterService) Register(c *gin.Context) *serializer.Response {
+	// 人机验证先于其他检查，避免被用于批量探测
+	if res := captcha.Verify(captcha.SceneRegister, u.CaptchaID, u.CaptchaAnswer); res != nil {
+		return res
+	}
+
 	user := &model.User{
 		UserName: u.UserName,
 		Nickname: u.NickName,
 		Status:   model.UserActive,
 		Email:    &u.Email,
-		Role:     "normal",
+		Role:     model.RoleNormal,
 	}
 
 	// 表单验证
+	if res := checkPassword(u.Password, u.UserName); res != nil {
+		return res
+	}
+	if res := checkHandle(u.UserName, 0); res != nil {
+		return res
+	}
+	nickname, res := keyword.Check("昵称", u.NickName)
+	if res != nil {
+		return res
+	}
+	user.Nickname = nickname.Text
 	var count int64
-	orm.DB().Model(&model.User{}).Where("username = ?", u.UserName).Count(&count)
+	orm.DB().Model(&model.User{}).Where("email = ?", u.Email).Count(&count)
 	if count > 0 {
-		count = 0
-		return serializer.ParamErr("用户名已经注册", nil)
+		return serializer.ParamErr("邮箱已经注册", nil)
+	}
+	// 配置了邮件服务时需验证邮箱后才激活
+	if mail.Enabled() {
+		user.Status = model.UserInactive
 	}
 
 	// 加密密码
@@ -111,6 +249,18 @@ func (u *RegisterService) Register() *serializer.Response {
 	if err := orm.DB().Create(&user).Error; err != nil {
 		return serializer.ParamErr("注册失败", err)
 	}
+	audit.Record(c, &model.AuditLog{
+		ActorID:    user.ID,
+		Action:     model.AuditUserRegister,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, user)
+	keyword.Flag(model.ReportUser, user.ID, user.ID, map[string]*filter.Result{"昵称": nickname})
+	if user.Status == model.UserInactive {
+		if err := sendVerifyEmail(user); err != nil {
+			logger.Logger().Warn("[Mail] verify email not sent", zap.Int64("user_id", user.ID), zap.Error(err))
+		}
+	}
 
 	return serializer.BuildUserResponse(user)
 }
@@ -123,47 +273,93 @@ func (q *QueryUsersService) QueryUsers() *serializer.Response {
 		page = 1
 	}
 	if limit < 1 {
-		limit = 1
+		limit = 20
+	}
+
+	tx := orm.DB().Model(&model.User{})
+	if q.Keyword != "" {
+		like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Keyword) + "%"
+		if id, err := strconv.ParseInt(q.Keyword, 10, 64); err == nil {
+			tx = tx.Where("id = ? OR username LIKE ? OR nickname LIKE ? OR email LIKE ?", id, like, like, like)
+		} else {
+			tx = tx.Where("username LIKE ? OR nickname LIKE ? OR email LIKE ?", like, like, like)
+		}
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+	if q.Role != "" {
+		tx = tx.Where("role = ?", q.Role)
+	}
+	order := "id DESC"
+	if q.Order == "id_asc" {
+		order = "id"
 	}
 
 	users := make([]*model.User, 0)
 	var total int64
-	orm.DB().Model(&model.User{}).Count(&total)
-	orm.DB().Offset((page - 1) * limit).Limit(limit).Model(&model.User{}).Find(&users)
+	if err := tx.Count(&total).Error; err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
+	if err := tx.Order(order).Offset((page - 1) * limit).Limit(limit).Find(&users).Error; err != nil {
+		return serializer.DBErr("查找用户错误", err)
+	}
 
-	res := serializer.BuildListResponse(total, page, limit, serializer.BuildUsersResponse(users))
+	res := serializer.BuildListResponse(total, page, limit, serializer.BuildAdminUsersResponse(users))
 	return res
 }
 
-// ResetPassword 更新密码
-func (u *ResetPasswordService) ResetPassword() *serializer.Response {
-	user := &model.User{}
-
-	// 查找用户
-	if rdb := orm.DB().Where("username = ?", u.UserName).First(user); rdb.RowsAffected == 0 {
-		return serializer.ParamErr("账号或密码错误", nil)
-	} else if err := rdb.Error; err != nil {
-		return serializer.DBErr("查找用户错误", err)
-	}
+// ResetPassword 校验旧密码后更新当前用户的密码，并注销其他设备上的会话
+func (u *ResetPasswordService) ResetPassword(c *gin.Context) *serializer.Response {
+	user := c.MustGet("user").(*model.User)
 
 	// 检查密码
 	if ok, err := user.MatchPassword(u.Password); err != nil {
 		return serializer.EncryptErr("密码校验失败", err)
 	} else if !ok {
-		return serializer.ParamErr("账号或密码错误", nil)
+		return serializer.ParamErr("密码错误", nil)
 	}
 
-	bytes, err := bcrypt.GenerateFromPassword([]byte(u.NewPassword), model.PasswordCost)
-	if err != nil {
-		return serializer.ParamErr("参数错误", err)
+	if res := checkPassword(u.NewPassword, user.UserName); res != nil {
+		return res
+	}
+	updated := &model.User{}
+	if err := updated.SetPassword(u.NewPassword); err != nil {
+		return serializer.EncryptErr("密码加密失败", err)
 	}
 
-	update := orm.DB().Model(&model.User{}).Update("password", string(bytes))
-	if update == nil {
-		return serializer.ParamErr("密码加密失败", err)
+	if err := orm.DB().Model(&model.User{}).Where("id = ?", user.ID).Update("password", updated.Password).Error; err != nil {
+		return serializer.DBErr("更新密码失败", err)
 	}
+	// 其他设备上的会话随之失效，当前会话保留
+	if err := revokeOtherSessions(user.ID, c.GetString("session_id")); err != nil {
+		return serializer.DBErr("注销会话失败", err)
+	}
+	audit.Record(c, &model.AuditLog{
+		Action:     model.AuditUserPassword,
+		TargetType: "user",
+		TargetID:   strconv.FormatInt(user.ID, 10),
+	}, nil, nil)
 	return &serializer.Response{
 		Code: 200,
 		Msg:  "更新成功",
 	}
 }
+
+// checkPassword 按密码策略检查新密码
+func checkPassword(plain, username string) *serializer.Response {
+	switch err := password.DefaultPolicy.Check(plain, username); {
+	case err == nil:
+		return nil
+	case errors.Is(err, password.ErrTooShort):
+		return serializer.ParamErr(fmt.Sprintf("密码至少需要%d个字符", password.DefaultPolicy.MinLength), nil)
+	case errors.Is(err, password.ErrTooLong):
+		return serializer.ParamErr("密码过长", nil)
+	case errors.Is(err, password.ErrSameAsUsername):
+		return serializer.ParamErr("密码不能与用户名相同", nil)
+	case errors.Is(err, password.ErrCommon):
+		return serializer.ParamErr("密码过于常见，请更换", nil)
+	default:
+		return serializer.ParamErr("密码不符合要求", err)
+	}
+}
@@ -20,7 +20,12 @@ func (g *GetVideoListService) GetVideoList(c *gin.Context) *serializer.Response
 	var total int64
 
 	tx := orm.Pagination(orm.DB().Model(&model.Video{}), g.Page, g.Limit).
-		Preload("Author")
+		Preload("Author").
+		Where("status = ?", model.VideoNormal)
+	// 登录用户不展示其拉黑的作者
+	if user, exists := c.Get("user"); exists {
+		tx = tx.Where("user_id NOT IN (?)", model.BlockedUIDs(user.(*model.User).ID))
+	}
 
 	if g.CategoryID == nil {
 		tx.Count(&total).
@@ -0,0 +1,150 @@
+package captcha
+
+import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeImage = "image" // 图片验证码，识别扭曲的文字
+	TypePoW   = "pow"   // 工作量证明，由客户端脚本计算，用户无需操作
+)
+
+var ErrType = errors.New("captcha: unknown type")
+
+// Options 验证码参数
+type Options struct {
+	Length     int           // 图片文字长度
+	Width      int           // 图片宽度
+	Height     int           // 图片高度
+	Difficulty int           // 工作量证明要求的0比特数
+	TTL        time.Duration // 挑战有效期
+}
+
+// Challenge 下发给客户端的挑战
+// 图片验证码的答案为图片中的文字，不区分大小写
+// 工作量证明的答案为任意nonce，使sha256(prefix+nonce)开头至少有difficulty个0比特
+type Challenge struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Image      string `json:"image,omitempty"` // data URL
+	Prefix     string `json:"prefix,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	Expires    int64  `json:"expires"`
+}
+
+// Manager 创建与校验挑战
+type Manager struct {
+	prefix  string
+	options *Options
+	store   Store
+	now     func() time.Time
+}
+
+// New create manager, keys are namespaced by prefix
+func New(prefix string, options *Options, store Store) *Manager {
+	return &Manager{prefix: prefix, options: options, store: store, now: time.Now}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// randomText 使用crypto/rand从alphabet中选取字符
+func randomText(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		idx, _ := crand.Int(crand.Reader, big.NewInt(int64(len(alphabet))))
+		b[i] = alphabet[idx.Int64()]
+	}
+	return string(b)
+}
+
+// newRand 渲染图片用的随机数，种子取自crypto/rand
+func newRand() *rand.Rand {
+	b := make([]byte, 8)
+	_, _ = crand.Read(b)
+	return rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(b))))
+}
+
+// Create 创建指定类型的挑战
+func (m *Manager) Create(typ string) (*Challenge, error) {
+	ch := &Challenge{
+		ID:      randomHex(16),
+		Type:    typ,
+		Expires: m.now().Add(m.options.TTL).Unix(),
+	}
+	var answer string
+	switch typ {
+	case TypeImage:
+		text := randomText(m.options.Length)
+		img, err := Render(text, m.options.Width, m.options.Height, newRand())
+		if err != nil {
+			return nil, err
+		}
+		ch.Image = "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)
+		answer = text
+	case TypePoW:
+		ch.Prefix = randomHex(16)
+		ch.Difficulty = m.options.Difficulty
+		answer = strconv.Itoa(ch.Difficulty) + ":" + ch.Prefix
+	default:
+		return nil, ErrType
+	}
+	if err := m.store.Set(m.prefix+ch.ID, typ+":"+answer, m.options.TTL); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Verify 校验答案，无论结果如何挑战都会失效，挑战不存在或已过期时返回false
+// 指定types时挑战必须为其中一种类型
+func (m *Manager) Verify(id, answer string, types ...string) (bool, error) {
+	if id == "" || answer == "" {
+		return false, nil
+	}
+	value, err := m.store.Take(m.prefix + id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	parts := strings.SplitN(value, ":", 3)
+	if len(types) > 0 && !contains(types, parts[0]) {
+		return false, nil
+	}
+	switch parts[0] {
+	case TypeImage:
+		return len(parts) == 2 && strings.EqualFold(strings.TrimSpace(answer), parts[1]), nil
+	case TypePoW:
+		if len(parts) != 3 {
+			return false, nil
+		}
+		difficulty, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return false, nil
+		}
+		return VerifyWork(parts[2], answer, difficulty), nil
+	}
+	return false, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
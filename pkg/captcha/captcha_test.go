@@ -0,0 +1,94 @@
+package captcha
+
+import (
+	"bytes"
+	"image/png"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	b, err := Render("a2k9x", 160, 60, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, 160, img.Bounds().Dx())
+	assert.Equal(t, 60, img.Bounds().Dy())
+}
+
+func TestVerifyWork(t *testing.T) {
+	// sha256("") = e3b0c442...
+	assert.Equal(t, 0, LeadingZeroBits("", ""))
+	nonce := 0
+	for LeadingZeroBits("abc", strconv.Itoa(nonce)) < 8 {
+		nonce++
+	}
+	assert.True(t, VerifyWork("abc", strconv.Itoa(nonce), 8))
+	assert.False(t, VerifyWork("abc", strconv.Itoa(nonce), 256))
+	assert.False(t, VerifyWork("abc", "", 0))
+	assert.False(t, VerifyWork("abc", strings.Repeat("0", 65), 0))
+}
+
+func TestManager(t *testing.T) {
+	now := time.Unix(1614600000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	m := New("captcha:", &Options{Length: 5, Width: 160, Height: 60, Difficulty: 8, TTL: time.Minute}, store)
+	m.now = store.now
+
+	// 图片验证码不区分大小写，只能使用一次
+	ch, err := m.Create(TypeImage)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ch.Image, "data:image/png;base64,"))
+	text := store.entries["captcha:"+ch.ID].value[len(TypeImage)+1:]
+	ok, _ := m.Verify(ch.ID, strings.ToUpper(text))
+	assert.True(t, ok)
+	ok, _ = m.Verify(ch.ID, text)
+	assert.False(t, ok)
+
+	// 答错后挑战同样失效
+	ch, _ = m.Create(TypeImage)
+	text = store.entries["captcha:"+ch.ID].value[len(TypeImage)+1:]
+	ok, _ = m.Verify(ch.ID, "wrong")
+	assert.False(t, ok)
+	ok, _ = m.Verify(ch.ID, text)
+	assert.False(t, ok)
+
+	// 工作量证明
+	ch, err = m.Create(TypePoW)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, ch.Difficulty)
+	nonce := 0
+	for LeadingZeroBits(ch.Prefix, strconv.Itoa(nonce)) < ch.Difficulty {
+		nonce++
+	}
+	ok, _ = m.Verify(ch.ID, strconv.Itoa(nonce))
+	assert.True(t, ok)
+
+	// 只接受图片验证码时，工作量证明即使答案正确也不通过
+	ch, _ = m.Create(TypePoW)
+	nonce = 0
+	for LeadingZeroBits(ch.Prefix, strconv.Itoa(nonce)) < ch.Difficulty {
+		nonce++
+	}
+	ok, _ = m.Verify(ch.ID, strconv.Itoa(nonce), TypeImage)
+	assert.False(t, ok)
+	ch, _ = m.Create(TypeImage)
+	text = store.entries["captcha:"+ch.ID].value[len(TypeImage)+1:]
+	ok, _ = m.Verify(ch.ID, text, TypeImage)
+	assert.True(t, ok)
+
+	// 过期
+	ch, _ = m.Create(TypePoW)
+	now = now.Add(time.Minute)
+	ok, _ = m.Verify(ch.ID, "1")
+	assert.False(t, ok)
+
+	_, err = m.Create("sms")
+	assert.Equal(t, ErrType, err)
+}
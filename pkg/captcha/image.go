@@ -0,0 +1,110 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/rand"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// alphabet 验证码字符，去掉了容易混淆的0o1il
+const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// glyph 使用basicfont渲染的单个字符，7x13的灰度点阵
+func glyph(r rune) *image.Alpha {
+	face := basicfont.Face7x13
+	mask := image.NewAlpha(image.Rect(0, 0, face.Width, face.Height))
+	d := &font.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	d.DrawString(string(r))
+	return mask
+}
+
+// Render 将文本渲染为扭曲的PNG图片
+// 每个字符随机缩放、旋转与偏移，整体叠加正弦波形扭曲，并加入干扰线与噪点
+func Render(text string, width, height int, rnd *rand.Rand) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	bg := color.RGBA{R: uint8(230 + rnd.Intn(26)), G: uint8(230 + rnd.Intn(26)), B: uint8(230 + rnd.Intn(26)), A: 255}
+	draw.Draw(img, img.Rect, &image.Uniform{C: bg}, image.Point{}, draw.Src)
+
+	// 正弦扭曲参数，所有字符共用以使笔画连贯变形
+	amp := float64(height) * (0.05 + rnd.Float64()*0.05)
+	freq := 2 * math.Pi / (float64(width) * (0.4 + rnd.Float64()*0.4))
+	phase := rnd.Float64() * 2 * math.Pi
+	warp := func(x, y int) float64 {
+		return float64(y) + amp*math.Sin(float64(x)*freq+phase)
+	}
+
+	runes := []rune(text)
+	cell := float64(width) / float64(len(runes)+1)
+	for i, r := range runes {
+		mask := glyph(r)
+		gw, gh := float64(mask.Rect.Dx()), float64(mask.Rect.Dy())
+		scale := float64(height) / gh * (0.55 + rnd.Float64()*0.15)
+		angle := (rnd.Float64() - 0.5) * 0.7
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		cx := cell*(float64(i)+1) + (rnd.Float64()-0.5)*cell*0.3
+		cy := float64(height)/2 + (rnd.Float64()-0.5)*float64(height)*0.15
+		fg := randomDark(rnd)
+
+		// 逆向映射：对字符附近的每个像素求其在点阵中的位置
+		radius := int(math.Hypot(gw, gh)*scale/2) + 1
+		for y := int(cy) - radius - int(amp); y <= int(cy)+radius+int(amp); y++ {
+			for x := int(cx) - radius; x <= int(cx)+radius; x++ {
+				if !(image.Point{X: x, Y: y}).In(img.Rect) {
+					continue
+				}
+				dx, dy := float64(x)-cx, warp(x, y)-cy
+				u := (dx*cos+dy*sin)/scale + gw/2
+				v := (-dx*sin+dy*cos)/scale + gh/2
+				if u < 0 || v < 0 || u >= gw || v >= gh {
+					continue
+				}
+				if mask.AlphaAt(int(u), int(v)).A > 0 {
+					img.SetRGBA(x, y, fg)
+				}
+			}
+		}
+	}
+
+	// 穿过文字的干扰曲线
+	for n := 0; n < 2+rnd.Intn(2); n++ {
+		c := randomDark(rnd)
+		y0 := float64(height) * (0.3 + rnd.Float64()*0.4)
+		a := float64(height) * (0.1 + rnd.Float64()*0.15)
+		f := 2 * math.Pi / (float64(width) * (0.5 + rnd.Float64()))
+		p := rnd.Float64() * 2 * math.Pi
+		for x := 0; x < width; x++ {
+			y := int(y0 + a*math.Sin(float64(x)*f+p))
+			for t := 0; t < 2; t++ {
+				if (image.Point{X: x, Y: y + t}).In(img.Rect) {
+					img.SetRGBA(x, y+t, c)
+				}
+			}
+		}
+	}
+	for n := 0; n < width*height/12; n++ {
+		img.SetRGBA(rnd.Intn(width), rnd.Intn(height), randomDark(rnd))
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func randomDark(rnd *rand.Rand) color.RGBA {
+	return color.RGBA{R: uint8(rnd.Intn(120)), G: uint8(rnd.Intn(120)), B: uint8(rnd.Intn(120)), A: 255}
+}
@@ -0,0 +1,28 @@
+package captcha
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+// LeadingZeroBits 返回sha256(prefix+nonce)开头的0比特数
+func LeadingZeroBits(prefix, nonce string) int {
+	sum := sha256.Sum256([]byte(prefix + nonce))
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// VerifyWork 校验工作量证明：sha256(prefix+nonce)开头至少有difficulty个0比特
+// 期望的计算量为2^difficulty次哈希，校验只需一次
+func VerifyWork(prefix, nonce string, difficulty int) bool {
+	if len(nonce) == 0 || len(nonce) > 64 {
+		return false
+	}
+	return LeadingZeroBits(prefix, nonce) >= difficulty
+}
@@ -0,0 +1,90 @@
+package captcha
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+var ErrNotFound = errors.New("captcha: challenge not found")
+
+// Store 挑战答案的存储，每个挑战只能取出一次
+type Store interface {
+	Set(key, value string, ttl time.Duration) error
+	// Take 取出并删除key，不存在或已过期时返回ErrNotFound
+	Take(key string) (string, error)
+}
+
+// RedisStore 基于redis的存储，多实例共享挑战
+type RedisStore struct{}
+
+// Set ...
+func (r *RedisStore) Set(key, value string, ttl time.Duration) error {
+	return redis.Set(key, value, ttl)
+}
+
+// Take ...
+func (r *RedisStore) Take(key string) (string, error) {
+	v, err := redis.GetDel(key)
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return v, err
+}
+
+// MemoryStore 进程内存储，redis不可用时使用，多实例部署时需要会话保持
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	ops     int
+	now     func() time.Time
+}
+
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+// sweepEvery 每隔若干次写操作清理一次过期记录
+const sweepEvery = 1024
+
+// NewMemoryStore ...
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*entry{}, now: time.Now}
+}
+
+// Set ...
+func (m *MemoryStore) Set(key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.ops++
+	if m.ops%sweepEvery == 0 {
+		for k, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	m.entries[key] = &entry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Take ...
+func (m *MemoryStore) Take(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	delete(m.entries, key)
+	if !m.now().Before(e.expires) {
+		return "", ErrNotFound
+	}
+	return e.value, nil
+}
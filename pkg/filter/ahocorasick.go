@@ -0,0 +1,86 @@
+package filter
+
+// automaton Aho-Corasick自动机，一次扫描找出文本中出现的全部模式串
+type automaton struct {
+	nodes   []*acNode
+	lengths []int // 各模式串的长度
+}
+
+type acNode struct {
+	next map[rune]int
+	fail int
+	out  []int // 以该节点结尾的模式串，包括沿失配链可达的
+}
+
+// acMatch 匹配结果，end为模式串最后一个字符之后的位置
+type acMatch struct {
+	pattern    int
+	start, end int
+}
+
+func newAutomaton(patterns [][]rune) *automaton {
+	a := &automaton{
+		nodes:   []*acNode{{next: map[rune]int{}}},
+		lengths: make([]int, len(patterns)),
+	}
+	for i, p := range patterns {
+		a.lengths[i] = len(p)
+		cur := 0
+		for _, r := range p {
+			next, ok := a.nodes[cur].next[r]
+			if !ok {
+				next = len(a.nodes)
+				a.nodes = append(a.nodes, &acNode{next: map[rune]int{}})
+				a.nodes[cur].next[r] = next
+			}
+			cur = next
+		}
+		a.nodes[cur].out = append(a.nodes[cur].out, i)
+	}
+
+	// 按层构建失配指针，子节点的输出合并失配节点的输出
+	queue := make([]int, 0, len(a.nodes))
+	for _, child := range a.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range a.nodes[cur].next {
+			fail := a.nodes[cur].fail
+			for fail > 0 {
+				if _, ok := a.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = a.nodes[fail].fail
+			}
+			if next, ok := a.nodes[fail].next[r]; ok && next != child {
+				a.nodes[child].fail = next
+			}
+			a.nodes[child].out = append(a.nodes[child].out, a.nodes[a.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return a
+}
+
+// find 查找text中出现的全部模式串，允许重叠
+func (a *automaton) find(text []rune) []acMatch {
+	var matches []acMatch
+	cur := 0
+	for i, r := range text {
+		for cur > 0 {
+			if _, ok := a.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = a.nodes[cur].fail
+		}
+		if next, ok := a.nodes[cur].next[r]; ok {
+			cur = next
+		}
+		for _, p := range a.nodes[cur].out {
+			matches = append(matches, acMatch{pattern: p, start: i + 1 - a.lengths[p], end: i + 1})
+		}
+	}
+	return matches
+}
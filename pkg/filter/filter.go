@@ -0,0 +1,185 @@
+package filter
+
+import (
+	"bufio"
+	_ "embed"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Rule 过滤规则
+type Rule struct {
+	ID     int64  `json:"id"`
+	Word   string `json:"word"`
+	Action string `json:"action"`
+}
+
+// Match 命中的规则，Start与End为命中内容在原文中的位置（按rune计），不含End
+type Match struct {
+	Rule  *Rule `json:"rule"`
+	Start int   `json:"start"`
+	End   int   `json:"end"`
+}
+
+// Result 过滤结果
+type Result struct {
+	Text    string   `json:"text"`    // 替换后的文本
+	Matches []*Match `json:"matches"` // 按位置排序
+}
+
+// Filter 关键词过滤器，创建后只读，可并发使用
+type Filter struct {
+	rules  []*Rule
+	plain  *automaton // 规范化后的规则词
+	pinyin *automaton // 含汉字规则词的拼音形式
+	// 自动机中的模式串对应的规则下标
+	plainRules, pinyinRules []int
+}
+
+const (
+	ActionBlock = "block" // 拒绝提交
+	ActionMask  = "mask"  // 替换为*
+	ActionFlag  = "flag"  // 允许提交，转人工审核
+
+	maskRune = '*'
+)
+
+var (
+	//go:embed default.txt
+	defaultList string
+
+	current atomic.Value
+)
+
+// DefaultRules 内置的默认规则
+func DefaultRules() []*Rule {
+	rules := make([]*Rule, 0)
+	scanner := bufio.NewScanner(strings.NewReader(defaultList))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rules = append(rules, &Rule{Word: fields[0], Action: fields[1]})
+	}
+	return rules
+}
+
+// New 根据规则创建过滤器，规范化后为空的规则被忽略
+// 含汉字的规则同时按拼音匹配，可识别拼音与中英混写，命中的内容须包含字母
+func New(rules []*Rule) *Filter {
+	f := &Filter{rules: rules}
+	var plain, py [][]rune
+	for i, rule := range rules {
+		word := normalize(rule.Word).runes
+		if len(word) == 0 {
+			continue
+		}
+		plain = append(plain, word)
+		f.plainRules = append(f.plainRules, i)
+		// 单字的拼音太短，容易误伤正常内容
+		if len(word) > 1 && hasHan(word) {
+			if p := pinyinOf(word); p != nil {
+				py = append(py, p)
+				f.pinyinRules = append(f.pinyinRules, i)
+			}
+		}
+	}
+	f.plain = newAutomaton(plain)
+	f.pinyin = newAutomaton(py)
+	return f
+}
+
+// Check 检查文本，返回命中的规则与替换后的文本
+func (f *Filter) Check(s string) *Result {
+	res := &Result{Text: s}
+	if s == "" || len(f.rules) == 0 {
+		return res
+	}
+	t := normalize(s)
+	seen := map[[3]int]bool{}
+	collect := func(t *text, a *automaton, rules []int) {
+		for _, m := range a.find(t.runes) {
+			if !t.start[m.start] || !t.end[m.end-1] {
+				continue
+			}
+			// 纯汉字的内容已由原词匹配，拼音只用于识别含拼音或字母的写法，避免同音的正常词语被误伤
+			if t.latin != nil && !anyTrue(t.latin[m.start:m.end]) {
+				continue
+			}
+			rule := rules[m.pattern]
+			key := [3]int{rule, t.pos[m.start], t.pos[m.end-1] + 1}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			res.Matches = append(res.Matches, &Match{Rule: f.rules[rule], Start: key[1], End: key[2]})
+		}
+	}
+	collect(t, f.plain, f.plainRules)
+	collect(t.toPinyin(), f.pinyin, f.pinyinRules)
+	sort.Slice(res.Matches, func(i, j int) bool {
+		if res.Matches[i].Start != res.Matches[j].Start {
+			return res.Matches[i].Start < res.Matches[j].Start
+		}
+		return res.Matches[i].End < res.Matches[j].End
+	})
+
+	masked := false
+	runes := []rune(s)
+	for _, m := range res.Matches {
+		if m.Rule.Action != ActionMask {
+			continue
+		}
+		for i := m.Start; i < m.End; i++ {
+			runes[i] = maskRune
+		}
+		masked = true
+	}
+	if masked {
+		res.Text = string(runes)
+	}
+	return res
+}
+
+func anyTrue(v []bool) bool {
+	for _, b := range v {
+		if b {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocked 是否命中拒绝提交的规则
+func (r *Result) Blocked() bool {
+	return len(r.Words(ActionBlock)) > 0
+}
+
+// Words 命中指定处理方式的规则词，已去重
+func (r *Result) Words(action string) []string {
+	words := make([]string, 0)
+	seen := map[string]bool{}
+	for _, m := range r.Matches {
+		if m.Rule.Action == action && !seen[m.Rule.Word] {
+			seen[m.Rule.Word] = true
+			words = append(words, m.Rule.Word)
+		}
+	}
+	return words
+}
+
+// Load 替换当前使用的规则，正在进行的检查不受影响
+func Load(rules []*Rule) {
+	current.Store(New(rules))
+}
+
+// Check 使用当前规则检查文本，未加载规则时不做过滤
+func Check(s string) *Result {
+	f, ok := current.Load().(*Filter)
+	if !ok {
+		return &Result{Text: s}
+	}
+	return f.Check(s)
+}
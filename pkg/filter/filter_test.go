@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testFilter() *Filter {
+	return New([]*Rule{
+		{ID: 1, Word: "傻逼", Action: ActionMask},
+		{ID: 2, Word: "代开发票", Action: ActionBlock},
+		{ID: 3, Word: "Fuck", Action: ActionMask},
+		{ID: 4, Word: "赌博", Action: ActionFlag},
+		{ID: 5, Word: "  ", Action: ActionBlock},
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "fuck傻逼", Normalize("ＦＵ　ｃｋ *傻-逼!"))
+	assert.Equal(t, "", Normalize("  ,.!"))
+}
+
+func TestCheck(t *testing.T) {
+	f := testFilter()
+
+	res := f.Check("你好世界")
+	assert.Empty(t, res.Matches)
+	assert.Equal(t, "你好世界", res.Text)
+
+	res = f.Check("你这个傻.逼")
+	assert.Equal(t, "你这个***", res.Text)
+	assert.Equal(t, []string{"傻逼"}, res.Words(ActionMask))
+
+	// 全角、大小写与插入的符号
+	assert.Equal(t, "what the *******", f.Check("what the Ｆ_u_C_k").Text)
+
+	// 拼音与中英混写
+	assert.Equal(t, "*****", f.Check("shabi").Text)
+	assert.Equal(t, "a*** b", f.Check("a傻bi b").Text)
+	assert.Equal(t, "****", f.Check("sha笔").Text)
+
+	res = f.Check("专业代 开 发 票")
+	assert.True(t, res.Blocked())
+	assert.Equal(t, []string{"代开发票"}, res.Words(ActionBlock))
+
+	res = f.Check("线上赌博")
+	assert.False(t, res.Blocked())
+	assert.Equal(t, []string{"赌博"}, res.Words(ActionFlag))
+	assert.Equal(t, "线上赌博", res.Text)
+}
+
+func TestCheckSyllableBoundary(t *testing.T) {
+	f := New([]*Rule{{Word: "爱你", Action: ActionMask}})
+	assert.Equal(t, "***", f.Check("ai泥").Text)
+	// "拜你"展开为"baini"，其中的"aini"不是从音节开头开始的，不算命中
+	assert.Empty(t, f.Check("拜你").Matches)
+}
+
+func TestCheckHomophone(t *testing.T) {
+	f := New(DefaultRules())
+	// 纯汉字的同音词不按拼音匹配
+	for _, s := range []string{"见人就笑", "坚忍", "独播剧场", "我在读博", "沙比"} {
+		res := f.Check(s)
+		assert.Empty(t, res.Matches, s)
+		assert.Equal(t, s, res.Text, s)
+	}
+	// 字母只在词首尾或完整切分的音节边界处匹配
+	assert.Empty(t, f.Check("Dubois").Matches)
+	assert.Empty(t, f.Check("Dubois 先生").Matches)
+	assert.Equal(t, []string{"赌博"}, f.Check("dubo").Words(ActionFlag))
+	assert.Equal(t, []string{"赌博"}, f.Check("woyaodubo").Words(ActionFlag))
+	assert.Equal(t, []string{"赌博"}, f.Check("du bo").Words(ActionFlag))
+	assert.Equal(t, []string{"赌博"}, f.Check("赌bo").Words(ActionFlag))
+}
+
+func TestSyllableBounds(t *testing.T) {
+	assert.Equal(t, []bool{true, false, true, false, true}, syllableBounds([]rune("dubo")))
+	assert.Equal(t, []bool{true, false, false, false, false, false, true}, syllableBounds([]rune("dubois")))
+}
+
+func TestGlobal(t *testing.T) {
+	assert.Equal(t, "傻逼", Check("傻逼").Text)
+	Load([]*Rule{{Word: "傻逼", Action: ActionMask}})
+	assert.Equal(t, "**", Check("傻逼").Text)
+}
+
+func TestDefaultRules(t *testing.T) {
+	rules := DefaultRules()
+	assert.NotEmpty(t, rules)
+	for _, rule := range rules {
+		assert.Contains(t, []string{ActionBlock, ActionMask, ActionFlag}, rule.Action, rule.Word)
+	}
+}
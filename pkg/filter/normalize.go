@@ -0,0 +1,200 @@
+package filter
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+)
+
+var (
+	//go:embed pinyin.txt
+	pinyinList string
+
+	//go:embed syllables.txt
+	syllableList string
+
+	pinyin    = loadPinyin(pinyinList)
+	syllables = loadSyllables(syllableList)
+)
+
+// maxSyllable 最长音节的字母数
+const maxSyllable = 6
+
+func loadSyllables(list string) map[string]bool {
+	res := map[string]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, s := range strings.Fields(line) {
+			res[s] = true
+		}
+	}
+	return res
+}
+
+// loadPinyin 读取拼音表，同一个字出现多次时以先出现的读音为准
+func loadPinyin(list string) map[rune]string {
+	res := map[rune]string{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, r := range fields[1] {
+			if _, ok := res[r]; !ok {
+				res[r] = fields[0]
+			}
+		}
+	}
+	return res
+}
+
+// text 规范化后的文本，pos为每个字符在原文中的位置（按rune计）
+// start与end标记可以作为匹配起止的位置，拼音展开后只能从音节首字母开始、到音节末字母结束
+// latin标记拼音展开后不是由汉字展开得到的字符
+type text struct {
+	runes      []rune
+	pos        []int
+	start, end []bool
+	latin      []bool
+}
+
+// foldRune 全角转半角并转为小写，不参与匹配的字符返回false
+// 只保留字母、数字与汉字，空白、标点、符号与零宽字符都被忽略，以识别插入符号的绕过
+func foldRune(r rune) (rune, bool) {
+	switch {
+	case r == '　':
+		return 0, false
+	case r >= '！' && r <= '～':
+		r -= 0xfee0
+	}
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return 0, false
+	}
+	return unicode.ToLower(r), true
+}
+
+// Normalize 规范化文本，用于规则词的去重与比较
+func Normalize(s string) string {
+	return string(normalize(s).runes)
+}
+
+func normalize(s string) *text {
+	t := &text{}
+	i := 0
+	for _, r := range s {
+		if f, ok := foldRune(r); ok {
+			t.runes = append(t.runes, f)
+			t.pos = append(t.pos, i)
+			t.start = append(t.start, true)
+			t.end = append(t.end, true)
+		}
+		i++
+	}
+	return t
+}
+
+// toPinyin 将收录的汉字展开为拼音，其余字符保持不变
+// 原文中连续的字母组成一个词，能完整切分为音节时可以在音节边界处起止，否则只能在词的首尾起止
+func (t *text) toPinyin() *text {
+	p := &text{}
+	for i := 0; i < len(t.runes); {
+		r := t.runes[i]
+		if py, ok := pinyin[r]; ok {
+			for j, c := range py {
+				p.runes = append(p.runes, c)
+				p.pos = append(p.pos, t.pos[i])
+				p.start = append(p.start, j == 0)
+				p.end = append(p.end, j == len(py)-1)
+				p.latin = append(p.latin, false)
+			}
+			i++
+			continue
+		}
+		j := i + 1
+		if isLetter(r) {
+			for j < len(t.runes) && isLetter(t.runes[j]) && t.pos[j] == t.pos[j-1]+1 {
+				j++
+			}
+		}
+		bounds := syllableBounds(t.runes[i:j])
+		for k := i; k < j; k++ {
+			p.runes = append(p.runes, t.runes[k])
+			p.pos = append(p.pos, t.pos[k])
+			p.start = append(p.start, bounds[k-i])
+			p.end = append(p.end, bounds[k-i+1])
+			p.latin = append(p.latin, true)
+		}
+		i = j
+	}
+	return p
+}
+
+// isLetter 未收录拼音的字母，汉字单独成词
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r) && !unicode.Is(unicode.Han, r)
+}
+
+// syllableBounds 词中可以作为匹配边界的位置，下标为字符之间的间隔，首尾总是边界
+// 词能完整切分为拼音音节时，所有可能切分方式中的音节边界同样可用
+func syllableBounds(word []rune) []bool {
+	n := len(word)
+	bounds := make([]bool, n+1)
+	bounds[0], bounds[n] = true, true
+	// forward[i]表示前i个字母可以切分，backward[i]表示从i开始的部分可以切分
+	forward, backward := make([]bool, n+1), make([]bool, n+1)
+	forward[0], backward[n] = true, true
+	for i := 0; i < n; i++ {
+		if !forward[i] {
+			continue
+		}
+		for l := 1; l <= maxSyllable && i+l <= n; l++ {
+			if syllables[string(word[i:i+l])] {
+				forward[i+l] = true
+			}
+		}
+	}
+	if !forward[n] {
+		return bounds
+	}
+	for i := n - 1; i >= 0; i-- {
+		for l := 1; l <= maxSyllable && i+l <= n; l++ {
+			if backward[i+l] && syllables[string(word[i:i+l])] {
+				backward[i] = true
+				break
+			}
+		}
+	}
+	for i := range bounds {
+		bounds[i] = bounds[i] || forward[i] && backward[i]
+	}
+	return bounds
+}
+
+// hasHan 是否包含汉字
+func hasHan(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinyinOf 词的拼音形式，词中有未收录的汉字时返回nil
+func pinyinOf(runes []rune) []rune {
+	res := make([]rune, 0, len(runes)*3)
+	for _, r := range runes {
+		if py, ok := pinyin[r]; ok {
+			res = append(res, []rune(py)...)
+		} else if unicode.Is(unicode.Han, r) {
+			return nil
+		} else {
+			res = append(res, r)
+		}
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+package handle
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	//go:embed reserved.txt
+	reservedList string
+	//go:embed profanity.txt
+	profanityList string
+
+	ErrFormat   = errors.New("handle: invalid format")
+	ErrReserved = errors.New("handle: reserved")
+	ErrProfane  = errors.New("handle: contains banned word")
+
+	pattern   = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	reserved  = loadList(reservedList)
+	profanity = loadList(profanityList)
+
+	// reservedPatterns 系统生成的用户名，例如注销后匿名化的deleted_<id>，按规范形式匹配
+	reservedPatterns = []*regexp.Regexp{regexp.MustCompile(`^deleted[0-9]+$`)}
+
+	// leet 常见的形近替换字符
+	leet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g")
+)
+
+const (
+	MinLength = 3
+	MaxLength = 20
+)
+
+func loadList(list string) []string {
+	res := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		res = append(res, strings.ToLower(line))
+	}
+	return res
+}
+
+// Normalize 比较用的规范形式，忽略大小写与下划线
+func Normalize(handle string) string {
+	return strings.ToLower(strings.ReplaceAll(handle, "_", ""))
+}
+
+// tokens 按下划线与大小写变化将用户名拆分为小写的词，例如Big_BadWolf拆分为big、bad、wolf
+func tokens(handle string) []string {
+	res := make([]string, 0)
+	for _, part := range strings.Split(handle, "_") {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			if unicode.IsLower(rune(part[i-1])) && unicode.IsUpper(rune(part[i])) {
+				res = append(res, strings.ToLower(part[start:i]))
+				start = i
+			}
+		}
+		if start < len(part) {
+			res = append(res, strings.ToLower(part[start:]))
+		}
+	}
+	return res
+}
+
+// profane 词是否为敏感词，数字既可能是替换字符也可能是敏感词本身，也可能是附加的编号，几种形式都检查
+func profane(token string) bool {
+	forms := []string{token, leet.Replace(token), strings.Trim(token, "0123456789")}
+	for _, s := range forms {
+		for _, word := range profanity {
+			if s == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate 检查用户名的格式、保留词与敏感词
+func Validate(handle string) error {
+	if len(handle) < MinLength || len(handle) > MaxLength || !pattern.MatchString(handle) {
+		return ErrFormat
+	}
+	if strings.Trim(handle, "0123456789") == "" || strings.Trim(handle, "_") == "" {
+		return ErrFormat
+	}
+
+	normalized := Normalize(handle)
+	for _, word := range reserved {
+		if normalized == word {
+			return ErrReserved
+		}
+	}
+	for _, p := range reservedPatterns {
+		if p.MatchString(normalized) {
+			return ErrReserved
+		}
+	}
+	// 按整词匹配，避免误伤包含敏感词片段的正常单词，例如Scunthorpe
+	for _, token := range tokens(handle) {
+		if profane(token) {
+			return ErrProfane
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package handle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("alice_01"))
+	assert.NoError(t, Validate("Bob"))
+
+	assert.ErrorIs(t, Validate("ab"), ErrFormat)
+	assert.ErrorIs(t, Validate("abcdefghijklmnopqrstu"), ErrFormat)
+	assert.ErrorIs(t, Validate("bad name"), ErrFormat)
+	assert.ErrorIs(t, Validate("张三abc"), ErrFormat)
+	assert.ErrorIs(t, Validate("123456"), ErrFormat)
+	assert.ErrorIs(t, Validate("___"), ErrFormat)
+
+	assert.ErrorIs(t, Validate("Admin"), ErrReserved)
+	assert.ErrorIs(t, Validate("ad_min"), ErrReserved)
+	assert.ErrorIs(t, Validate("deleted_42"), ErrReserved)
+	assert.ErrorIs(t, Validate("Deleted42"), ErrReserved)
+	assert.NoError(t, Validate("deletedscenes"))
+	assert.NoError(t, Validate("deleted_42x"))
+
+	assert.ErrorIs(t, Validate("fuck"), ErrProfane)
+	assert.ErrorIs(t, Validate("sh1t_happens"), ErrProfane)
+	assert.ErrorIs(t, Validate("sb250"), ErrProfane)
+	assert.ErrorIs(t, Validate("BigDick"), ErrProfane)
+	assert.ErrorIs(t, Validate("cnm123"), ErrProfane)
+
+	// 只按整词匹配
+	assert.NoError(t, Validate("Scunthorpe"))
+	assert.NoError(t, Validate("dickens_fan"))
+	assert.NoError(t, Validate("cnmaker"))
+	assert.NoError(t, Validate("Sussex"))
+}
+
+func TestTokens(t *testing.T) {
+	assert.Equal(t, []string{"big", "bad", "wolf"}, tokens("Big_BadWolf"))
+	assert.Equal(t, []string{"alice01"}, tokens("alice01"))
+	assert.Equal(t, []string{"ab"}, tokens("__ab"))
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "alice01", Normalize("Alice_01"))
+}
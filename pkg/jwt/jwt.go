@@ -1,30 +1,75 @@
 package jwt
 
 import (
-	"github.com/kataras/jwt"
+	"errors"
 	"time"
+
+	"github.com/kataras/jwt"
 )
 
-// sharedKey
 var (
-	sharedKey = []byte("sercrethatmaycontainch@r$32chars")
-	issuer    = "seefs"
+	keyring  = NewKeyring()
+	issuer   = "seefs"
+	audience = ""
+
+	ErrAudience = errors.New("jwt: audience not match")
+	ErrPurpose  = errors.New("jwt: purpose not match")
 )
 
-func SetMeta(secret, publisher string) {
-	sharedKey = []byte(secret)
+// SetMeta set issuer and audience written into and checked against tokens
+func SetMeta(publisher, aud string) {
 	issuer = publisher
+	audience = aud
+}
+
+// SetKeyring replace the keyring used to sign and verify tokens
+func SetKeyring(k *Keyring) {
+	keyring = k
+}
+
+// DefaultKeyring the keyring used to sign and verify tokens
+func DefaultKeyring() *Keyring {
+	return keyring
 }
 
 // UserClaims ...
 type UserClaims struct {
-	UID int64 `json:"uid"`
+	UID int64  `json:"uid"`
+	SID string `json:"sid,omitempty"` // session id
+	Pur string `json:"pur,omitempty"` // purpose of a challenge token, empty for access tokens
 }
 
 // GenerateToken generate token by userID
 func GenerateToken(uid int64, expire time.Duration) ([]byte, error) {
+	return GenerateSessionToken(uid, "", expire)
+}
+
+// GenerateSessionToken generate token by userID bound to a login session
+func GenerateSessionToken(uid int64, sid string, expire time.Duration) ([]byte, error) {
+	userClaims := UserClaims{
+		UID: uid,
+		SID: sid,
+	}
+
+	now := time.Now()
+	standardClaims := jwt.Claims{
+		Expiry:   now.Add(expire).Unix(),
+		IssuedAt: now.Unix(),
+		Issuer:   issuer,
+	}
+	if audience != "" {
+		standardClaims.Audience = jwt.Audience{audience}
+	}
+
+	return keyring.Sign(userClaims, standardClaims)
+}
+
+// GenerateChallengeToken generate a short-lived token for an unfinished login step, e.g. 2FA
+// it carries no session id, so it is rejected wherever an access token is required
+func GenerateChallengeToken(uid int64, purpose string, expire time.Duration) ([]byte, error) {
 	userClaims := UserClaims{
 		UID: uid,
+		Pur: purpose,
 	}
 
 	now := time.Now()
@@ -33,12 +78,23 @@ func GenerateToken(uid int64, expire time.Duration) ([]byte, error) {
 		IssuedAt: now.Unix(),
 		Issuer:   issuer,
 	}
+	if audience != "" {
+		standardClaims.Audience = jwt.Audience{audience}
+	}
 
-	token, err := jwt.Sign(jwt.HS256, sharedKey, userClaims, standardClaims)
+	return keyring.Sign(userClaims, standardClaims)
+}
+
+// ParseChallengeToken parse a challenge token and check its purpose
+func ParseChallengeToken(token []byte, purpose string) (UserClaims, error) {
+	claims, err := ParseToken(token)
 	if err != nil {
-		return nil, err
+		return UserClaims{}, err
 	}
-	return token, err
+	if claims.Pur != purpose || claims.SID != "" {
+		return UserClaims{}, ErrPurpose
+	}
+	return claims, nil
 }
 
 // GenerateTokenWithoutExpire generate token by userID without expire time
@@ -46,18 +102,19 @@ func GenerateTokenWithoutExpire(uid int64) ([]byte, error) {
 	userClaims := UserClaims{
 		UID: uid,
 	}
-
-	token, err := jwt.Sign(jwt.HS256, sharedKey, userClaims)
-	if err != nil {
-		return nil, err
+	standardClaims := jwt.Claims{
+		Issuer: issuer,
+	}
+	if audience != "" {
+		standardClaims.Audience = jwt.Audience{audience}
 	}
 
-	return token, err
+	return keyring.Sign(userClaims, standardClaims)
 }
 
-// ParseToken parse token
+// ParseToken parse token, checking signature, expiry, issuer and audience
 func ParseToken(token []byte) (UserClaims, error) {
-	verify, err := jwt.Verify(jwt.HS256, sharedKey, token)
+	verify, err := keyring.Verify(token, jwt.TokenValidatorFunc(validateMeta))
 	if err != nil {
 		return UserClaims{}, err
 	}
@@ -67,3 +124,22 @@ func ParseToken(token []byte) (UserClaims, error) {
 
 	return claims, err
 }
+
+// validateMeta 校验iss与aud，aud中包含本服务即可
+func validateMeta(token []byte, c jwt.Claims, err error) error {
+	if err != nil {
+		return err
+	}
+	if c.Issuer != issuer {
+		return jwt.ErrExpected
+	}
+	if audience == "" {
+		return nil
+	}
+	for _, aud := range c.Audience {
+		if aud == audience {
+			return nil
+		}
+	}
+	return ErrAudience
+}
@@ -1,33 +1,126 @@
 package jwt
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
 )
 
+func setupKeyring(t *testing.T) *Keyring {
+	k := NewKeyring()
+	key, err := NewKey("hs", "HS256", []byte("sercrethatmaycontainch@r$32chars"), nil)
+	assert.NoError(t, err)
+	k.Add(key)
+	assert.NoError(t, k.Activate("hs"))
+	SetKeyring(k)
+	SetMeta("vid.srv.auth", "")
+	return k
+}
+
 func TestGenerateToken(t *testing.T) {
-	userID := 1
+	setupKeyring(t)
+	var userID int64 = 1
 	token, err := GenerateToken(userID, 3*time.Hour)
-	if err != nil {
-		assert.Error(t, err)
-	}
+	assert.NoError(t, err)
 	newUserClaim, err := ParseToken(token)
-	if err != nil {
-		assert.Error(t, err)
-	}
-	assert.Equal(t, newUserClaim.UID, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, newUserClaim.UID)
 }
 
 func TestGenerateTokenWithoutExpire(t *testing.T) {
-	userID := 1
+	setupKeyring(t)
+	var userID int64 = 1
 	token, err := GenerateTokenWithoutExpire(userID)
-	if err != nil {
-		assert.Error(t, err)
-	}
+	assert.NoError(t, err)
 	newUserClaim, err := ParseToken(token)
-	if err != nil {
-		assert.Error(t, err)
-	}
-	assert.Equal(t, newUserClaim.UID, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, newUserClaim.UID)
+}
+
+func TestKeyRotation(t *testing.T) {
+	k := setupKeyring(t)
+	old, err := GenerateSessionToken(1, "sid", time.Hour)
+	assert.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := NewKey("rs", "RS256", rsaKey, nil)
+	assert.NoError(t, err)
+	k.Add(key)
+	assert.NoError(t, k.Activate("rs"))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key, err = NewKey("ed", "EdDSA", edKey, nil)
+	assert.NoError(t, err)
+	k.Add(key)
+
+	// 新旧密钥签发的token都能校验
+	fresh, err := GenerateSessionToken(2, "sid", time.Hour)
+	assert.NoError(t, err)
+	claims, err := ParseToken(fresh)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), claims.UID)
+	claims, err = ParseToken(old)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UID)
+
+	assert.NoError(t, k.Activate("ed"))
+	ed, err := GenerateSessionToken(3, "sid", time.Hour)
+	assert.NoError(t, err)
+	claims, err = ParseToken(ed)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), claims.UID)
+
+	// 退役后旧token失效，当前签发密钥不能退役
+	assert.NoError(t, k.Retire("hs"))
+	_, err = ParseToken(old)
+	assert.Error(t, err)
+	assert.Equal(t, ErrKeyActive, k.Retire("ed"))
+
+	jwks := k.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+}
+
+func TestIssuerAndAudience(t *testing.T) {
+	setupKeyring(t)
+	SetMeta("vid.srv.auth", "vid.api")
+	token, err := GenerateToken(1, time.Hour)
+	assert.NoError(t, err)
+	_, err = ParseToken(token)
+	assert.NoError(t, err)
+
+	SetMeta("vid.srv.auth", "other.api")
+	_, err = ParseToken(token)
+	assert.Equal(t, ErrAudience, err)
+
+	SetMeta("other.issuer", "vid.api")
+	_, err = ParseToken(token)
+	assert.Error(t, err)
+}
+
+func TestChallengeToken(t *testing.T) {
+	setupKeyring(t)
+	token, err := GenerateChallengeToken(1, "2fa", time.Minute)
+	assert.NoError(t, err)
+
+	claims, err := ParseChallengeToken(token, "2fa")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UID)
+	assert.Empty(t, claims.SID)
+
+	_, err = ParseChallengeToken(token, "reset")
+	assert.Equal(t, ErrPurpose, err)
+
+	// 普通令牌不能当作挑战令牌使用
+	access, err := GenerateSessionToken(1, "sid", time.Minute)
+	assert.NoError(t, err)
+	_, err = ParseChallengeToken(access, "2fa")
+	assert.Equal(t, ErrPurpose, err)
 }
@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"sync"
+
+	"github.com/kataras/jwt"
+)
+
+var (
+	ErrNoActiveKey  = errors.New("jwt: no active signing key")
+	ErrNoPrivateKey = errors.New("jwt: key has no private part")
+	ErrKeyActive    = errors.New("jwt: cannot retire the active key")
+	ErrUnknownAlg   = errors.New("jwt: unsupported algorithm")
+)
+
+// Keyring 按kid管理签名密钥，active用于签发，其余密钥仅用于校验直到被移除
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   jwt.Keys
+	active string
+}
+
+// NewKeyring create an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: jwt.Keys{}}
+}
+
+// Add 添加密钥，已存在的kid会被覆盖
+func (k *Keyring) Add(key *jwt.Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key.ID] = key
+}
+
+// Activate 指定用于签发的密钥，旧的密钥仍可校验
+func (k *Keyring) Activate(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys.Get(kid)
+	if !ok {
+		return jwt.ErrUnknownKid
+	}
+	if key.Private == nil {
+		return ErrNoPrivateKey
+	}
+	k.active = kid
+	return nil
+}
+
+// Retire 移除密钥，由其签发的token随即失效
+func (k *Keyring) Retire(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if kid == k.active {
+		return ErrKeyActive
+	}
+	delete(k.keys, kid)
+	return nil
+}
+
+// Active 当前签发密钥的kid
+func (k *Keyring) Active() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// Sign 使用当前签发密钥签名，header中带有kid
+func (k *Keyring) Sign(claims interface{}, opts ...jwt.SignOption) ([]byte, error) {
+	k.mu.RLock()
+	key, ok := k.keys.Get(k.active)
+	k.mu.RUnlock()
+	if !ok {
+		return nil, ErrNoActiveKey
+	}
+	return jwt.SignWithHeader(key.Alg, key.Private, claims, jwt.HeaderWithKid{
+		Kid: key.ID,
+		Alg: key.Alg.Name(),
+	}, opts...)
+}
+
+// Verify 按header中的kid选择密钥校验token
+func (k *Keyring) Verify(token []byte, validators ...jwt.TokenValidator) (*jwt.VerifiedToken, error) {
+	return jwt.VerifyWithHeaderValidator(nil, nil, token, k.validateHeader, validators...)
+}
+
+func (k *Keyring) validateHeader(alg string, header []byte) (jwt.Alg, jwt.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys.ValidateHeader(alg, header)
+}
+
+// NewKey 由已解析的密钥构造，private为nil时仅可用于校验
+func NewKey(kid, alg string, private jwt.PrivateKey, public jwt.PublicKey) (*jwt.Key, error) {
+	a, err := parseAlg(alg)
+	if err != nil {
+		return nil, err
+	}
+	if public == nil {
+		switch p := private.(type) {
+		case *rsa.PrivateKey:
+			public = &p.PublicKey
+		case ed25519.PrivateKey:
+			public = p.Public().(ed25519.PublicKey)
+		case []byte:
+			public = p
+		}
+	}
+	return &jwt.Key{ID: kid, Alg: a, Public: public, Private: private}, nil
+}
+
+// LoadKey 从PEM文件加载密钥，HS256使用secret，RS256/EdDSA的公钥缺省时由私钥导出
+func LoadKey(kid, alg, privateFile, publicFile, secret string) (*jwt.Key, error) {
+	var private jwt.PrivateKey
+	var public jwt.PublicKey
+	var err error
+
+	switch alg {
+	case "HS256":
+		if secret == "" {
+			return nil, errors.New("jwt: empty secret for key " + kid)
+		}
+		private = []byte(secret)
+	case "RS256":
+		if privateFile != "" {
+			if private, err = jwt.LoadPrivateKeyRSA(privateFile); err != nil {
+				return nil, err
+			}
+		}
+		if publicFile != "" {
+			if public, err = jwt.LoadPublicKeyRSA(publicFile); err != nil {
+				return nil, err
+			}
+		}
+	case "EdDSA":
+		if privateFile != "" {
+			if private, err = jwt.LoadPrivateKeyEdDSA(privateFile); err != nil {
+				return nil, err
+			}
+		}
+		if publicFile != "" {
+			if public, err = jwt.LoadPublicKeyEdDSA(publicFile); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, ErrUnknownAlg
+	}
+	if private == nil && public == nil {
+		return nil, errors.New("jwt: no key material for key " + kid)
+	}
+	return NewKey(kid, alg, private, public)
+}
+
+func parseAlg(alg string) (jwt.Alg, error) {
+	switch alg {
+	case "HS256":
+		return jwt.HS256, nil
+	case "RS256":
+		return jwt.RS256, nil
+	case "EdDSA":
+		return jwt.EdDSA, nil
+	}
+	return nil, ErrUnknownAlg
+}
@@ -0,0 +1,97 @@
+package lockout
+
+import (
+	"time"
+)
+
+// Store 失败次数与锁定时间的存储
+type Store interface {
+	// Get 返回key的失败次数与锁定截止时间(unix毫秒)
+	Get(key string, now time.Time) (count int64, lockedUntil int64, err error)
+	// Fail 记录一次失败，失败次数达到threshold后按指数退避锁定，返回新的失败次数与锁定截止时间
+	Fail(key string, now time.Time, p *Policy) (count int64, lockedUntil int64, err error)
+	// Reset 清除key的记录
+	Reset(key string) error
+}
+
+// Policy 锁定策略，第Threshold次失败锁定Base，此后每次失败锁定时间翻倍，最长Max
+// 距最近一次失败超过Window后记录自动清除
+type Policy struct {
+	Threshold int64
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// backoff 第count次失败后的锁定时长
+func (p *Policy) backoff(count int64) time.Duration {
+	if count < p.Threshold {
+		return 0
+	}
+	d := p.Base
+	for i := p.Threshold; i < count && d < p.Max; i++ {
+		d *= 2
+	}
+	if d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+// ttl 记录的保留时长，至少覆盖锁定时间
+func (p *Policy) ttl(lock time.Duration) time.Duration {
+	if lock > p.Window {
+		return lock
+	}
+	return p.Window
+}
+
+// Limiter 按key统计失败并锁定
+type Limiter struct {
+	prefix string
+	policy *Policy
+	store  Store
+	now    func() time.Time
+}
+
+// New create limiter, keys are namespaced by prefix
+func New(prefix string, policy *Policy, store Store) *Limiter {
+	return &Limiter{prefix: prefix, policy: policy, store: store, now: time.Now}
+}
+
+// Check 返回key剩余的锁定时间，未锁定时为0
+func (l *Limiter) Check(key string) (time.Duration, error) {
+	_, until, err := l.store.Get(l.prefix+key, l.now())
+	if err != nil {
+		return 0, err
+	}
+	return l.remaining(until), nil
+}
+
+// Count 返回key当前的失败次数
+func (l *Limiter) Count(key string) (int64, error) {
+	count, _, err := l.store.Get(l.prefix+key, l.now())
+	return count, err
+}
+
+// Fail 记录一次失败，返回剩余的锁定时间，locked表示本次失败是否使key首次进入锁定
+func (l *Limiter) Fail(key string) (retryAfter time.Duration, locked bool, err error) {
+	count, until, err := l.store.Fail(l.prefix+key, l.now(), l.policy)
+	if err != nil {
+		return 0, false, err
+	}
+	return l.remaining(until), count == l.policy.Threshold, nil
+}
+
+// Reset 登录成功或解锁后清除记录
+func (l *Limiter) Reset(key string) error {
+	return l.store.Reset(l.prefix + key)
+}
+
+func (l *Limiter) remaining(until int64) time.Duration {
+	d := time.Duration(until-l.now().UnixNano()/int64(time.Millisecond)) * time.Millisecond
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,75 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	p := &Policy{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute}
+	cases := map[int64]time.Duration{
+		1: 0,
+		2: 0,
+		3: time.Minute,
+		4: 2 * time.Minute,
+		5: 4 * time.Minute,
+		6: 8 * time.Minute,
+		7: 10 * time.Minute,
+		9: 10 * time.Minute,
+	}
+	for count, want := range cases {
+		assert.Equal(t, want, p.backoff(count), count)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1614600000, 0)
+	l := New("login:", &Policy{Threshold: 3, Base: time.Minute, Max: time.Hour, Window: time.Hour}, NewMemoryStore())
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		retry, locked, err := l.Fail("tom")
+		assert.NoError(t, err)
+		assert.False(t, locked)
+		assert.Zero(t, retry)
+	}
+	count, _ := l.Count("tom")
+	assert.Equal(t, int64(2), count)
+	retry, locked, err := l.Fail("tom")
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	assert.Equal(t, time.Minute, retry)
+
+	retry, _ = l.Check("tom")
+	assert.Equal(t, time.Minute, retry)
+	retry, _ = l.Check("jerry")
+	assert.Zero(t, retry)
+
+	// 锁定期过后再次失败，锁定时间翻倍
+	now = now.Add(time.Minute)
+	retry, _ = l.Check("tom")
+	assert.Zero(t, retry)
+	retry, locked, _ = l.Fail("tom")
+	assert.Equal(t, 2*time.Minute, retry)
+	assert.False(t, locked)
+
+	assert.NoError(t, l.Reset("tom"))
+	retry, _ = l.Check("tom")
+	assert.Zero(t, retry)
+}
+
+func TestMemoryStoreExpire(t *testing.T) {
+	now := time.Unix(1614600000, 0)
+	p := &Policy{Threshold: 3, Base: time.Minute, Max: time.Hour, Window: 10 * time.Minute}
+	s := NewMemoryStore()
+
+	s.Fail("k", now, p)
+	count, _, _ := s.Fail("k", now, p)
+	assert.Equal(t, int64(2), count)
+
+	// 超过窗口后重新计数
+	count, _, _ = s.Fail("k", now.Add(11*time.Minute), p)
+	assert.Equal(t, int64(1), count)
+}
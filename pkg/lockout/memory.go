@@ -0,0 +1,74 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore 进程内存储，redis不可用时使用，多实例部署时各实例分别计数
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	ops     int
+}
+
+type entry struct {
+	count       int64
+	lockedUntil int64 // unix毫秒
+	expires     time.Time
+}
+
+// sweepEvery 每隔若干次写操作清理一次过期记录
+const sweepEvery = 1024
+
+// NewMemoryStore ...
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*entry{}}
+}
+
+// Get ...
+func (m *MemoryStore) Get(key string, now time.Time) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expires) {
+		return 0, 0, nil
+	}
+	return e.count, e.lockedUntil, nil
+}
+
+// Fail ...
+func (m *MemoryStore) Fail(key string, now time.Time, p *Policy) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ops++
+	if m.ops%sweepEvery == 0 {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expires) {
+		e = &entry{}
+		m.entries[key] = e
+	}
+	e.count++
+	lock := p.backoff(e.count)
+	if lock > 0 {
+		e.lockedUntil = now.Add(lock).UnixNano() / int64(time.Millisecond)
+	}
+	e.expires = now.Add(p.ttl(lock))
+	return e.count, e.lockedUntil, nil
+}
+
+// Reset ...
+func (m *MemoryStore) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
@@ -0,0 +1,64 @@
+package lockout
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// RedisStore 基于redis的存储，多实例共享计数
+type RedisStore struct{}
+
+// failScript 原子地增加失败次数并计算锁定时间
+// KEYS[1] key; ARGV: now(ms) threshold base(ms) max(ms) window(ms)
+const failScript = `
+local count = redis.call('HINCRBY', KEYS[1], 'count', 1)
+local now, threshold, base, max, window = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4]), tonumber(ARGV[5])
+local lock = 0
+if count >= threshold then
+	lock = base
+	for i = threshold + 1, count do
+		if lock >= max then break end
+		lock = lock * 2
+	end
+	if lock > max then lock = max end
+	redis.call('HSET', KEYS[1], 'locked_until', now + lock)
+end
+if lock > window then window = lock end
+redis.call('PEXPIRE', KEYS[1], window)
+local locked = redis.call('HGET', KEYS[1], 'locked_until')
+return {count, tonumber(locked or '0')}`
+
+// Get ...
+func (r *RedisStore) Get(key string, now time.Time) (int64, int64, error) {
+	values, err := redis.HGetAll(key)
+	if err != nil {
+		return 0, 0, err
+	}
+	count, _ := strconv.ParseInt(values["count"], 10, 64)
+	until, _ := strconv.ParseInt(values["locked_until"], 10, 64)
+	return count, until, nil
+}
+
+// Fail ...
+func (r *RedisStore) Fail(key string, now time.Time, p *Policy) (int64, int64, error) {
+	ms := func(d time.Duration) int64 { return int64(d / time.Millisecond) }
+	res, err := redis.Eval(failScript, []string{key},
+		now.UnixNano()/int64(time.Millisecond), p.Threshold, ms(p.Base), ms(p.Max), ms(p.Window))
+	if err != nil {
+		return 0, 0, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, redis.Nil
+	}
+	count, _ := values[0].(int64)
+	until, _ := values[1].(int64)
+	return count, until, nil
+}
+
+// Reset ...
+func (r *RedisStore) Reset(key string) error {
+	return redis.Delete(key)
+}
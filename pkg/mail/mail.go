@@ -0,0 +1,123 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Message 邮件内容，Text与HTML至少提供一个
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender 邮件发送器
+type Sender interface {
+	Send(msg *Message) error
+}
+
+var (
+	sender Sender
+
+	ErrNoRecipient = errors.New("mail: no recipient")
+	ErrNoBody      = errors.New("mail: empty body")
+)
+
+// Init set the default sender
+func Init(s Sender) {
+	sender = s
+}
+
+// Enabled whether a default sender has been set
+func Enabled() bool {
+	return sender != nil
+}
+
+// Send send message with the default sender
+func Send(msg *Message) error {
+	if sender == nil {
+		return errors.New("mail: sender is not initialized")
+	}
+	return sender.Send(msg)
+}
+
+// build 构造MIME邮件，同时提供text与html时使用multipart/alternative
+func (m *Message) build(from string) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, ErrNoRecipient
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, ErrNoBody
+	}
+	for _, to := range m.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	header := func(k, v string) { fmt.Fprintf(buf, "%s: %s\r\n", k, v) }
+	header("From", from)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if m.Text == "" || m.HTML == "" {
+		contentType, body := "text/plain", m.Text
+		if m.HTML != "" {
+			contentType, body = "text/html", m.HTML
+		}
+		header("Content-Type", contentType+"; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		return buf.Bytes(), writeQP(buf, body)
+	}
+
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		fmt.Fprintf(buf, "--%s\r\n", boundary)
+		fmt.Fprintf(buf, "Content-Type: %s; charset=UTF-8\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQP(buf, part.body); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+func writeQP(buf *bytes.Buffer, body string) error {
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func randomBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
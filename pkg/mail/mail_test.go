@@ -0,0 +1,150 @@
+package mail
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP 进程内的最小SMTP服务器，记录收到的信封与邮件内容
+type fakeSMTP struct {
+	listener net.Listener
+	from     string
+	rcpt     []string
+	data     chan string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{listener: l, data: make(chan string, 1)}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = address(line[len("MAIL FROM:"):])
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt = append(s.rcpt, address(line[len("RCPT TO:"):]))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				body.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data <- body.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// address 取出<addr>中的地址，忽略BODY=8BITMIME等参数
+func address(arg string) string {
+	arg = strings.TrimSpace(arg)
+	if i := strings.Index(arg, ">"); i >= 0 {
+		arg = arg[:i]
+	}
+	return strings.TrimPrefix(arg, "<")
+}
+
+func TestSMTPSender(t *testing.T) {
+	server := newFakeSMTP(t)
+	sender := &SMTPSender{Host: "127.0.0.1", Port: server.port(), From: "noreply@vid.test", Name: "Vid"}
+
+	msg, err := Render("user@vid.test", "验证你的 Vid 邮箱", "verify_email", map[string]interface{}{
+		"Nickname":      "<tom>",
+		"Link":          "http://vid.test/verify-email?token=abc&x=1",
+		"ExpireMinutes": 60,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sender.Send(msg))
+
+	assert.Equal(t, "noreply@vid.test", server.from)
+	assert.Equal(t, []string{"user@vid.test"}, server.rcpt)
+
+	raw := <-server.data
+	m, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "验证你的 Vid 邮箱", subject)
+
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	parts := multipart.NewReader(m.Body, params["boundary"])
+
+	text, err := parts.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=UTF-8", text.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(text)
+	assert.Contains(t, string(body), "<tom>，你好")
+	assert.Contains(t, string(body), "token=abc&x=1")
+
+	html, err := parts.NextPart()
+	require.NoError(t, err)
+	body, _ = io.ReadAll(html)
+	assert.Contains(t, string(body), "&lt;tom&gt;")
+	assert.Contains(t, string(body), "token=abc&amp;x=1")
+}
+
+func TestMessageBuild(t *testing.T) {
+	_, err := (&Message{Subject: "x", Text: "x"}).build("a@vid.test")
+	assert.Equal(t, ErrNoRecipient, err)
+	_, err = (&Message{To: []string{"b@vid.test"}}).build("a@vid.test")
+	assert.Equal(t, ErrNoBody, err)
+	_, err = (&Message{To: []string{"not an address"}, Text: "x"}).build("a@vid.test")
+	assert.Error(t, err)
+
+	raw, err := (&Message{To: []string{"b@vid.test"}, Subject: "hi", Text: "hello"}).build("a@vid.test")
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "Content-Type: text/plain; charset=UTF-8\r\n")
+	assert.NotContains(t, string(raw), "multipart")
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+)
+
+// Render 使用templates目录下的name.html与name.txt渲染邮件正文
+func Render(to, subject, name string, data interface{}) (*Message, error) {
+	msg := &Message{To: []string{to}, Subject: subject}
+
+	buf := &bytes.Buffer{}
+	if err := textTemplates.ExecuteTemplate(buf, name+".txt", data); err != nil {
+		return nil, err
+	}
+	msg.Text = buf.String()
+
+	buf.Reset()
+	if err := htmlTemplates.ExecuteTemplate(buf, name+".html", data); err != nil {
+		return nil, err
+	}
+	msg.HTML = buf.String()
+	return msg, nil
+}
@@ -0,0 +1,194 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Identity 外部身份提供方返回的用户信息
+type Identity struct {
+	Provider      string
+	Subject       string // 提供方内的唯一用户ID
+	Username      string // 提供方内的用户名，可能为空
+	Name          string
+	Email         string
+	EmailVerified bool
+	Avatar        string
+}
+
+// Provider 外部身份提供方
+type Provider interface {
+	// Name 提供方名称，如github
+	Name() string
+	// AuthCodeURL 用户授权页地址，challenge为PKCE的S256 code challenge
+	AuthCodeURL(state, challenge, nonce string) string
+	// Exchange 用授权码换取令牌并获取用户信息
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+var (
+	ErrUnknownProvider = errors.New("oauth: unknown provider")
+	ErrToken           = errors.New("oauth: token exchange failed")
+	ErrIDToken         = errors.New("oauth: invalid id token")
+
+	providers = map[string]Provider{}
+	mu        sync.RWMutex
+
+	// HTTPClient 访问提供方使用的客户端
+	HTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Register register a provider
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get get provider by name
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if p, ok := providers[name]; ok {
+		return p, nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+// Names names of registered providers
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RandomString 生成用于state、nonce与PKCE verifier的随机串
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// S256Challenge 计算PKCE的S256 code challenge
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Endpoint OAuth2端点与客户端凭据
+type Endpoint struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	RedirectURL  string
+	Scopes       []string
+}
+
+func (e *Endpoint) authCodeURL(state, challenge string, extra url.Values) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", e.ClientID)
+	v.Set("redirect_uri", e.RedirectURL)
+	v.Set("scope", strings.Join(e.Scopes, " "))
+	v.Set("state", state)
+	v.Set("code_challenge", challenge)
+	v.Set("code_challenge_method", "S256")
+	for k := range extra {
+		v.Set(k, extra.Get(k))
+	}
+	sep := "?"
+	if strings.Contains(e.AuthURL, "?") {
+		sep = "&"
+	}
+	return e.AuthURL + sep + v.Encode()
+}
+
+// tokenResponse 令牌端点的响应
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// exchange 用授权码与PKCE verifier换取令牌
+func (e *Endpoint) exchange(ctx context.Context, code, verifier string) (*tokenResponse, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", e.RedirectURL)
+	v.Set("client_id", e.ClientID)
+	v.Set("client_secret", e.ClientSecret)
+	v.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.TokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	token := &tokenResponse{}
+	if err := doJSON(req, token); err != nil {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrToken, token.Error, token.Description)
+	}
+	if token.AccessToken == "" {
+		return nil, ErrToken
+	}
+	return token, nil
+}
+
+// doJSON 发送请求并解析JSON响应
+func doJSON(req *http.Request, v interface{}) error {
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	// 令牌端点出错时返回400与error字段，交给调用方解析
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("oauth: %s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// getJSON GET请求JSON资源
+func getJSON(ctx context.Context, rawURL, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return doJSON(req, v)
+}
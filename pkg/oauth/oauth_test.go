@@ -0,0 +1,208 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDC 本地的OIDC提供方，实现discovery、JWKS与带PKCE校验的令牌端点
+type mockOIDC struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]url.Values  // code -> 授权请求参数
+	claims map[string]interface{} // 覆盖ID token中的声明
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := &mockOIDC{key: key, codes: map[string]url.Values{}, claims: map[string]interface{}{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		auth, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+		if !ok || r.PostForm.Get("client_secret") != "secret" ||
+			S256Challenge(r.PostForm.Get("code_verifier")) != auth.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := map[string]interface{}{
+			"iss": m.URL, "sub": "u-1", "aud": auth.Get("client_id"),
+			"exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix(),
+			"nonce": auth.Get("nonce"), "email": "tom@vid.test", "email_verified": true,
+			"name": "Tom", "preferred_username": "tom",
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "at", "token_type": "Bearer", "id_token": m.sign(claims),
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockOIDC) sign(claims map[string]interface{}) string {
+	seg := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := seg(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"}) + "." + seg(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, sum[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// authorize 模拟用户在授权页同意授权，返回授权码
+func (m *mockOIDC) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := "code-" + u.Query().Get("state")
+	m.codes[code] = u.Query()
+	return code
+}
+
+func login(t *testing.T, m *mockOIDC, p Provider) (*Identity, error) {
+	verifier, _ := RandomString()
+	nonce, _ := RandomString()
+	state, _ := RandomString()
+	code := m.authorize(t, p.AuthCodeURL(state, S256Challenge(verifier), nonce))
+	return p.Exchange(context.Background(), code, verifier, nonce)
+}
+
+func TestOIDCProvider(t *testing.T) {
+	m := newMockOIDC(t)
+	p, err := NewOIDCProvider(context.Background(), "mock", m.URL, "vid", "secret", "http://vid.test/cb", []string{"email"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openid", "email"}, p.Scopes)
+
+	u, _ := url.Parse(p.AuthCodeURL("s", "c", "n"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	assert.Equal(t, "openid email", u.Query().Get("scope"))
+
+	identity, err := login(t, m, p)
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{
+		Provider: "mock", Subject: "u-1", Username: "tom", Name: "Tom",
+		Email: "tom@vid.test", EmailVerified: true,
+	}, identity)
+
+	// PKCE verifier不匹配
+	state, _ := RandomString()
+	code := m.authorize(t, p.AuthCodeURL(state, S256Challenge("right"), "n"))
+	_, err = p.Exchange(context.Background(), code, "wrong", "n")
+	assert.ErrorIs(t, err, ErrToken)
+
+	// nonce不匹配
+	code = m.authorize(t, p.AuthCodeURL(state, S256Challenge("v"), "n"))
+	_, err = p.Exchange(context.Background(), code, "v", "other")
+	assert.Equal(t, ErrIDToken, err)
+
+	for name, claims := range map[string]map[string]interface{}{
+		"aud":     {"aud": "other"},
+		"iss":     {"iss": "http://evil.test"},
+		"expired": {"exp": time.Now().Add(-time.Hour).Unix()},
+		"azp":     {"aud": []string{"vid", "other"}},
+	} {
+		m.claims = claims
+		_, err = login(t, m, p)
+		assert.Equal(t, ErrIDToken, err, name)
+	}
+	m.claims = map[string]interface{}{}
+
+	// 签名被篡改
+	_, err = p.VerifyIDToken(context.Background(), m.sign(map[string]interface{}{"iss": m.URL})+"x", "")
+	assert.Equal(t, ErrIDToken, err)
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockOIDC(t)
+	_, err := NewOIDCProvider(context.Background(), "mock", m.URL+"/", "vid", "secret", "", nil)
+	assert.Error(t, err)
+}
+
+func TestGitHubProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") != "ok" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "gho", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gho", r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "login": "octo", "name": "Octo", "email": "public@vid.test"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"email": "other@vid.test", "primary": false, "verified": true},
+			{"email": "octo@vid.test", "primary": true, "verified": true},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	p := NewGitHubProvider("github", "id", "secret", "http://vid.test/cb",
+		srv.URL+"/login/oauth/authorize", srv.URL+"/login/oauth/access_token", srv.URL, nil)
+	identity, err := p.Exchange(context.Background(), "ok", "v", "")
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{
+		Provider: "github", Subject: "42", Username: "octo", Name: "Octo",
+		Email: "octo@vid.test", EmailVerified: true,
+	}, identity)
+
+	_, err = p.Exchange(context.Background(), "bad", "v", "")
+	assert.ErrorIs(t, err, ErrToken)
+}
+
+func TestRegistry(t *testing.T) {
+	Register(NewGitHubProvider("gh-test", "id", "secret", "", "", "", "", nil))
+	p, err := Get("gh-test")
+	require.NoError(t, err)
+	assert.Equal(t, "gh-test", p.Name())
+	assert.Contains(t, Names(), "gh-test")
+	_, err = Get("none")
+	assert.Equal(t, ErrUnknownProvider, err)
+}
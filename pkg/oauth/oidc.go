@@ -0,0 +1,340 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512" // RS512
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCProvider 通用OpenID Connect提供方，端点通过discovery获取
+type OIDCProvider struct {
+	Endpoint
+	name     string
+	issuer   string
+	userInfo string
+	jwksURI  string
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// discovery /.well-known/openid-configuration中用到的字段
+type discovery struct {
+	Issuer           string `json:"issuer"`
+	AuthEndpoint     string `json:"authorization_endpoint"`
+	TokenEndpoint    string `json:"token_endpoint"`
+	UserInfoEndpoint string `json:"userinfo_endpoint"`
+	JWKSURI          string `json:"jwks_uri"`
+}
+
+// jwksRefetchInterval 遇到未知kid时重新获取JWKS的最小间隔，避免被利用放大请求
+const jwksRefetchInterval = time.Minute
+
+// clockSkew 校验exp、iat时容忍的时钟误差
+const clockSkew = time.Minute
+
+// NewOIDCProvider 通过discovery创建提供方，scopes中总会包含openid
+func NewOIDCProvider(ctx context.Context, name, issuer, clientID, clientSecret, redirectURL string, scopes []string) (*OIDCProvider, error) {
+	d := &discovery{}
+	wellKnown := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, wellKnown, "", d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != issuer {
+		return nil, fmt.Errorf("oauth: issuer %q does not match discovery %q", issuer, d.Issuer)
+	}
+	if d.AuthEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oauth: incomplete discovery document")
+	}
+
+	hasOpenID := false
+	for _, s := range scopes {
+		hasOpenID = hasOpenID || s == "openid"
+	}
+	if !hasOpenID {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return &OIDCProvider{
+		Endpoint: Endpoint{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			AuthURL:      d.AuthEndpoint,
+			TokenURL:     d.TokenEndpoint,
+			RedirectURL:  redirectURL,
+			Scopes:       scopes,
+		},
+		name:     name,
+		issuer:   d.Issuer,
+		userInfo: d.UserInfoEndpoint,
+		jwksURI:  d.JWKSURI,
+	}, nil
+}
+
+// Name ...
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL ...
+func (p *OIDCProvider) AuthCodeURL(state, challenge, nonce string) string {
+	return p.authCodeURL(state, challenge, url.Values{"nonce": {nonce}})
+}
+
+// idTokenClaims ID token中用到的声明
+type idTokenClaims struct {
+	Issuer            string      `json:"iss"`
+	Subject           string      `json:"sub"`
+	Audience          interface{} `json:"aud"` // 字符串或字符串数组
+	AuthorizedParty   string      `json:"azp"`
+	Expiry            int64       `json:"exp"`
+	IssuedAt          int64       `json:"iat"`
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // 部分提供方返回字符串
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+	Picture           string      `json:"picture"`
+}
+
+func (c *idTokenClaims) audiences() []string {
+	switch aud := c.Audience.(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		res := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// Exchange 换取令牌并校验ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	token, err := p.exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, ErrIDToken
+	}
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+	return &Identity{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Username:      claims.PreferredUsername,
+		Name:          claims.Name,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Avatar:        claims.Picture,
+	}, nil
+}
+
+// VerifyIDToken 校验ID token的签名、iss、aud、exp与nonce，见OIDC Core §3.1.3.7
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, raw, nonce string) (*idTokenClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrIDToken
+	}
+	header := &struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeSegment(parts[0], header); err != nil {
+		return nil, ErrIDToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrIDToken
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWS(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	claims := &idTokenClaims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, ErrIDToken
+	}
+	now := time.Now()
+	if claims.Issuer != p.issuer || claims.Subject == "" {
+		return nil, ErrIDToken
+	}
+	audOK := false
+	auds := claims.audiences()
+	for _, aud := range auds {
+		audOK = audOK || aud == p.ClientID
+	}
+	if !audOK || (len(auds) > 1 && claims.AuthorizedParty != p.ClientID) {
+		return nil, ErrIDToken
+	}
+	if claims.Expiry == 0 || now.Add(-clockSkew).Unix() >= claims.Expiry || claims.IssuedAt > now.Add(clockSkew).Unix() {
+		return nil, ErrIDToken
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrIDToken
+	}
+	return claims, nil
+}
+
+// key 按kid查找签名公钥，未知kid时按间隔重新获取JWKS以支持提供方轮换密钥
+func (p *OIDCProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lookup := func() crypto.PublicKey {
+		if k, ok := p.keys[kid]; ok {
+			return k
+		}
+		// 只有一个密钥时允许省略kid
+		if kid == "" && len(p.keys) == 1 {
+			for _, k := range p.keys {
+				return k
+			}
+		}
+		return nil
+	}
+	if k := lookup(); k != nil {
+		return k, nil
+	}
+	if time.Since(p.fetched) < jwksRefetchInterval {
+		return nil, ErrIDToken
+	}
+
+	set := &struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}{}
+	if err := getJSON(ctx, p.jwksURI, "", set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	p.keys = keys
+	p.fetched = time.Now()
+
+	if k := lookup(); k != nil {
+		return k, nil
+	}
+	return nil, ErrIDToken
+}
+
+// jsonWebKey RFC 7517 JWK
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	b := func(s string) *big.Int {
+		v, _ := base64.RawURLEncoding.DecodeString(s)
+		return new(big.Int).SetBytes(v)
+	}
+	switch {
+	case k.Kty == "RSA" && k.N != "" && k.E != "":
+		return &rsa.PublicKey{N: b(k.N), E: int(b(k.E).Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: b(k.X), Y: b(k.Y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, ErrIDToken
+		}
+		return pub, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrIDToken
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, ErrIDToken
+}
+
+// verifyJWS 校验JWS签名，只接受非对称算法
+func verifyJWS(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		var h crypto.Hash
+		switch alg {
+		case "RS256":
+			h = crypto.SHA256
+		case "RS512":
+			h = crypto.SHA512
+		default:
+			return ErrIDToken
+		}
+		hasher := h.New()
+		hasher.Write(signed)
+		if rsa.VerifyPKCS1v15(k, h, hasher.Sum(nil), sig) != nil {
+			return ErrIDToken
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(sig) != 64 {
+			return ErrIDToken
+		}
+		sum := sha256.Sum256(signed)
+		if !ecdsa.Verify(k, sum[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return ErrIDToken
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != "EdDSA" || !ed25519.Verify(k, signed, sig) {
+			return ErrIDToken
+		}
+		return nil
+	}
+	return ErrIDToken
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
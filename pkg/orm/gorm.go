@@ -1,8 +1,11 @@
 package orm
 
 import (
+	"errors"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm/schema"
@@ -64,3 +67,44 @@ func Pagination(db *gorm.DB, page int, limit int) *gorm.DB {
 	}
 	return db.Limit(limit).Offset((page - 1) * limit)
 }
+
+// Cursor 按(created, id)倒序的游标
+type Cursor struct {
+	Created int64
+	ID      int64
+}
+
+// ParseCursor 解析形如"created_id"的游标，空字符串表示从头开始
+func ParseCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(s, "_", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+	created, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &Cursor{Created: created, ID: id}, nil
+}
+
+func (c *Cursor) String() string {
+	if c == nil {
+		return ""
+	}
+	return strconv.FormatInt(c.Created, 10) + "_" + strconv.FormatInt(c.ID, 10)
+}
+
+// Before 限定结果位于游标之后（更早），column为id所在列名
+func (c *Cursor) Before(db *gorm.DB, column string) *gorm.DB {
+	if c == nil {
+		return db
+	}
+	return db.Where("(created < ? OR (created = ? AND "+column+" < ?))", c.Created, c.Created, c.ID)
+}
@@ -0,0 +1,23 @@
+package orm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCursor(t *testing.T) {
+	cursor, err := ParseCursor("1600000000_42")
+	assert.NoError(t, err)
+	assert.Equal(t, &Cursor{Created: 1600000000, ID: 42}, cursor)
+	assert.Equal(t, "1600000000_42", cursor.String())
+
+	cursor, err = ParseCursor("")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+	assert.Equal(t, "", cursor.String())
+
+	for _, s := range []string{"abc", "1_", "_1", "1_x"} {
+		_, err = ParseCursor(s)
+		assert.Error(t, err, s)
+	}
+}
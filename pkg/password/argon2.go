@@ -0,0 +1,116 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2ID = "argon2id"
+
+// OWASP推荐的argon2id参数
+const (
+	DefaultArgon2Memory  = 64 * 1024 // KiB
+	DefaultArgon2Time    = 3
+	DefaultArgon2Threads = 2
+	argon2SaltLen        = 16
+	argon2KeyLen         = 32
+)
+
+// Argon2id argon2id哈希，编码为PHC字符串格式
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2id struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+}
+
+// ID ...
+func (a *Argon2id) ID() string {
+	return argon2ID
+}
+
+func (a *Argon2id) params() (uint32, uint32, uint8) {
+	m, t, p := a.Memory, a.Time, a.Threads
+	if m == 0 {
+		m = DefaultArgon2Memory
+	}
+	if t == 0 {
+		t = DefaultArgon2Time
+	}
+	if p == 0 {
+		p = DefaultArgon2Threads
+	}
+	return m, t, p
+}
+
+// Hash ...
+func (a *Argon2id) Hash(password string) (string, error) {
+	m, t, p := a.params()
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, t, m, p, argon2KeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2ID, argon2.Version, m, t, p,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+type argon2Hash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func decodeArgon2(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != argon2ID {
+		return nil, ErrMalformed
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrMalformed
+	}
+	h := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, ErrMalformed
+	}
+	// 防止篡改的哈希使用过大的参数耗尽资源
+	if h.memory == 0 || h.memory > 1<<22 || h.time == 0 || h.time > 100 || h.threads == 0 {
+		return nil, ErrMalformed
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrMalformed
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 || len(h.key) > 128 {
+		return nil, ErrMalformed
+	}
+	return h, nil
+}
+
+// Verify ...
+func (a *Argon2id) Verify(password, encoded string) (bool, error) {
+	h, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+// Outdated 任一参数低于当前配置时需要重新哈希
+func (a *Argon2id) Outdated(encoded string) bool {
+	h, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	m, t, p := a.params()
+	return h.memory < m || h.time < t || h.threads < p || len(h.key) < argon2KeyLen
+}
@@ -0,0 +1,55 @@
+package password
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	bcryptID = "bcrypt"
+
+	DefaultBcryptCost = 12
+)
+
+// Bcrypt bcrypt哈希，只使用密码的前72字节
+type Bcrypt struct {
+	Cost int
+}
+
+// ID ...
+func (b *Bcrypt) ID() string {
+	return bcryptID
+}
+
+// Hash ...
+func (b *Bcrypt) Hash(password string) (string, error) {
+	cost := b.Cost
+	if cost == 0 {
+		cost = DefaultBcryptCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	return string(hash), err
+}
+
+// Verify ...
+func (b *Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == nil {
+		return true, nil
+	} else if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return false, err
+}
+
+// Outdated cost低于当前配置时需要重新哈希
+func (b *Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	want := b.Cost
+	if want == 0 {
+		want = DefaultBcryptCost
+	}
+	return cost < want
+}
@@ -0,0 +1,100 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Hasher 密码哈希算法，生成的哈希自带算法与参数
+type Hasher interface {
+	// ID 哈希前缀中的算法标识
+	ID() string
+	// Hash 计算密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码与哈希是否匹配
+	Verify(password, encoded string) (bool, error)
+	// Outdated 哈希是否使用了比当前配置更弱的参数
+	Outdated(encoded string) bool
+}
+
+var (
+	ErrUnknownHash = errors.New("password: unknown hash format")
+	ErrMalformed   = errors.New("password: malformed hash")
+
+	hashers       = map[string]Hasher{}
+	defaultHasher Hasher
+
+	dummyMu   sync.Mutex
+	dummyHash string // 默认算法计算的哈希，供VerifyDummy使用
+)
+
+func init() {
+	SetDefault(&Bcrypt{Cost: DefaultBcryptCost})
+	Register(&Argon2id{})
+}
+
+// Register register a hasher used to verify existing hashes
+func Register(h Hasher) {
+	hashers[h.ID()] = h
+}
+
+// SetDefault set the hasher used for new hashes, it is registered as well
+func SetDefault(h Hasher) {
+	Register(h)
+	defaultHasher = h
+	dummyMu.Lock()
+	dummyHash = ""
+	dummyMu.Unlock()
+}
+
+// identify 根据哈希前缀选择算法，形如$2a$...或$argon2id$...
+func identify(encoded string) (Hasher, error) {
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return nil, ErrUnknownHash
+	}
+	id := parts[1]
+	if strings.HasPrefix(id, "2") {
+		id = bcryptID
+	}
+	if h, ok := hashers[id]; ok {
+		return h, nil
+	}
+	return nil, ErrUnknownHash
+}
+
+// Hash 使用默认算法计算密码哈希
+func Hash(password string) (string, error) {
+	return defaultHasher.Hash(password)
+}
+
+// Verify 按哈希自带的算法校验密码
+func Verify(password, encoded string) (bool, error) {
+	h, err := identify(encoded)
+	if err != nil {
+		return false, err
+	}
+	return h.Verify(password, encoded)
+}
+
+// NeedsRehash 哈希的算法不是默认算法或参数已过时，需要在用户登录时重新计算
+func NeedsRehash(encoded string) bool {
+	h, err := identify(encoded)
+	if err != nil {
+		return true
+	}
+	return h.ID() != defaultHasher.ID() || defaultHasher.Outdated(encoded)
+}
+
+// VerifyDummy 使用默认算法做一次无意义的校验
+// 用户不存在时调用，使耗时与校验真实密码相近，避免通过响应时间判断用户是否存在
+func VerifyDummy(password string) {
+	dummyMu.Lock()
+	if dummyHash == "" {
+		dummyHash, _ = defaultHasher.Hash("vid dummy password")
+	}
+	hash := dummyHash
+	dummyMu.Unlock()
+	_, _ = Verify(password, hash)
+}
@@ -0,0 +1,80 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	for _, h := range []Hasher{&Bcrypt{Cost: 4}, &Argon2id{Memory: 1024, Time: 1, Threads: 1}} {
+		hash, err := h.Hash("correct horse")
+		require.NoError(t, err)
+
+		ok, err := h.Verify("correct horse", hash)
+		assert.NoError(t, err)
+		assert.True(t, ok, h.ID())
+		ok, err = h.Verify("wrong horse", hash)
+		assert.NoError(t, err)
+		assert.False(t, ok, h.ID())
+
+		// 通过哈希前缀识别算法
+		Register(h)
+		ok, err = Verify("correct horse", hash)
+		assert.NoError(t, err)
+		assert.True(t, ok, h.ID())
+	}
+
+	_, err := Verify("x", "plaintext")
+	assert.Equal(t, ErrUnknownHash, err)
+	_, err = Verify("x", "$argon2id$v=19$m=99999999,t=1,p=1$c2FsdA$a2V5")
+	assert.Equal(t, ErrMalformed, err)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer SetDefault(defaultHasher)
+
+	weak, _ := (&Bcrypt{Cost: 4}).Hash("pw")
+	SetDefault(&Bcrypt{Cost: 5})
+	assert.True(t, NeedsRehash(weak))
+	strong, _ := Hash("pw")
+	assert.False(t, NeedsRehash(strong))
+
+	// 切换算法后旧哈希仍可校验，但需要重新哈希
+	SetDefault(&Argon2id{Memory: 1024, Time: 1, Threads: 1})
+	assert.True(t, NeedsRehash(strong))
+	ok, _ := Verify("pw", strong)
+	assert.True(t, ok)
+
+	argon, _ := Hash("pw")
+	assert.True(t, strings.HasPrefix(argon, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.False(t, NeedsRehash(argon))
+	SetDefault(&Argon2id{Memory: 2048, Time: 1, Threads: 1})
+	assert.True(t, NeedsRehash(argon))
+}
+
+func TestVerifyDummy(t *testing.T) {
+	defer SetDefault(defaultHasher)
+
+	SetDefault(&Bcrypt{Cost: 4})
+	VerifyDummy("pw")
+	assert.True(t, strings.HasPrefix(dummyHash, "$2a$04$"))
+
+	// 切换算法后使用新算法的哈希
+	SetDefault(&Argon2id{Memory: 1024, Time: 1, Threads: 1})
+	VerifyDummy("pw")
+	assert.True(t, strings.HasPrefix(dummyHash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+}
+
+func TestPolicy(t *testing.T) {
+	p := DefaultPolicy
+	assert.Equal(t, ErrTooShort, p.Check("short", "tom"))
+	assert.Equal(t, ErrTooLong, p.Check(strings.Repeat("a", 73), "tom"))
+	assert.Equal(t, ErrCommon, p.Check("Password123", "tom"))
+	assert.Equal(t, ErrSameAsUsername, p.Check("TomCat2021", "tomcat2021"))
+	assert.NoError(t, p.Check("中文密码也可以用", "tom"))
+	assert.NoError(t, p.Check("correct horse battery", "tom"))
+	assert.False(t, common["# 常见弱密码，比较时忽略大小写"])
+}
@@ -0,0 +1,61 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed common.txt
+var commonList string
+
+var (
+	ErrTooShort       = errors.New("password is too short")
+	ErrTooLong        = errors.New("password is too long")
+	ErrCommon         = errors.New("password is too common")
+	ErrSameAsUsername = errors.New("password must not equal username")
+
+	common = loadCommon(commonList)
+)
+
+// Policy 密码强度要求
+type Policy struct {
+	MinLength int // 最少字符数
+	MaxLength int // 最多字节数，bcrypt只使用前72字节
+}
+
+// DefaultPolicy 默认密码策略
+var DefaultPolicy = &Policy{MinLength: 8, MaxLength: 72}
+
+func loadCommon(list string) map[string]bool {
+	res := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		res[strings.ToLower(line)] = true
+	}
+	return res
+}
+
+// Check 检查密码是否满足策略
+func (p *Policy) Check(password, username string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return ErrTooShort
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return ErrTooLong
+	}
+	lower := strings.ToLower(password)
+	if username != "" && lower == strings.ToLower(username) {
+		return ErrSameAsUsername
+	}
+	if common[lower] {
+		return ErrCommon
+	}
+	return nil
+}
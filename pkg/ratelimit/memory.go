@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore 进程内存储，redis不可用时使用，多实例部署时各实例分别计数
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	windows map[string][]int64
+	expires map[string]int64 // unix毫秒，过期的记录在清理时删除
+	ops     int
+}
+
+// sweepEvery 每隔若干次请求清理一次过期记录
+const sweepEvery = 1024
+
+// NewMemoryStore ...
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		windows: map[string][]int64{},
+		expires: map[string]int64{},
+	}
+}
+
+// Take ...
+func (m *MemoryStore) Take(key string, p *Policy, now int64) (*Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ops++
+	if m.ops%sweepEvery == 0 {
+		for k, expires := range m.expires {
+			if now >= expires {
+				delete(m.buckets, k)
+				delete(m.windows, k)
+				delete(m.expires, k)
+			}
+		}
+	}
+
+	var res *Result
+	if p.Algorithm == TokenBucket {
+		m.buckets[key], res = p.take(m.buckets[key], now)
+	} else {
+		m.windows[key], res = p.slide(m.windows[key], now)
+	}
+	// 配额完全恢复后的状态与没有记录相同，可以删除
+	m.expires[key] = now + int64(res.Reset/time.Millisecond)
+	return res, nil
+}
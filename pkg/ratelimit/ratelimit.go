@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	TokenBucket   = "token-bucket"   // 令牌桶，允许短时突发
+	SlidingWindow = "sliding-window" // 滑动窗口，任意Period内不超过Limit次
+)
+
+var ErrPolicy = errors.New("ratelimit: invalid policy")
+
+// Store 限流状态的存储
+type Store interface {
+	// Take 尝试消耗一次配额，now为unix毫秒
+	Take(key string, p *Policy, now int64) (*Result, error)
+}
+
+// Policy 限流策略
+// 令牌桶的容量为Burst（未设置时等于Limit），每Period补充Limit个令牌
+// 滑动窗口统计最近Period内的请求数，不超过Limit
+type Policy struct {
+	Algorithm string
+	Limit     int64
+	Period    time.Duration
+	Burst     int64
+}
+
+// Result 限流结果，时间均向上取整到毫秒
+type Result struct {
+	Allowed    bool
+	Limit      int64         // 配额上限，令牌桶为桶容量
+	Remaining  int64         // 剩余配额
+	Reset      time.Duration // 配额完全恢复所需的时间
+	RetryAfter time.Duration // 被拒绝时距下次可以请求的时间
+}
+
+// Validate 检查策略参数
+func (p *Policy) Validate() error {
+	if p.Algorithm != TokenBucket && p.Algorithm != SlidingWindow {
+		return ErrPolicy
+	}
+	if p.Limit <= 0 || p.Period < time.Millisecond || p.Burst < 0 {
+		return ErrPolicy
+	}
+	return nil
+}
+
+// Capacity 配额上限
+func (p *Policy) Capacity() int64 {
+	if p.Algorithm == TokenBucket && p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Limit
+}
+
+func (p *Policy) periodMs() int64 {
+	return int64(p.Period / time.Millisecond)
+}
+
+// bucket 令牌桶状态，ts为上次计算令牌数的时间(unix毫秒)
+type bucket struct {
+	tokens float64
+	ts     int64
+}
+
+// take 按经过的时间补充令牌后消耗一个，b为nil表示新建的满桶
+func (p *Policy) take(b *bucket, now int64) (*bucket, *Result) {
+	capacity := float64(p.Capacity())
+	rate := float64(p.Limit) / float64(p.periodMs()) // 每毫秒补充的令牌数
+	if b == nil {
+		b = &bucket{tokens: capacity, ts: now}
+	}
+	if now > b.ts {
+		b.tokens = math.Min(capacity, b.tokens+float64(now-b.ts)*rate)
+		b.ts = now
+	}
+	res := &Result{Limit: p.Capacity()}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = ms(math.Ceil((1 - b.tokens) / rate))
+	}
+	res.Remaining = int64(b.tokens)
+	res.Reset = ms(math.Ceil((capacity - b.tokens) / rate))
+	return b, res
+}
+
+// slide 移除窗口外的请求后尝试记录本次请求，log为窗口内请求的时间，按时间正序
+// 窗口为(now-Period, now]
+func (p *Policy) slide(log []int64, now int64) ([]int64, *Result) {
+	period := p.periodMs()
+	i := 0
+	for i < len(log) && log[i] <= now-period {
+		i++
+	}
+	log = log[i:]
+	res := &Result{Limit: p.Limit}
+	if int64(len(log)) < p.Limit {
+		log = append(log, now)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = ms(float64(log[0] + period - now))
+	}
+	res.Remaining = p.Limit - int64(len(log))
+	if len(log) > 0 {
+		res.Reset = ms(float64(log[len(log)-1] + period - now))
+	}
+	return log, res
+}
+
+func ms(v float64) time.Duration {
+	return time.Duration(v) * time.Millisecond
+}
+
+// Limiter 按key限流
+type Limiter struct {
+	prefix string
+	policy *Policy
+	store  Store
+	now    func() time.Time
+}
+
+// New create limiter, keys are namespaced by prefix
+func New(prefix string, policy *Policy, store Store) *Limiter {
+	return &Limiter{prefix: prefix, policy: policy, store: store, now: time.Now}
+}
+
+// Policy 限流策略
+func (l *Limiter) Policy() *Policy {
+	return l.policy
+}
+
+// Allow 尝试消耗key的一次配额
+func (l *Limiter) Allow(key string) (*Result, error) {
+	return l.store.Take(l.prefix+key, l.policy, l.now().UnixNano()/int64(time.Millisecond))
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&Policy{Algorithm: TokenBucket, Limit: 10, Period: time.Second}).Validate())
+	assert.NoError(t, (&Policy{Algorithm: SlidingWindow, Limit: 5, Period: time.Minute}).Validate())
+	assert.Equal(t, ErrPolicy, (&Policy{Algorithm: "fixed", Limit: 10, Period: time.Second}).Validate())
+	assert.Equal(t, ErrPolicy, (&Policy{Algorithm: TokenBucket, Limit: 0, Period: time.Second}).Validate())
+	assert.Equal(t, ErrPolicy, (&Policy{Algorithm: TokenBucket, Limit: 10}).Validate())
+	assert.Equal(t, ErrPolicy, (&Policy{Algorithm: TokenBucket, Limit: 10, Period: time.Second, Burst: -1}).Validate())
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1614600000, 0)
+	l := New("api:", &Policy{Algorithm: TokenBucket, Limit: 1, Period: time.Second, Burst: 3}, NewMemoryStore())
+	l.now = func() time.Time { return now }
+
+	// 满桶允许突发
+	for i := int64(2); i >= 0; i-- {
+		res, err := l.Allow("tom")
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(3), res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res, _ := l.Allow("tom")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// 其他key不受影响
+	res, _ = l.Allow("jerry")
+	assert.True(t, res.Allowed)
+
+	// 按速率补充令牌
+	now = now.Add(500 * time.Millisecond)
+	res, _ = l.Allow("tom")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	now = now.Add(500 * time.Millisecond)
+	res, _ = l.Allow("tom")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+
+	// 补充不超过容量
+	now = now.Add(time.Hour)
+	res, _ = l.Allow("tom")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(2), res.Remaining)
+	assert.Equal(t, time.Second, res.Reset)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(1614600000, 0)
+	l := New("login:", &Policy{Algorithm: SlidingWindow, Limit: 3, Period: time.Minute}, NewMemoryStore())
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		res, _ := l.Allow("tom")
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(2-i), res.Remaining)
+		now = now.Add(10 * time.Second)
+	}
+	// 窗口内已有0s、10s、20s三次请求
+	res, _ := l.Allow("tom")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+	assert.Equal(t, 30*time.Second, res.RetryAfter)
+	assert.Equal(t, 50*time.Second, res.Reset)
+
+	// 第一次请求移出窗口后可以再次请求
+	now = now.Add(30 * time.Second)
+	res, _ = l.Allow("tom")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+	assert.Equal(t, time.Minute, res.Reset)
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// RedisStore 基于redis的存储，多实例共享配额，每次请求只执行一个脚本
+type RedisStore struct{}
+
+// tokenBucketScript 补充令牌后消耗一个
+// KEYS[1] key; ARGV: capacity limit period(ms) now(ms)
+// 返回 {allowed, remaining, reset(ms), retry_after(ms)}
+const tokenBucketScript = `
+local capacity, limit, period, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local rate = limit / period
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens, ts = tonumber(state[1]), tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens, ts = capacity, now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed, retry = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), reset, retry}`
+
+// slidingWindowScript 有序集合记录窗口内每次请求的时间
+// KEYS[1] key; ARGV: limit period(ms) now(ms) member
+// 返回 {allowed, remaining, reset(ms), retry_after(ms)}
+const slidingWindowScript = `
+local limit, period, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed, retry = 0, 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+else
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	retry = tonumber(oldest[2]) + period - now
+end
+local reset = 0
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if newest[2] then
+	reset = tonumber(newest[2]) + period - now
+end
+redis.call('PEXPIRE', KEYS[1], period)
+return {allowed, limit - count, reset, retry}`
+
+// Take ...
+func (r *RedisStore) Take(key string, p *Policy, now int64) (*Result, error) {
+	var res interface{}
+	var err error
+	if p.Algorithm == TokenBucket {
+		res, err = redis.Eval(tokenBucketScript, []string{key}, p.Capacity(), p.Limit, p.periodMs(), now)
+	} else {
+		// 同一毫秒内的多次请求需要不同的成员
+		member := strconv.FormatInt(now, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+		res, err = redis.Eval(slidingWindowScript, []string{key}, p.Limit, p.periodMs(), now, member)
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 4 {
+		return nil, redis.Nil
+	}
+	ints := make([]int64, 4)
+	for i, v := range values {
+		ints[i], _ = v.(int64)
+	}
+	return &Result{
+		Allowed:    ints[0] == 1,
+		Limit:      p.Capacity(),
+		Remaining:  ints[1],
+		Reset:      time.Duration(ints[2]) * time.Millisecond,
+		RetryAfter: time.Duration(ints[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"gorm.io/gorm"
+)
+
+// CasbinRule casbin策略表，与历史tbl_casbin_rule结构一致
+type CasbinRule struct {
+	ID    int64  `gorm:"primaryKey;autoIncrement"`
+	PType string `gorm:"size:100;index"`
+	V0    string `gorm:"size:100"`
+	V1    string `gorm:"size:100"`
+	V2    string `gorm:"size:100"`
+	V3    string `gorm:"size:100"`
+	V4    string `gorm:"size:100"`
+	V5    string `gorm:"size:100"`
+}
+
+// Adapter 基于gorm的casbin策略存储
+type Adapter struct {
+	db *gorm.DB
+}
+
+var _ persist.Adapter = (*Adapter)(nil)
+
+// NewAdapter 创建adapter并迁移策略表
+func NewAdapter(db *gorm.DB) (*Adapter, error) {
+	if err := db.AutoMigrate(&CasbinRule{}); err != nil {
+		return nil, err
+	}
+	return &Adapter{db: db}, nil
+}
+
+// LoadPolicy 从数据库加载全部策略
+func (a *Adapter) LoadPolicy(m model.Model) error {
+	var rules []*CasbinRule
+	if err := a.db.Order("id").Find(&rules).Error; err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		persist.LoadPolicyArray(rule.values(), m)
+	}
+	return nil
+}
+
+// SavePolicy 用模型中的策略覆盖数据库
+func (a *Adapter) SavePolicy(m model.Model) error {
+	var rules []*CasbinRule
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				rules = append(rules, newRule(ptype, rule))
+			}
+		}
+	}
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&CasbinRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return tx.Create(&rules).Error
+	})
+}
+
+// AddPolicy 添加一条策略
+func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.db.Create(newRule(ptype, rule)).Error
+}
+
+// RemovePolicy 删除一条策略
+func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.RemoveFilteredPolicy(sec, ptype, 0, rule...)
+}
+
+// RemoveFilteredPolicy 删除从fieldIndex开始匹配fieldValues的策略，空值表示不限
+func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	tx := a.db.Where("p_type = ?", ptype)
+	columns := []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+	for i, value := range fieldValues {
+		if value != "" && fieldIndex+i < len(columns) {
+			tx = tx.Where(columns[fieldIndex+i]+" = ?", value)
+		}
+	}
+	return tx.Delete(&CasbinRule{}).Error
+}
+
+func newRule(ptype string, rule []string) *CasbinRule {
+	r := &CasbinRule{PType: ptype}
+	fields := []*string{&r.V0, &r.V1, &r.V2, &r.V3, &r.V4, &r.V5}
+	for i, v := range rule {
+		if i < len(fields) {
+			*fields[i] = v
+		}
+	}
+	return r
+}
+
+// values 转为casbin所需的[ptype, v0, v1, ...]，去掉末尾空值
+func (r *CasbinRule) values() []string {
+	values := []string{r.PType, r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
+	for len(values) > 1 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
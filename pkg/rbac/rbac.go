@@ -0,0 +1,108 @@
+package rbac
+
+import (
+	"os"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"gorm.io/gorm"
+)
+
+// defaultModel 未提供模型配置文件时使用的RBAC模型
+const defaultModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch2(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
+var (
+	// enforcer casbin instance
+	enforcer *casbin.SyncedEnforcer
+	watcher  *Watcher
+)
+
+func Enforcer() *casbin.SyncedEnforcer {
+	if enforcer == nil {
+		panic("please init rbac")
+	}
+	return enforcer
+}
+
+// Init initialize casbin enforcer with policies stored in db
+func Init(db *gorm.DB, modelPath string) error {
+	var m model.Model
+	var err error
+	if _, statErr := os.Stat(modelPath); modelPath != "" && statErr == nil {
+		m, err = model.NewModelFromFile(modelPath)
+	} else {
+		m, err = model.NewModelFromString(defaultModel)
+	}
+	if err != nil {
+		return err
+	}
+
+	adapter, err := NewAdapter(db)
+	if err != nil {
+		return err
+	}
+	e, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err != nil {
+		return err
+	}
+	enforcer = e
+	return nil
+}
+
+// SetWatcher 设置策略变更通知，变更后其他实例自动重新加载
+func SetWatcher(w *Watcher) error {
+	if err := Enforcer().SetWatcher(w); err != nil {
+		return err
+	}
+	watcher = w
+	return nil
+}
+
+// Reload 从数据库重新加载策略，并通知其他实例重新加载
+func Reload() error {
+	if err := Enforcer().LoadPolicy(); err != nil {
+		return err
+	}
+	if watcher != nil {
+		return watcher.Update()
+	}
+	return nil
+}
+
+// Seed 策略为空时写入默认策略与角色继承
+func Seed(policies, groupings [][]string) error {
+	if len(Enforcer().GetPolicy()) > 0 || len(Enforcer().GetGroupingPolicy()) > 0 {
+		return nil
+	}
+	if len(policies) > 0 {
+		if _, err := Enforcer().AddPolicies(policies); err != nil {
+			return err
+		}
+	}
+	if len(groupings) > 0 {
+		if _, err := Enforcer().AddGroupingPolicies(groupings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enforce 校验sub能否对obj执行act
+func Enforce(sub, obj, act string) (bool, error) {
+	return Enforcer().Enforce(sub, obj, act)
+}
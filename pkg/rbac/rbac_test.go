@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDefaultModel(t *testing.T) {
+	m, err := model.NewModelFromString(defaultModel)
+	assert.NoError(t, err)
+	e, err := casbin.NewSyncedEnforcer(m)
+	assert.NoError(t, err)
+
+	_, _ = e.AddPolicy("normal", "/api/v1/auth/*", "*")
+	_, _ = e.AddPolicy("admin", "/api/v1/admin/*", "*")
+	_, _ = e.AddPolicy("normal", "/api/v1/video", "GET")
+	_, _ = e.AddGroupingPolicy("admin", "normal")
+
+	cases := []struct {
+		sub, obj, act string
+		want          bool
+	}{
+		{"normal", "/api/v1/auth/UserAuth", "GET", true},
+		{"normal", "/api/v1/admin/AddPolicy", "POST", false},
+		{"admin", "/api/v1/admin/AddPolicy", "POST", true},
+		{"admin", "/api/v1/auth/UserAuth", "GET", true},
+		{"normal", "/api/v1/video", "GET", true},
+		{"normal", "/api/v1/video", "POST", false},
+		{"guest", "/api/v1/auth/UserAuth", "GET", false},
+	}
+	for _, c := range cases {
+		ok, err := e.Enforce(c.sub, c.obj, c.act)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, ok, "%s %s %s", c.sub, c.obj, c.act)
+	}
+}
+
+func TestRuleValues(t *testing.T) {
+	r := newRule("p", []string{"normal", "/api/v1/auth/*", "GET"})
+	assert.Equal(t, []string{"p", "normal", "/api/v1/auth/*", "GET"}, r.values())
+}
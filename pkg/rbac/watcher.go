@@ -0,0 +1,66 @@
+package rbac
+
+import (
+	"sync"
+
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/vidorg/vid_backend/pkg/redis"
+)
+
+// Watcher 通过redis发布订阅在多个实例之间同步策略变更
+type Watcher struct {
+	mu       sync.RWMutex
+	channel  string
+	callback func(string)
+	done     chan struct{}
+}
+
+var _ persist.Watcher = (*Watcher)(nil)
+
+// NewWatcher 订阅channel，收到消息时调用回调
+func NewWatcher(channel string) *Watcher {
+	w := &Watcher{
+		channel: channel,
+		done:    make(chan struct{}),
+	}
+	pubsub := redis.Subscribe(channel)
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				w.mu.RLock()
+				callback := w.callback
+				w.mu.RUnlock()
+				if callback != nil {
+					callback(msg.Payload)
+				}
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// SetUpdateCallback 设置策略变更回调，通常为重新加载策略
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	w.callback = callback
+	w.mu.Unlock()
+	return nil
+}
+
+// Update 通知所有实例重新加载策略
+func (w *Watcher) Update() error {
+	return redis.Publish(w.channel, "update")
+}
+
+// Close 停止订阅
+func (w *Watcher) Close() {
+	close(w.done)
+}
@@ -20,20 +20,26 @@ func Rdb() *redis.Client {
 	return rdb
 }
 
+// Enabled whether redis has been initialized successfully
+func Enabled() bool {
+	return rdb != nil
+}
+
 // Init initialize redis client
 func Init(addr, pass string, db int) error {
-	rdb = redis.NewClient(&redis.Options{
+	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: pass,
 		DB:       db,
 	})
-	pong, err := rdb.Ping(ctx).Result()
+	pong, err := client.Ping(ctx).Result()
 	if err != nil {
 		return err
 	}
 	if pong != "PONG" {
 		return errors.New("redis pong err")
 	}
+	rdb = client
 	return nil
 }
 
@@ -107,3 +113,56 @@ func Subscribe(channel string) *redis.PubSub {
 func SubscribeChan(channel string) <-chan *redis.Message {
 	return Rdb().Subscribe(ctx, channel).Channel()
 }
+
+// HSet set hash fields
+func HSet(key string, values ...interface{}) error {
+	return Rdb().HSet(ctx, key, values...).Err()
+}
+
+// HGetAll get all hash fields
+func HGetAll(key string) (map[string]string, error) {
+	return Rdb().HGetAll(ctx, key).Result()
+}
+
+// HIncrBy increment hash field
+func HIncrBy(key, field string, incr int64) (int64, error) {
+	return Rdb().HIncrBy(ctx, key, field, incr).Result()
+}
+
+// Expire set key expiration
+func Expire(key string, expiration time.Duration) error {
+	return Rdb().Expire(ctx, key, expiration).Err()
+}
+
+// SAdd add members to set
+func SAdd(key string, members ...interface{}) error {
+	return Rdb().SAdd(ctx, key, members...).Err()
+}
+
+// SRem remove members from set
+func SRem(key string, members ...interface{}) error {
+	return Rdb().SRem(ctx, key, members...).Err()
+}
+
+// SMembers get all members of set
+func SMembers(key string) ([]string, error) {
+	return Rdb().SMembers(ctx, key).Result()
+}
+
+// Nil reply returned by redis when key does not exist
+const Nil = redis.Nil
+
+var getDelScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v then redis.call('DEL', KEYS[1]) end
+return v`)
+
+// GetDel get value and delete the key atomically, returns Nil if the key does not exist
+func GetDel(key string) (string, error) {
+	return getDelScript.Run(ctx, Rdb(), []string{key}).Text()
+}
+
+// Eval run lua script
+func Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return Rdb().Eval(ctx, script, keys, args...).Result()
+}
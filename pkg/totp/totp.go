@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// RFC 6238 TOTP，使用主流验证器App的默认参数：HMAC-SHA1、6位、30秒
+const (
+	Period = 30 // 时间步长，秒
+	Digits = 6  // 验证码位数
+	Skew   = 1  // 允许前后偏移的时间步数，容忍客户端时钟误差
+)
+
+var (
+	encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	ErrSecret = errors.New("totp: invalid secret")
+)
+
+// GenerateSecret 生成160位随机密钥，返回base32编码
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Counter 时间t所在的时间步
+func Counter(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code 计算时间t的验证码
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(Counter(t)), Digits), nil
+}
+
+// Validate 校验验证码，允许前后Skew个时间步，返回匹配的时间步
+// 调用方应记录最近一次使用的时间步，拒绝不大于它的counter以防止重放
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+	now := Counter(t)
+	for i := -Skew; i <= Skew; i++ {
+		counter := now + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(counter), Digits)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// URI 生成验证器App识别的otpauth链接
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(Period))
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// QRCode 将otpauth链接编码为PNG二维码
+func QRCode(uri string, size int) ([]byte, error) {
+	return qrcode.Encode(uri, qrcode.Medium, size)
+}
+
+// hotp RFC 4226 HOTP
+func hotp(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrSecret
+	}
+	return key, nil
+}
@@ -0,0 +1,61 @@
+package totp
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录B的SHA1测试向量
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for ts, want := range cases {
+		assert.Equal(t, want, hotp(key, uint64(Counter(time.Unix(ts, 0))), 8), ts)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1614600000, 0)
+
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+	counter, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Counter(now), counter)
+
+	// 允许一个时间步的误差
+	_, ok = Validate(secret, code, now.Add(Period*time.Second))
+	assert.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(3*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+	_, ok = Validate("!!", code, now)
+	assert.False(t, ok)
+}
+
+func TestURIAndQRCode(t *testing.T) {
+	uri := URI("Vid", "tom@vid.test", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/Vid:tom@vid.test?algorithm=SHA1&digits=6&issuer=Vid&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+
+	img, err := QRCode(uri, 256)
+	require.NoError(t, err)
+	decoded, err := png.Decode(bytes.NewReader(img))
+	require.NoError(t, err)
+	assert.Equal(t, 256, decoded.Bounds().Dx())
+}
@@ -0,0 +1,96 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxImagePixels 解码图片的像素上限，防止解压炸弹
+const MaxImagePixels = 4096 * 4096
+
+var (
+	ErrTooManyPixels = errors.New("upload: image dimensions too large")
+	ErrCrop          = errors.New("upload: crop area out of bounds")
+)
+
+// ReadImage 读取上传的图片并校验大小与类型，返回原始数据
+func ReadImage(fh *multipart.FileHeader) ([]byte, error) {
+	if fh.Size > MaxImageSize {
+		return nil, ErrTooLarge
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxImageSize {
+		return nil, ErrTooLarge
+	}
+	if _, ok := imageExt[http.DetectContentType(data)]; !ok {
+		return nil, ErrUnsupported
+	}
+	return data, nil
+}
+
+// SquareImage 按crop裁剪图片并缩放为size*size，crop为空时居中裁剪最大正方形
+// 原图为jpeg时输出jpeg，否则输出png以保留透明度，gif只取第一帧
+func SquareImage(data []byte, crop image.Rectangle, size int) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > MaxImagePixels {
+		return nil, "", ErrTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+
+	bounds := src.Bounds()
+	if crop.Empty() {
+		side := bounds.Dx()
+		if bounds.Dy() < side {
+			side = bounds.Dy()
+		}
+		x := bounds.Min.X + (bounds.Dx()-side)/2
+		y := bounds.Min.Y + (bounds.Dy()-side)/2
+		crop = image.Rect(x, y, x+side, y+side)
+	} else {
+		crop = crop.Add(bounds.Min)
+		if !crop.In(bounds) {
+			return nil, "", ErrCrop
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	opaque := format == "jpeg"
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	buf := &bytes.Buffer{}
+	if opaque {
+		err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: 90})
+		return buf.Bytes(), ".jpg", err
+	}
+	err = png.Encode(buf, dst)
+	return buf.Bytes(), ".png", err
+}
@@ -0,0 +1,41 @@
+package upload
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestSquareImage(t *testing.T) {
+	data := encodePNG(t, 300, 200)
+
+	out, ext, err := SquareImage(data, image.Rectangle{}, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, ".png", ext)
+	img, err := png.Decode(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), img.Bounds())
+
+	_, _, err = SquareImage(data, image.Rect(10, 10, 110, 110), 32)
+	assert.NoError(t, err)
+	_, _, err = SquareImage(data, image.Rect(250, 150, 350, 250), 32)
+	assert.ErrorIs(t, err, ErrCrop)
+	_, _, err = SquareImage([]byte("not an image"), image.Rectangle{}, 32)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
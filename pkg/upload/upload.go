@@ -0,0 +1,73 @@
+package upload
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var (
+	basePath = "./upload/"
+	baseURL  = "/static/"
+
+	// MaxImageSize 图片大小上限
+	MaxImageSize int64 = 5 << 20
+
+	ErrTooLarge    = errors.New("upload: file too large")
+	ErrUnsupported = errors.New("upload: unsupported file type")
+)
+
+// imageExt 允许上传的图片类型
+var imageExt = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+func SetMeta(path, url string) {
+	basePath = path
+	baseURL = url
+}
+
+// BasePath 本地存储根目录
+func BasePath() string {
+	return basePath
+}
+
+// SaveImage 校验并保存图片到dir子目录，返回可访问的URL
+func SaveImage(fh *multipart.FileHeader, dir string) (string, error) {
+	data, err := ReadImage(fh)
+	if err != nil {
+		return "", err
+	}
+	return Save(data, dir, imageExt[http.DetectContentType(data)])
+}
+
+// Save 以随机文件名保存数据到dir子目录，返回可访问的URL
+func Save(data []byte, dir, ext string) (string, error) {
+	name, err := randomName()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(basePath, dir), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(basePath, dir, name+ext), data, 0644); err != nil {
+		return "", err
+	}
+	return baseURL + path.Join(dir, name+ext), nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package useragent
+
+import "strings"
+
+// rule 按顺序匹配，先匹配更具体的标识
+type rule struct {
+	token string
+	name  string
+}
+
+var browsers = []rule{
+	{"MicroMessenger", "WeChat"},
+	{"QQBrowser", "QQ Browser"},
+	{"UCBrowser", "UC Browser"},
+	{"Edg/", "Edge"},
+	{"Edge/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Opera", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"MSIE ", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+	{"okhttp", "Android App"},
+	{"CFNetwork", "iOS App"},
+	{"curl/", "curl"},
+	{"PostmanRuntime", "Postman"},
+}
+
+var systems = []rule{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"HarmonyOS", "HarmonyOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"CrOS", "Chrome OS"},
+	{"Linux", "Linux"},
+}
+
+// DeviceName 从User-Agent解析出便于展示的设备名，如"Chrome on Windows"
+func DeviceName(ua string) string {
+	browser := match(ua, browsers)
+	system := match(ua, systems)
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		if strings.Contains(ua, r.token) {
+			return r.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+package useragent
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDeviceName(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.150 Safari/537.36":                     "Chrome on Windows",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.150 Safari/537.36 Edg/88.0.705":        "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15E148 Safari/604.1": "Safari on iPhone",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:85.0) Gecko/20100101 Firefox/85.0":                                                      "Firefox on macOS",
+		"curl/7.68.0": "curl",
+		"":            "Unknown device",
+	}
+	for ua, want := range cases {
+		assert.Equal(t, want, DeviceName(ua), ua)
+	}
+}
@@ -0,0 +1,71 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// 认证器数据标志位
+const (
+	flagUserPresent   = 0x01
+	flagUserVerified  = 0x04
+	flagAttestedData  = 0x40
+	flagExtensionData = 0x80
+)
+
+var ErrAuthData = errors.New("webauthn: malformed authenticator data")
+
+// authenticatorData 认证器数据，见WebAuthn §6.1
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// 仅注册时存在
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE_Key原始编码
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, ErrAuthData
+	}
+	ad := &authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if ad.Flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, ErrAuthData
+		}
+		ad.AAGUID = rest[:16]
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n == 0 || n > 1023 || len(rest) < n {
+			return nil, ErrAuthData
+		}
+		ad.CredentialID = rest[:n]
+		rest = rest[n:]
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrAuthData
+		}
+		ad.PublicKey = rest[:n]
+		rest = rest[n:]
+	}
+	if ad.Flags&flagExtensionData != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrAuthData
+		}
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return nil, ErrAuthData
+	}
+	return ad, nil
+}
@@ -0,0 +1,107 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE算法标识
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgs 注册时向浏览器声明支持的算法，按优先级排序
+var SupportedAlgs = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+var (
+	ErrUnsupportedKey = errors.New("webauthn: unsupported public key")
+	ErrSignature      = errors.New("webauthn: invalid signature")
+)
+
+// parseCOSEKey 解析COSE_Key，返回公钥与算法
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	v, n, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n != len(raw) {
+		return nil, 0, ErrCBOR
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, ErrCBOR
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == AlgES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return pub, alg, nil
+	case kty == 1 && alg == AlgEdDSA:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case kty == 3 && alg == AlgRS256:
+		nb, _ := m[int64(-1)].([]byte)
+		eb, _ := m[int64(-2)].([]byte)
+		if len(nb) < 256 || len(eb) == 0 || len(eb) > 4 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(new(big.Int).SetBytes(eb).Int64())}, alg, nil
+	}
+	return nil, 0, ErrUnsupportedKey
+}
+
+// verifySignature 按COSE算法校验签名
+func verifySignature(pub crypto.PublicKey, alg int64, data, sig []byte) error {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if alg != AlgES256 {
+			return ErrUnsupportedKey
+		}
+		sum := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, sum[:], sig) {
+			return ErrSignature
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA {
+			return ErrUnsupportedKey
+		}
+		if !ed25519.Verify(key, data, sig) {
+			return ErrSignature
+		}
+		return nil
+	case *rsa.PublicKey:
+		if alg != AlgRS256 {
+			return ErrUnsupportedKey
+		}
+		sum := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) != nil {
+			return ErrSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedKey
+}
@@ -0,0 +1,68 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+)
+
+// idFidoGenCeAAGUID 证明证书中携带AAGUID的扩展
+var idFidoGenCeAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// verifyPacked 校验packed格式的证明，见WebAuthn §8.2
+// 带x5c时为基本证明，只校验证书本身的约束，不校验到厂商根证书的信任链；不带x5c时为自证明
+func verifyPacked(stmt map[interface{}]interface{}, authData, clientDataHash, aaguid []byte, credKey crypto.PublicKey, credAlg int64) error {
+	alg, _ := stmt["alg"].(int64)
+	sig, _ := stmt["sig"].([]byte)
+	if len(sig) == 0 {
+		return ErrAttestation
+	}
+	signed := append(append([]byte(nil), authData...), clientDataHash...)
+
+	x5c, ok := stmt["x5c"].([]interface{})
+	if !ok {
+		if _, has := stmt["x5c"]; has {
+			return ErrAttestation
+		}
+		if alg != credAlg {
+			return ErrAttestation
+		}
+		return verifySignature(credKey, alg, signed, sig)
+	}
+
+	if len(x5c) == 0 {
+		return ErrAttestation
+	}
+	der, _ := x5c[0].([]byte)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return ErrAttestation
+	}
+	if err := verifySignature(cert.PublicKey, alg, signed, sig); err != nil {
+		return err
+	}
+
+	// 证书要求，见WebAuthn §8.2.1
+	if cert.Version != 3 || cert.IsCA || len(cert.Subject.Country) == 0 ||
+		len(cert.Subject.Organization) == 0 || cert.Subject.CommonName == "" {
+		return ErrAttestation
+	}
+	ouOK := false
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		ouOK = ouOK || ou == "Authenticator Attestation"
+	}
+	if !ouOK {
+		return ErrAttestation
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(idFidoGenCeAAGUID) {
+			continue
+		}
+		var value []byte
+		if _, err := asn1.Unmarshal(ext.Value, &value); err != nil || !bytes.Equal(value, aaguid) {
+			return ErrAttestation
+		}
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Config 依赖方配置
+type Config struct {
+	RPID        string   // 依赖方ID，通常为站点域名
+	RPName      string   // 浏览器中显示的名称
+	Origins     []string // 允许的前端origin，如https://vid.example.com
+	Attestation string   // 注册时请求的证明方式，none或direct，默认none
+	Timeout     int      // 浏览器等待用户操作的毫秒数
+}
+
+var (
+	ErrClientData        = errors.New("webauthn: malformed client data")
+	ErrCeremonyType      = errors.New("webauthn: client data type not match")
+	ErrChallenge         = errors.New("webauthn: challenge not match")
+	ErrOrigin            = errors.New("webauthn: origin not allowed")
+	ErrRPID              = errors.New("webauthn: rp id hash not match")
+	ErrUserPresence      = errors.New("webauthn: user not present")
+	ErrUserVerification  = errors.New("webauthn: user not verified")
+	ErrAttestation       = errors.New("webauthn: invalid attestation")
+	ErrAttestationFormat = errors.New("webauthn: unsupported attestation format")
+	ErrCredential        = errors.New("webauthn: credential not match")
+	ErrSignCount         = errors.New("webauthn: sign count did not increase, authenticator may be cloned")
+)
+
+// Base64URL 前端以base64url编码传递的二进制数据
+type Base64URL []byte
+
+// MarshalJSON ...
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON 兼容带填充的编码
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// CredentialDescriptor 凭证描述
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+// CredentialParameter 支持的公钥算法
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// RelyingParty 依赖方信息
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity 注册的用户信息
+type UserEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+// AuthenticatorSelection 认证器要求
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions navigator.credentials.create的publicKey参数
+type CreationOptions struct {
+	Challenge              Base64URL              `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions navigator.credentials.get的publicKey参数
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	Timeout          int                    `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse 注册时浏览器返回的PublicKeyCredential
+type AttestationResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AttestationObject Base64URL `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse 登录时浏览器返回的PublicKeyCredential
+type AssertionResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AuthenticatorData Base64URL `json:"authenticatorData"`
+		Signature         Base64URL `json:"signature"`
+		UserHandle        Base64URL `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential 注册成功后需要保存的凭证
+type Credential struct {
+	ID          []byte
+	PublicKey   []byte // COSE_Key原始编码
+	Alg         int64
+	SignCount   uint32
+	AAGUID      []byte
+	Attestation string // 证明格式
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewChallenge 生成随机挑战
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	res := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		res[i] = CredentialDescriptor{Type: "public-key", ID: id}
+	}
+	return res
+}
+
+// NewCreationOptions 生成注册参数，exclude为用户已注册的凭证ID，避免同一认证器重复注册
+func (c *Config) NewCreationOptions(challenge, userID []byte, name, displayName string, exclude [][]byte) *CreationOptions {
+	params := make([]CredentialParameter, len(SupportedAlgs))
+	for i, alg := range SupportedAlgs {
+		params[i] = CredentialParameter{Type: "public-key", Alg: alg}
+	}
+	attestation := c.Attestation
+	if attestation == "" {
+		attestation = "none"
+	}
+	return &CreationOptions{
+		Challenge:          challenge,
+		RP:                 RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:               UserEntity{ID: userID, Name: name, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            c.Timeout,
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "required",
+		},
+		Attestation: attestation,
+	}
+}
+
+// NewRequestOptions 生成登录参数，allow为空时由认证器选择可发现凭证
+func (c *Config) NewRequestOptions(challenge []byte, allow [][]byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          c.Timeout,
+		RPID:             c.RPID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+// verifyClientData 校验clientDataJSON的类型、挑战与origin
+func (c *Config) verifyClientData(raw []byte, typ string, challenge []byte) error {
+	cd := &clientData{}
+	if err := json.Unmarshal(raw, cd); err != nil {
+		return ErrClientData
+	}
+	if cd.Type != typ {
+		return ErrCeremonyType
+	}
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cd.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallenge
+	}
+	for _, origin := range c.Origins {
+		if cd.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOrigin
+}
+
+// verifyAuthData 校验依赖方ID哈希与用户在场、用户验证标志
+func (c *Config) verifyAuthData(ad *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(ad.RPIDHash, rpIDHash[:]) {
+		return ErrRPID
+	}
+	if ad.Flags&flagUserPresent == 0 {
+		return ErrUserPresence
+	}
+	if ad.Flags&flagUserVerified == 0 {
+		return ErrUserVerification
+	}
+	return nil
+}
+
+// VerifyRegistration 校验注册响应，成功时返回需要保存的凭证
+func (c *Config) VerifyRegistration(challenge []byte, resp *AttestationResponse) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, ErrCredential
+	}
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	v, n, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil || n != len(resp.Response.AttestationObject) {
+		return nil, ErrAttestation
+	}
+	obj, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrAttestation
+	}
+	format, _ := obj["fmt"].(string)
+	stmt, _ := obj["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := obj["authData"].([]byte)
+	if stmt == nil {
+		return nil, ErrAttestation
+	}
+
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyAuthData(ad); err != nil {
+		return nil, err
+	}
+	if ad.Flags&flagAttestedData == 0 {
+		return nil, ErrAttestation
+	}
+	if len(resp.RawID) > 0 && !bytes.Equal(resp.RawID, ad.CredentialID) {
+		return nil, ErrCredential
+	}
+	pub, alg, err := parseCOSEKey(ad.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	switch format {
+	case "none":
+		if len(stmt) != 0 {
+			return nil, ErrAttestation
+		}
+	case "packed":
+		if err := verifyPacked(stmt, rawAuthData, clientDataHash[:], ad.AAGUID, pub, alg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrAttestationFormat
+	}
+
+	return &Credential{
+		ID:          append([]byte(nil), ad.CredentialID...),
+		PublicKey:   append([]byte(nil), ad.PublicKey...),
+		Alg:         alg,
+		SignCount:   ad.SignCount,
+		AAGUID:      append([]byte(nil), ad.AAGUID...),
+		Attestation: format,
+	}, nil
+}
+
+// VerifyAssertion 使用已保存的凭证校验登录响应，返回新的签名计数
+func (c *Config) VerifyAssertion(challenge []byte, cred *Credential, resp *AssertionResponse) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, ErrCredential
+	}
+	if len(resp.RawID) > 0 && !bytes.Equal(resp.RawID, cred.ID) {
+		return 0, ErrCredential
+	}
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	ad, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.verifyAuthData(ad); err != nil {
+		return 0, err
+	}
+
+	pub, alg, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := verifySignature(pub, alg, signed, resp.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// 计数为0表示认证器不支持计数，否则必须严格递增
+	if (ad.SignCount != 0 || cred.SignCount != 0) && ad.SignCount <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return ad.SignCount, nil
+}
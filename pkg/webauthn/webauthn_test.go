@@ -0,0 +1,303 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cborMap 保持键顺序的CBOR映射，仅用于测试中构造认证器数据
+type cborMap [][2]interface{}
+
+func encodeCBOR(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(n))
+			return b
+		default:
+			b := []byte{major<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(b[1:], uint32(n))
+			return b
+		}
+	}
+	switch x := v.(type) {
+	case int:
+		if x < 0 {
+			return head(1, uint64(-1-x))
+		}
+		return head(0, uint64(x))
+	case int64:
+		return encodeCBOR(int(x))
+	case []byte:
+		return append(head(2, uint64(len(x))), x...)
+	case string:
+		return append(head(3, uint64(len(x))), x...)
+	case []interface{}:
+		b := head(4, uint64(len(x)))
+		for _, item := range x {
+			b = append(b, encodeCBOR(item)...)
+		}
+		return b
+	case cborMap:
+		b := head(5, uint64(len(x)))
+		for _, kv := range x {
+			b = append(b, encodeCBOR(kv[0])...)
+			b = append(b, encodeCBOR(kv[1])...)
+		}
+		return b
+	}
+	panic("unsupported cbor value")
+}
+
+// softAuthenticator 软件实现的认证器
+type softAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	credID    []byte
+	aaguid    []byte
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &softAuthenticator{key: key, credID: id, aaguid: make([]byte, 16)}
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	x, y := make([]byte, 32), make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	return encodeCBOR(cborMap{{1, 2}, {3, -7}, {-1, 1}, {-2, x}, {-3, y}})
+}
+
+func (a *softAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	b := append([]byte(nil), hash[:]...)
+	b = append(b, flags)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[33:], a.signCount)
+	if attested {
+		b = append(b, a.aaguid...)
+		b = append(b, byte(len(a.credID)>>8), byte(len(a.credID)))
+		b = append(b, a.credID...)
+		b = append(b, a.coseKey()...)
+	}
+	return b
+}
+
+func clientDataJSON(typ string, challenge []byte, origin string) []byte {
+	b, _ := json.Marshal(map[string]interface{}{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	return b
+}
+
+func (a *softAuthenticator) sign(key interface{}, data []byte) []byte {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256(data)
+		sig, _ := ecdsa.SignASN1(rand.Reader, k, sum[:])
+		return sig
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, data)
+	}
+	panic("unsupported key")
+}
+
+// create 模拟navigator.credentials.create，stmt为nil时生成packed自证明
+func (a *softAuthenticator) create(cfg *Config, challenge []byte, format string, x5c *x509Fixture) *AttestationResponse {
+	cd := clientDataJSON("webauthn.create", challenge, cfg.Origins[0])
+	ad := a.authData(cfg.RPID, flagUserPresent|flagUserVerified|flagAttestedData, true)
+	hash := sha256.Sum256(cd)
+	signed := append(append([]byte(nil), ad...), hash[:]...)
+
+	stmt := cborMap{}
+	if format == "packed" {
+		if x5c == nil {
+			stmt = cborMap{{"alg", -7}, {"sig", a.sign(a.key, signed)}}
+		} else {
+			stmt = cborMap{{"alg", -7}, {"sig", a.sign(x5c.key, signed)}, {"x5c", []interface{}{x5c.der}}}
+		}
+	}
+	resp := &AttestationResponse{ID: base64.RawURLEncoding.EncodeToString(a.credID), RawID: a.credID, Type: "public-key"}
+	resp.Response.ClientDataJSON = cd
+	resp.Response.AttestationObject = encodeCBOR(cborMap{{"fmt", format}, {"attStmt", stmt}, {"authData", ad}})
+	return resp
+}
+
+// get 模拟navigator.credentials.get
+func (a *softAuthenticator) get(cfg *Config, challenge []byte, flags byte) *AssertionResponse {
+	a.signCount++
+	cd := clientDataJSON("webauthn.get", challenge, cfg.Origins[0])
+	ad := a.authData(cfg.RPID, flags, false)
+	hash := sha256.Sum256(cd)
+	resp := &AssertionResponse{ID: base64.RawURLEncoding.EncodeToString(a.credID), RawID: a.credID, Type: "public-key"}
+	resp.Response.ClientDataJSON = cd
+	resp.Response.AuthenticatorData = ad
+	resp.Response.Signature = a.sign(a.key, append(append([]byte(nil), ad...), hash[:]...))
+	return resp
+}
+
+type x509Fixture struct {
+	key *ecdsa.PrivateKey
+	der []byte
+}
+
+func newAttestationCert(t *testing.T, aaguid []byte, ou string) *x509Fixture {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ext, _ := asn1.Marshal(aaguid)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"CN"},
+			Organization:       []string{"Vid Test"},
+			OrganizationalUnit: []string{ou},
+			CommonName:         "Vid Soft Authenticator",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: idFidoGenCeAAGUID, Value: ext}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return &x509Fixture{key: key, der: der}
+}
+
+func testConfig() *Config {
+	return &Config{RPID: "vid.test", RPName: "Vid", Origins: []string{"https://vid.test"}}
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	cfg := testConfig()
+	a := newSoftAuthenticator(t)
+
+	for _, format := range []string{"none", "packed"} {
+		challenge, err := NewChallenge()
+		require.NoError(t, err)
+		cred, err := cfg.VerifyRegistration(challenge, a.create(cfg, challenge, format, nil))
+		require.NoError(t, err, format)
+		assert.Equal(t, a.credID, cred.ID)
+		assert.Equal(t, AlgES256, cred.Alg)
+		assert.Equal(t, format, cred.Attestation)
+	}
+
+	challenge, _ := NewChallenge()
+	cred, err := cfg.VerifyRegistration(challenge, a.create(cfg, challenge, "none", nil))
+	require.NoError(t, err)
+
+	challenge, _ = NewChallenge()
+	count, err := cfg.VerifyAssertion(challenge, cred, a.get(cfg, challenge, flagUserPresent|flagUserVerified))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), count)
+	cred.SignCount = count
+
+	// 计数未增加说明认证器可能被克隆
+	a.signCount = 0
+	challenge, _ = NewChallenge()
+	_, err = cfg.VerifyAssertion(challenge, cred, a.get(cfg, challenge, flagUserPresent|flagUserVerified))
+	assert.Equal(t, ErrSignCount, err)
+
+	// 未进行用户验证
+	a.signCount = 5
+	challenge, _ = NewChallenge()
+	_, err = cfg.VerifyAssertion(challenge, cred, a.get(cfg, challenge, flagUserPresent))
+	assert.Equal(t, ErrUserVerification, err)
+
+	// 挑战不匹配
+	other, _ := NewChallenge()
+	_, err = cfg.VerifyAssertion(other, cred, a.get(cfg, challenge, flagUserPresent|flagUserVerified))
+	assert.Equal(t, ErrChallenge, err)
+
+	// 签名被篡改
+	resp := a.get(cfg, challenge, flagUserPresent|flagUserVerified)
+	resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff
+	_, err = cfg.VerifyAssertion(challenge, cred, resp)
+	assert.Error(t, err)
+}
+
+func TestRegistrationRejects(t *testing.T) {
+	cfg := testConfig()
+	a := newSoftAuthenticator(t)
+	challenge, _ := NewChallenge()
+
+	_, err := (&Config{RPID: "vid.test", Origins: []string{"https://evil.test"}}).
+		VerifyRegistration(challenge, a.create(cfg, challenge, "none", nil))
+	assert.Equal(t, ErrOrigin, err)
+
+	_, err = (&Config{RPID: "evil.test", Origins: cfg.Origins}).
+		VerifyRegistration(challenge, a.create(cfg, challenge, "none", nil))
+	assert.Equal(t, ErrRPID, err)
+
+	_, err = cfg.VerifyRegistration(challenge, a.create(cfg, challenge, "fido-u2f", nil))
+	assert.Equal(t, ErrAttestationFormat, err)
+
+	// 类型不同的仪式不能混用
+	assertion := a.get(cfg, challenge, flagUserPresent|flagUserVerified)
+	resp := a.create(cfg, challenge, "none", nil)
+	resp.Response.ClientDataJSON = assertion.Response.ClientDataJSON
+	_, err = cfg.VerifyRegistration(challenge, resp)
+	assert.Equal(t, ErrCeremonyType, err)
+}
+
+func TestPackedFullAttestation(t *testing.T) {
+	cfg := testConfig()
+	a := newSoftAuthenticator(t)
+	challenge, _ := NewChallenge()
+
+	cert := newAttestationCert(t, a.aaguid, "Authenticator Attestation")
+	cred, err := cfg.VerifyRegistration(challenge, a.create(cfg, challenge, "packed", cert))
+	require.NoError(t, err)
+	assert.Equal(t, "packed", cred.Attestation)
+
+	// 证书OU不符合要求
+	cert = newAttestationCert(t, a.aaguid, "Other")
+	_, err = cfg.VerifyRegistration(challenge, a.create(cfg, challenge, "packed", cert))
+	assert.Equal(t, ErrAttestation, err)
+
+	// 证书中的AAGUID与认证器数据不一致
+	cert = newAttestationCert(t, []byte("0123456789abcdef"), "Authenticator Attestation")
+	_, err = cfg.VerifyRegistration(challenge, a.create(cfg, challenge, "packed", cert))
+	assert.Equal(t, ErrAttestation, err)
+}
+
+func TestCBOR(t *testing.T) {
+	v, n, err := decodeCBOR(encodeCBOR(cborMap{{1, 2}, {"a", []interface{}{-300, []byte{1}, "x"}}}))
+	require.NoError(t, err)
+	assert.Equal(t, 13, n)
+	assert.Equal(t, map[interface{}]interface{}{
+		int64(1): int64(2),
+		"a":      []interface{}{int64(-300), []byte{1}, "x"},
+	}, v)
+
+	// 截断与超长长度
+	_, _, err = decodeCBOR([]byte{0x59, 0xff})
+	assert.Equal(t, ErrCBOR, err)
+	_, _, err = decodeCBOR([]byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	assert.Equal(t, ErrCBOR, err)
+}